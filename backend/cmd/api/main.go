@@ -1,36 +1,73 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gkalyan/aquaflow-analytics/internal/config"
 	"github.com/gkalyan/aquaflow-analytics/internal/core/db"
 	"github.com/gkalyan/aquaflow-analytics/internal/core/handlers"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
 	"github.com/gkalyan/aquaflow-analytics/internal/core/middleware"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/tracing"
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// JSON logs on stdout; LOG_LEVEL sets the level
+	if _, err := logging.Init("aquaflow-api", cfg.LogLevel); err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)), "component", "gin")
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, _ int) {
+		slog.Debug("Route registered", "component", "gin", "method", method, "route", path, "handler", handler)
+	}
+
+	// Tracing; OTEL_TRACES_EXPORTER selects the exporter
+	shutdownTracing, err := tracing.Init(context.Background(), "aquaflow-api")
+	if err != nil {
+		logging.Fatal("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
 	// Connect to database
 	database, err := db.Connect(cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logging.Fatal("Failed to connect to database", "error", err)
 	}
 	defer database.Close()
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), gin.Recovery())
+	r.Use(middleware.MetricsMiddleware())
+	r.Use(otelgin.Middleware("aquaflow-api", otelgin.WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/metrics" && req.URL.Path != "/health"
+	})))
 
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -57,6 +94,9 @@ func main() {
 		})
 	})
 
+	// Prometheus metrics
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(cfg.JWTSecret)
 	etlHandler := handlers.NewETLHandler(database)
@@ -140,17 +180,41 @@ func main() {
 			etl.GET("/logs", etlHandler.GetAllLogs)
 			
 			// New three-tier architecture endpoints
+			etl.GET("/job-types", etlHandler.GetJobTypes)
 			etl.GET("/job-definitions", etlHandler.GetJobDefinitions)
+			etl.POST("/job-definitions", etlHandler.CreateJobDefinition)
+			etl.PUT("/job-definitions/:id", etlHandler.UpdateJobDefinition)
+			etl.POST("/job-definitions/:id/run", etlHandler.RunJobDefinition)
+			etl.POST("/job-definitions/:id/backfill", etlHandler.CreateBackfill)
+			etl.GET("/job-definitions/:id/dependencies", etlHandler.GetJobDependencies)
+			etl.PUT("/job-definitions/:id/dependencies", etlHandler.SetJobDependencies)
+			etl.GET("/dag", etlHandler.GetJobDAG)
 			etl.GET("/schedules", etlHandler.GetSchedules)
+			etl.POST("/schedules", etlHandler.CreateSchedule)
+			etl.PUT("/schedules/:id", etlHandler.UpdateSchedule)
+			etl.POST("/schedules/:id/enable", etlHandler.EnableSchedule)
+			etl.GET("/calendars", etlHandler.GetCalendars)
+			etl.POST("/calendars", etlHandler.CreateCalendar)
+			etl.PUT("/calendars/:id", etlHandler.UpdateCalendar)
+			etl.DELETE("/calendars/:id", etlHandler.DeleteCalendar)
+			etl.GET("/blocked-fires", etlHandler.GetBlockedFires)
 			etl.GET("/runs", etlHandler.GetJobRuns)
+			etl.GET("/runs/:id", etlHandler.GetJobRun)
+			etl.POST("/runs/:id/retry", etlHandler.RetryShard)
+
+			// Dead-letter store
+			etl.GET("/rejected-records", etlHandler.GetRejectedRecords)
+			etl.POST("/rejected-records/replay", etlHandler.ReplayRejectedRecords)
+
+			// Notifications raised for investigation
+			etl.GET("/notifications", etlHandler.GetNotifications)
+			etl.POST("/notifications/:id/acknowledge", etlHandler.AcknowledgeNotification)
 		}
 	}
 
-	log.Printf("Starting AquaFlow Analytics API on port %s", cfg.Port)
-	log.Printf("Environment: %s", cfg.DatabaseURL)
-	log.Printf("Database Schema: %s", cfg.DBSchema)
-	
+	slog.Info("Starting AquaFlow Analytics API", "port", cfg.Port, "schema", cfg.DBSchema)
+
 	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+		logging.Fatal("Failed to start server", "error", err)
 	}
 }
\ No newline at end of file
@@ -15,6 +15,7 @@ type Config struct {
 	DBPort      string
 	DBSchema    string
 	JWTSecret   string
+	LogLevel    string
 }
 
 func Load() *Config {
@@ -29,6 +30,7 @@ func Load() *Config {
 		DBPort:      getEnv("DB_PORT", "5432"),
 		DBSchema:    getEnv("DB_SCHEMA", "aquaflow"),
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key-here"),
+		LogLevel:    getEnv("LOG_LEVEL", "info"),
 	}
 }
 
@@ -1,11 +1,16 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"log/slog"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DB struct {
@@ -13,7 +18,17 @@ type DB struct {
 }
 
 func Connect(databaseURL string) (*DB, error) {
-	db, err := sql.Open("postgres", databaseURL)
+	// Queries made with a traced context get a span; the rest, such as
+	// background health checks, are not traced
+	db, err := otelsql.Open("postgres", databaseURL,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -24,10 +39,10 @@ func Connect(databaseURL string) (*DB, error) {
 
 	// Ensure we're using the aquaflow schema
 	if _, err := db.Exec("SET search_path TO aquaflow, public"); err != nil {
-		log.Printf("Warning: Could not set search_path to aquaflow: %v", err)
+		slog.Warn("Could not set search_path to aquaflow", "error", err)
 	}
 
-	log.Println("Successfully connected to database with aquaflow schema")
+	slog.Info("Successfully connected to database with aquaflow schema")
 	return &DB{db}, nil
 }
 
@@ -60,6 +75,6 @@ func (db *DB) HealthCheck() error {
 		return fmt.Errorf("core tables check failed: %w", err)
 	}
 
-	log.Printf("Database health check passed: %d total tables, %d core tables found in aquaflow schema", count, coreTableCount)
+	slog.Debug("Database health check passed", "total_tables", count, "core_tables", coreTableCount)
 	return nil
 }
\ No newline at end of file
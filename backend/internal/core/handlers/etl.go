@@ -9,7 +9,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gkalyan/aquaflow-analytics/internal/core/db"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/tracing"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type ETLHandler struct {
@@ -39,12 +42,15 @@ type ETLJob struct {
 }
 
 type ETLJobLog struct {
-	LogID     int                    `json:"log_id"`
-	BatchID   string                 `json:"batch_id"`
-	Timestamp time.Time              `json:"timestamp"`
-	LogLevel  string                 `json:"log_level"`
-	Message   string                 `json:"message"`
-	Context   map[string]interface{} `json:"context,omitempty"`
+	LogID         int                    `json:"log_id"`
+	BatchID       string                 `json:"batch_id"`
+	Timestamp     time.Time              `json:"timestamp"`
+	LogLevel      string                 `json:"log_level"`
+	Message       string                 `json:"message"`
+	Context       map[string]interface{} `json:"context,omitempty"`
+	Component     *string                `json:"component,omitempty"`
+	CorrelationID *string                `json:"correlation_id,omitempty"`
+	StackTrace    *string                `json:"stack_trace,omitempty"`
 }
 
 type JobDefinition struct {
@@ -53,19 +59,47 @@ type JobDefinition struct {
 	JobType     string                 `json:"job_type"`
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
+	LogLevel    *string                `json:"log_level,omitempty"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+
+	OverlapPolicy     string `json:"overlap_policy"`
+	MaxConcurrentRuns *int   `json:"max_concurrent_runs,omitempty"`
+	Priority          int    `json:"priority"`
 }
 
 type Schedule struct {
 	ScheduleID     string    `json:"schedule_id"`
 	JobID          string    `json:"job_id"`
 	ScheduleName   string    `json:"schedule_name"`
-	CronExpression string    `json:"cron_expression"`
-	NextRun        time.Time `json:"next_run"`
-	IsActive       bool      `json:"is_active"`
-	CreatedAt      time.Time `json:"created_at"`
-	JobName        string    `json:"job_name,omitempty"`
+	CronExpression string     `json:"cron_expression"`
+	Timezone       string     `json:"timezone"`
+	NextRun        *time.Time `json:"next_run"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+	JobName        string     `json:"job_name,omitempty"`
+	// MisfirePolicy is fire_once, fire_all or skip for missed fires
+	MisfirePolicy       string `json:"misfire_policy"`
+	MaxCatchupRuns      int    `json:"max_catchup_runs"`
+	MisfireGraceSeconds int    `json:"misfire_grace_seconds"`
+	// LastRun is the scheduled time of the latest run created for the schedule
+	LastRun            *time.Time `json:"last_run,omitempty"`
+	LastRunID          *string    `json:"last_run_id,omitempty"`
+	LastRunStatus      *string    `json:"last_run_status,omitempty"`
+	LastRunCompletedAt *time.Time `json:"last_run_completed_at,omitempty"`
+	RunCount           int        `json:"run_count"`
+	FailureCount       int        `json:"failure_count"`
+	// ConsecutiveFailures disables the schedule once it reaches AutoDisableAfter
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	AutoDisableAfter    *int       `json:"auto_disable_after"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      *string    `json:"disabled_reason,omitempty"`
+	// Kind is cron, descriptor, interval or once; next_run is null once a
+	// one-time schedule has fired
+	Kind            string     `json:"schedule_kind"`
+	IntervalSeconds *int       `json:"interval_seconds,omitempty"`
+	AnchorAt        *time.Time `json:"anchor_at,omitempty"`
+	RunAt           *time.Time `json:"run_at,omitempty"`
 }
 
 type JobRun struct {
@@ -82,8 +116,74 @@ type JobRun struct {
 	RecordsProcessed int                    `json:"records_processed"`
 	RecordsFailed    int                    `json:"records_failed"`
 	ErrorMessage     *string                `json:"error_message,omitempty"`
+	ErrorCategory    *string                `json:"error_category,omitempty"`
 	JobName          string                 `json:"job_name,omitempty"`
 	JobType          string                 `json:"job_type,omitempty"`
+	ResolvedSeries   pq.Int64Array          `json:"resolved_series_ids,omitempty"`
+	DryRun           bool                   `json:"dry_run"`
+	DryRunReport     json.RawMessage        `json:"dry_run_report,omitempty"`
+	HeartbeatAt      *time.Time             `json:"heartbeat_at,omitempty"`
+	LogLevel         *string                `json:"log_level,omitempty"`
+	CorrelationID    string                 `json:"correlation_id"`
+	Progress         json.RawMessage        `json:"progress,omitempty"`
+	BlockedReason    *string                `json:"blocked_reason,omitempty"`
+	SkipReason       *string                `json:"skip_reason,omitempty"`
+	Priority         int                    `json:"priority"`
+	ParentRunID      *string                `json:"parent_run_id,omitempty"`
+	ShardBy          *string                `json:"shard_by,omitempty"`
+	ShardKey         *string                `json:"shard_key,omitempty"`
+	// TraceParent is the W3C traceparent of the request or cycle that created the run
+	TraceParent *string `json:"traceparent,omitempty"`
+	// Watermark is the latest data timestamp the run wrote
+	Watermark *time.Time `json:"watermark,omitempty"`
+	// DeferredUntil is when a run deferred by a blackout window is queued
+	DeferredUntil *time.Time `json:"deferred_until,omitempty"`
+}
+
+// jobRunColumns is the select list scanned by scanJobRun
+const jobRunColumns = `
+		r.run_id, r.job_id, r.schedule_id, r.run_name, r.status, r.trigger_type,
+		COALESCE(r.scheduled_for, r.started_at), r.started_at, r.completed_at, r.runtime_parameters,
+		r.records_processed, r.records_failed, r.error_message, r.error_category,
+		j.job_name, j.job_type, r.resolved_series_ids, r.dry_run, r.dry_run_report,
+		r.heartbeat_at, r.log_level, r.correlation_id, r.progress, r.blocked_reason,
+		r.skip_reason, r.priority, r.parent_run_id, r.shard_by, r.shard_key,
+		r.trace_context->>'traceparent', r.watermark, r.deferred_until
+`
+
+// scanJobRun scans a row selected with jobRunColumns
+func scanJobRun(row interface{ Scan(dest ...interface{}) error }) (JobRun, error) {
+	var run JobRun
+	var paramsJSON, reportJSON, progressJSON []byte
+
+	err := row.Scan(
+		&run.RunID, &run.JobID, &run.ScheduleID, &run.RunName,
+		&run.Status, &run.TriggerType, &run.ScheduledFor,
+		&run.StartedAt, &run.CompletedAt, &paramsJSON,
+		&run.RecordsProcessed, &run.RecordsFailed, &run.ErrorMessage, &run.ErrorCategory,
+		&run.JobName, &run.JobType, &run.ResolvedSeries, &run.DryRun, &reportJSON,
+		&run.HeartbeatAt, &run.LogLevel, &run.CorrelationID, &progressJSON, &run.BlockedReason,
+		&run.SkipReason, &run.Priority, &run.ParentRunID, &run.ShardBy, &run.ShardKey,
+		&run.TraceParent, &run.Watermark, &run.DeferredUntil,
+	)
+	if err != nil {
+		return run, err
+	}
+
+	// Parse runtime parameters JSON
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &run.RuntimeParams); err == nil {
+			// Parsed successfully
+		}
+	}
+	if len(reportJSON) > 0 {
+		run.DryRunReport = json.RawMessage(reportJSON)
+	}
+	if len(progressJSON) > 0 {
+		run.Progress = json.RawMessage(progressJSON)
+	}
+
+	return run, nil
 }
 
 // GetJobs returns all ETL job runs with optional filtering
@@ -115,7 +215,7 @@ func (h *ETLHandler) GetJobs(c *gin.Context) {
 
 	query += " ORDER BY r.started_at DESC LIMIT " + limit
 
-	rows, err := h.db.Query(query, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -184,7 +284,7 @@ func (h *ETLHandler) GetJobDetails(c *gin.Context) {
 		WHERE r.run_id = $1
 	`
 
-	err := h.db.QueryRow(query, runID).Scan(
+	err := h.db.QueryRowContext(c.Request.Context(), query, runID).Scan(
 		&job.BatchID, &job.JobName, &job.JobType, &job.LoadType,
 		&job.Status, &paramsJSON, &job.RecordsProcessed,
 		&job.RecordsFailed, &job.StartedAt, &job.CompletedAt,
@@ -223,7 +323,8 @@ func (h *ETLHandler) GetJobLogs(c *gin.Context) {
 	}
 
 	query := `
-		SELECT log_id, run_id as batch_id, timestamp, log_level, message, context
+		SELECT log_id, run_id as batch_id, timestamp, log_level, message, context,
+			   component, correlation_id, stack_trace
 		FROM aquaflow.etl_job_logs_v2
 		WHERE run_id = $1
 	`
@@ -240,7 +341,7 @@ func (h *ETLHandler) GetJobLogs(c *gin.Context) {
 
 	query += " ORDER BY timestamp DESC LIMIT " + limit
 
-	rows, err := h.db.Query(query, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -255,6 +356,7 @@ func (h *ETLHandler) GetJobLogs(c *gin.Context) {
 		err := rows.Scan(
 			&log.LogID, &log.BatchID, &log.Timestamp,
 			&log.LogLevel, &log.Message, &contextJSON,
+			&log.Component, &log.CorrelationID, &log.StackTrace,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -288,18 +390,21 @@ func (h *ETLHandler) RestartJob(c *gin.Context) {
 	}
 
 	// Get the original job run details
-	var jobID, scheduleID uuid.UUID
+	var jobID, correlationID string
+	var scheduleID *string
 	var runName string
 	var paramsJSON []byte
+	var priority int
 
 	query := `
-		SELECT r.job_id, r.schedule_id, r.run_name, COALESCE(r.runtime_parameters, j.parameters)
+		SELECT r.job_id, r.schedule_id, r.run_name, COALESCE(r.runtime_parameters, j.parameters),
+			   r.correlation_id, r.priority
 		FROM aquaflow.etl_job_runs r
 		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
 		WHERE r.run_id = $1 AND r.status IN ('failed', 'completed_with_errors')
 	`
 
-	err := h.db.QueryRow(query, runID).Scan(&jobID, &scheduleID, &runName, &paramsJSON)
+	err := h.db.QueryRowContext(c.Request.Context(), query, runID).Scan(&jobID, &scheduleID, &runName, &paramsJSON, &correlationID, &priority)
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"error": "job run not found or not in failed state"})
 		return
@@ -309,24 +414,54 @@ func (h *ETLHandler) RestartJob(c *gin.Context) {
 		return
 	}
 
-	// Create new job run
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Create new job run; it keeps the correlation ID so the restart can be
+	// traced back to the original run
 	newRunID := uuid.New()
 	insertQuery := `
 		INSERT INTO aquaflow.etl_job_runs 
-		(run_id, job_id, schedule_id, run_name, runtime_parameters, status, trigger_type, scheduled_for)
-		VALUES ($1, $2, $3, $4, $5, 'queued', 'manual', NOW())
+		(run_id, job_id, schedule_id, run_name, runtime_parameters, status, trigger_type, correlation_id, priority, trace_context)
+		VALUES ($1, $2, $3, $4, $5, 'queued', 'manual', $6, $7, $8)
 	`
 
-	_, err = h.db.Exec(insertQuery, newRunID, jobID, scheduleID, runName+" (Restart)", paramsJSON)
+	_, err = tx.ExecContext(c.Request.Context(), insertQuery, newRunID, jobID, scheduleID, runName+" (Restart)", paramsJSON, correlationID, priority,
+		tracing.Inject(c.Request.Context()))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	logContext, _ := json.Marshal(map[string]interface{}{
+		"original_run_id": runID,
+		"user_id":         c.GetString("userID"),
+	})
+	logQuery := `
+		INSERT INTO aquaflow.etl_job_logs_v2 (run_id, log_level, message, context, component, correlation_id)
+		VALUES ($1, 'INFO', 'Run restarted', $2, 'backend', $3)
+	`
+	if _, err := tx.ExecContext(c.Request.Context(), logQuery, newRunID, logContext, correlationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Run restarted",
+		logging.KeyRunID, newRunID.String(), "original_run_id", runID)
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Job run restarted successfully",
-		"new_run_id":    newRunID.String(),
-		"original_id":   runID,
+		"message":        "Job run restarted successfully",
+		"new_run_id":     newRunID.String(),
+		"original_id":    runID,
+		"correlation_id": correlationID,
 	})
 }
 
@@ -335,11 +470,21 @@ func (h *ETLHandler) GetAllLogs(c *gin.Context) {
 	jobName := c.Query("job_name")
 	logLevel := c.Query("level")
 	seriesID := c.Query("series_id")
+	component := c.Query("component")
+	correlationID := c.Query("correlation_id")
 	since := c.Query("since")
 	limit := c.DefaultQuery("limit", "200")
 
+	if correlationID != "" {
+		if _, err := uuid.Parse(correlationID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid correlation ID format"})
+			return
+		}
+	}
+
 	query := `
 		SELECT l.log_id, l.run_id as batch_id, l.timestamp, l.log_level, l.message, l.context,
+			   l.component, l.correlation_id, l.stack_trace,
 			   r.run_name as job_name, j.job_type
 		FROM aquaflow.etl_job_logs_v2 l
 		JOIN aquaflow.etl_job_runs r ON l.run_id = r.run_id
@@ -368,6 +513,18 @@ func (h *ETLHandler) GetAllLogs(c *gin.Context) {
 		args = append(args, seriesID)
 	}
 
+	if component != "" {
+		argCount++
+		query += fmt.Sprintf(" AND l.component = $%d", argCount)
+		args = append(args, component)
+	}
+
+	if correlationID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND l.correlation_id = $%d", argCount)
+		args = append(args, correlationID)
+	}
+
 	if since != "" {
 		sinceTime, err := time.Parse(time.RFC3339, since)
 		if err == nil {
@@ -379,7 +536,7 @@ func (h *ETLHandler) GetAllLogs(c *gin.Context) {
 
 	query += " ORDER BY l.timestamp DESC LIMIT " + limit
 
-	rows, err := h.db.Query(query, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -400,6 +557,7 @@ func (h *ETLHandler) GetAllLogs(c *gin.Context) {
 		err := rows.Scan(
 			&log.LogID, &log.BatchID, &log.Timestamp,
 			&log.LogLevel, &log.Message, &contextJSON,
+			&log.Component, &log.CorrelationID, &log.StackTrace,
 			&log.JobName, &log.JobType,
 		)
 		if err != nil {
@@ -428,11 +586,12 @@ func (h *ETLHandler) GetJobDefinitions(c *gin.Context) {
 	limit := c.DefaultQuery("limit", "50")
 
 	query := `
-		SELECT job_id, job_name, job_type, description, parameters, created_at, updated_at
+		SELECT job_id, job_name, job_type, description, parameters, log_level, created_at, updated_at,
+		       overlap_policy, max_concurrent_runs, priority
 		FROM aquaflow.etl_jobs_v2
 		ORDER BY created_at DESC LIMIT ` + limit
 
-	rows, err := h.db.Query(query)
+	rows, err := h.db.QueryContext(c.Request.Context(), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -446,7 +605,8 @@ func (h *ETLHandler) GetJobDefinitions(c *gin.Context) {
 
 		err := rows.Scan(
 			&job.JobID, &job.JobName, &job.JobType, &job.Description,
-			&paramsJSON, &job.CreatedAt, &job.UpdatedAt,
+			&paramsJSON, &job.LogLevel, &job.CreatedAt, &job.UpdatedAt,
+			&job.OverlapPolicy, &job.MaxConcurrentRuns, &job.Priority,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -475,8 +635,13 @@ func (h *ETLHandler) GetSchedules(c *gin.Context) {
 	limit := c.DefaultQuery("limit", "50")
 
 	query := `
-		SELECT s.schedule_id, s.job_id, s.schedule_name, s.cron_expression, 
-		       s.next_run, s.is_active, s.created_at, j.job_name
+		SELECT s.schedule_id, s.job_id, s.schedule_name, COALESCE(s.cron_expression, ''), s.timezone,
+		       s.next_run, s.is_active, s.created_at, j.job_name,
+		       s.misfire_policy, s.max_catchup_runs, s.misfire_grace_seconds,
+		       s.last_run, s.last_run_id, s.last_run_status, s.last_run_completed_at,
+		       COALESCE(s.run_count, 0), COALESCE(s.failure_count, 0), s.consecutive_failures, s.auto_disable_after,
+		       s.disabled_at, s.disabled_reason,
+		       s.schedule_kind, s.interval_seconds, s.anchor_at, s.run_at
 		FROM aquaflow.etl_schedules s
 		JOIN aquaflow.etl_jobs_v2 j ON s.job_id = j.job_id
 	`
@@ -487,9 +652,9 @@ func (h *ETLHandler) GetSchedules(c *gin.Context) {
 		args = append(args, active == "true")
 	}
 
-	query += " ORDER BY s.next_run ASC LIMIT " + limit
+	query += " ORDER BY s.next_run ASC NULLS LAST LIMIT " + limit
 
-	rows, err := h.db.Query(query, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -502,8 +667,13 @@ func (h *ETLHandler) GetSchedules(c *gin.Context) {
 
 		err := rows.Scan(
 			&schedule.ScheduleID, &schedule.JobID, &schedule.ScheduleName,
-			&schedule.CronExpression, &schedule.NextRun, &schedule.IsActive,
+			&schedule.CronExpression, &schedule.Timezone, &schedule.NextRun, &schedule.IsActive,
 			&schedule.CreatedAt, &schedule.JobName,
+			&schedule.MisfirePolicy, &schedule.MaxCatchupRuns, &schedule.MisfireGraceSeconds,
+			&schedule.LastRun, &schedule.LastRunID, &schedule.LastRunStatus, &schedule.LastRunCompletedAt,
+			&schedule.RunCount, &schedule.FailureCount, &schedule.ConsecutiveFailures, &schedule.AutoDisableAfter,
+			&schedule.DisabledAt, &schedule.DisabledReason,
+			&schedule.Kind, &schedule.IntervalSeconds, &schedule.AnchorAt, &schedule.RunAt,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -524,13 +694,11 @@ func (h *ETLHandler) GetJobRuns(c *gin.Context) {
 	status := c.Query("status")
 	jobID := c.Query("job_id")
 	scheduleID := c.Query("schedule_id")
+	parentRunID := c.Query("parent_run_id")
 	limit := c.DefaultQuery("limit", "100")
 
 	query := `
-		SELECT r.run_id, r.job_id, r.schedule_id, r.run_name, r.status, r.trigger_type,
-		       r.started_at, r.started_at, r.completed_at, r.runtime_parameters,
-		       r.records_processed, r.records_failed, r.error_message,
-		       j.job_name, j.job_type
+		SELECT ` + jobRunColumns + `
 		FROM aquaflow.etl_job_runs r
 		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
 		WHERE 1=1
@@ -557,9 +725,15 @@ func (h *ETLHandler) GetJobRuns(c *gin.Context) {
 		args = append(args, scheduleID)
 	}
 
+	if parentRunID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND r.parent_run_id = $%d", argCount)
+		args = append(args, parentRunID)
+	}
+
 	query += " ORDER BY r.started_at DESC LIMIT " + limit
 
-	rows, err := h.db.Query(query, args...)
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -568,28 +742,12 @@ func (h *ETLHandler) GetJobRuns(c *gin.Context) {
 
 	runs := []JobRun{}
 	for rows.Next() {
-		var run JobRun
-		var paramsJSON []byte
-
-		err := rows.Scan(
-			&run.RunID, &run.JobID, &run.ScheduleID, &run.RunName,
-			&run.Status, &run.TriggerType, &run.ScheduledFor,
-			&run.StartedAt, &run.CompletedAt, &paramsJSON,
-			&run.RecordsProcessed, &run.RecordsFailed, &run.ErrorMessage,
-			&run.JobName, &run.JobType,
-		)
+		run, err := scanJobRun(rows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Parse runtime parameters JSON
-		if len(paramsJSON) > 0 {
-			if err := json.Unmarshal(paramsJSON, &run.RuntimeParams); err == nil {
-				// Parsed successfully
-			}
-		}
-
 		runs = append(runs, run)
 	}
 
@@ -597,4 +755,34 @@ func (h *ETLHandler) GetJobRuns(c *gin.Context) {
 		"runs":  runs,
 		"count": len(runs),
 	})
-}
\ No newline at end of file
+}
+
+// GetJobRun returns a single job run, including its progress and dry-run report
+func (h *ETLHandler) GetJobRun(c *gin.Context) {
+	runID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(runID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run ID format"})
+		return
+	}
+
+	query := `
+		SELECT ` + jobRunColumns + `
+		FROM aquaflow.etl_job_runs r
+		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
+		WHERE r.run_id = $1
+	`
+
+	run, err := scanJobRun(h.db.QueryRowContext(c.Request.Context(), query, runID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aquaflow/etl-common/jobschema"
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/tracing"
+	"github.com/google/uuid"
+)
+
+// BackfillRequest is the body for starting a sharded backfill of a job
+type BackfillRequest struct {
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" binding:"required,datetime=2006-01-02"`
+	// ShardBy splits the backfill into a child run per month or per series
+	ShardBy    string                 `json:"shard_by" binding:"required,oneof=month series"`
+	RunName    string                 `json:"run_name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	DryRun     bool                   `json:"dry_run"`
+	LogLevel   *string                `json:"log_level" binding:"omitempty,oneof=DEBUG INFO WARN ERROR"`
+	Priority   *int                   `json:"priority" binding:"omitempty,min=1,max=10"`
+}
+
+// CreateBackfill queues a backfill parent run. The worker that claims it
+// splits it into child runs, which idle workers then process in parallel.
+func (h *ETLHandler) CreateBackfill(c *gin.Context) {
+	jobID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	var req BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	if req.EndDate < req.StartDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date is before start_date"})
+		return
+	}
+
+	var jobName, jobType string
+	var paramsJSON []byte
+	var priority int
+	query := `SELECT job_name, job_type, parameters, priority FROM aquaflow.etl_jobs_v2 WHERE job_id = $1`
+	err := h.db.QueryRowContext(c.Request.Context(), query, jobID).Scan(&jobName, &jobType, &paramsJSON, &priority)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job definition not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := map[string]interface{}{}
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for key, value := range req.Parameters {
+		params[key] = value
+	}
+	params["start_date"] = req.StartDate
+	params["end_date"] = req.EndDate
+
+	if !h.validateParameters(c, jobType, params, jobschema.ModeRun) {
+		return
+	}
+
+	runtimeJSON, err := json.Marshal(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runName := req.RunName
+	if runName == "" {
+		runName = jobName + " - Backfill " + req.StartDate + " to " + req.EndDate
+		if req.DryRun {
+			runName += " (Dry Run)"
+		}
+	}
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+
+	newRunID := uuid.New()
+	insertQuery := `
+		INSERT INTO aquaflow.etl_job_runs (run_id, job_id, run_name, runtime_parameters, status, trigger_type,
+			dry_run, log_level, priority, shard_by, trace_context)
+		VALUES ($1, $2, $3, $4, 'queued', 'manual', $5, $6, $7, $8, $9)
+	`
+	if _, err := h.db.ExecContext(c.Request.Context(), insertQuery, newRunID, jobID, runName, runtimeJSON, req.DryRun, req.LogLevel,
+		priority, req.ShardBy, tracing.Inject(c.Request.Context())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Backfill queued",
+		logging.KeyRunID, newRunID.String(), logging.KeyJobName, jobName, "shard_by", req.ShardBy)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Backfill queued successfully",
+		"run_id":   newRunID.String(),
+		"job_id":   jobID,
+		"shard_by": req.ShardBy,
+		"dry_run":  req.DryRun,
+		"priority": priority,
+	})
+}
+
+// RetryShard requeues a failed shard of a backfill in place, leaving the
+// other shards untouched. Called on the backfill parent, it requeues every
+// failed or cancelled shard. The parent goes back to running and is rolled up
+// again as the shards finish.
+func (h *ETLHandler) RetryShard(c *gin.Context) {
+	runID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(runID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run ID format"})
+		return
+	}
+
+	var parentRunID *string
+	var shardBy *string
+	var status string
+	err := h.db.QueryRowContext(c.Request.Context(), `SELECT parent_run_id, shard_by, status FROM aquaflow.etl_job_runs WHERE run_id = $1`, runID).
+		Scan(&parentRunID, &shardBy, &status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var parentID, shardFilter string
+	switch {
+	case parentRunID != nil:
+		if status != "failed" && status != "cancelled" && status != "completed_with_errors" {
+			c.JSON(http.StatusConflict, gin.H{"error": "only failed, cancelled or partially completed shards can be retried", "status": status})
+			return
+		}
+		parentID = *parentRunID
+		shardFilter = "run_id = $1"
+	case shardBy != nil:
+		parentID = runID
+		shardFilter = "parent_run_id = $1 AND status IN ('failed', 'cancelled')"
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "run is not part of a backfill; use restart instead"})
+		return
+	}
+
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Reopen the parent first so the rollup doesn't skip a cancelled backfill;
+	// requeueing the shards below rolls it up again
+	if _, err := tx.ExecContext(c.Request.Context(), `
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'running', completed_at = NULL, error_message = NULL
+		WHERE run_id = $1
+	`, parentID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := tx.QueryContext(c.Request.Context(), `
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'queued', started_at = NOW(), completed_at = NULL, duration_seconds = NULL,
+			error_message = NULL, error_category = NULL, records_processed = 0, records_failed = 0,
+			progress = NULL, dry_run_report = NULL, retry_count = retry_count + 1, trace_context = $2
+		WHERE `+shardFilter+`
+		RETURNING run_id, correlation_id, shard_key
+	`, runID, tracing.Inject(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	type retried struct {
+		runID, correlationID string
+		shardKey             *string
+	}
+	var shards []retried
+	for rows.Next() {
+		var r retried
+		if err := rows.Scan(&r.runID, &r.correlationID, &r.shardKey); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		shards = append(shards, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(shards) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "backfill has no failed shards to retry"})
+		return
+	}
+
+	retriedIDs := make([]string, 0, len(shards))
+	for _, r := range shards {
+		logContext, _ := json.Marshal(map[string]interface{}{
+			"parent_run_id": parentID,
+			"shard_key":     r.shardKey,
+			"user_id":       c.GetString("userID"),
+		})
+		logQuery := `
+			INSERT INTO aquaflow.etl_job_logs_v2 (run_id, log_level, message, context, component, correlation_id)
+			VALUES ($1, 'INFO', 'Shard requeued for retry', $2, 'backend', $3)
+		`
+		if _, err := tx.ExecContext(c.Request.Context(), logQuery, r.runID, logContext, r.correlationID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		retriedIDs = append(retriedIDs, r.runID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Shards requeued",
+		"parent_run_id", parentID, "count", len(retriedIDs))
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Shards requeued successfully",
+		"parent_run_id": parentID,
+		"retried":       retriedIDs,
+		"count":         len(retriedIDs),
+	})
+}
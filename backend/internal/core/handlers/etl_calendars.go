@@ -0,0 +1,602 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultBlockedHorizon is how far ahead blocked fires are listed by default
+	defaultBlockedHorizon = 7 * 24 * time.Hour
+	// maxBlockedHorizon caps how far ahead blocked fires are listed
+	maxBlockedHorizon = 366 * 24 * time.Hour
+	// maxScannedFires caps the fires evaluated per schedule when listing
+	// blocked fires, for schedules that fire every few seconds
+	maxScannedFires = 10000
+)
+
+// Calendar is a named set of blackout windows and what it is attached to
+type Calendar struct {
+	CalendarID   string  `json:"calendar_id"`
+	CalendarName string  `json:"calendar_name"`
+	Description  *string `json:"description,omitempty"`
+	// Action is skip or defer for fires landing in one of the windows
+	Action      string           `json:"action"`
+	IsActive    bool             `json:"is_active"`
+	CreatedBy   *string          `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Windows     []CalendarWindow `json:"windows"`
+	ScheduleIDs []string         `json:"schedule_ids"`
+	JobIDs      []string         `json:"job_ids"`
+}
+
+// CalendarWindow is a one-off or recurring blackout window
+type CalendarWindow struct {
+	WindowID   string     `json:"window_id,omitempty"`
+	WindowName string     `json:"window_name" binding:"required"`
+	Reason     *string    `json:"reason,omitempty"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	// Recurrence is the cron expression or descriptor a recurring window
+	// opens on, for DurationSeconds; StartsAt and EndsAt then bound it
+	Recurrence      *string `json:"recurrence,omitempty"`
+	DurationSeconds *int    `json:"duration_seconds,omitempty" binding:"omitempty,min=1"`
+	Timezone        string  `json:"timezone"`
+}
+
+// window returns w for evaluation with the cron package
+func (w CalendarWindow) window() (cron.Window, error) {
+	loc, err := cron.LoadLocation(w.Timezone)
+	if err != nil {
+		return cron.Window{}, err
+	}
+	window := cron.Window{Start: w.StartsAt, End: w.EndsAt, Location: loc}
+	if w.Recurrence != nil {
+		window.Recurrence = strings.TrimSpace(*w.Recurrence)
+	}
+	if w.DurationSeconds != nil {
+		window.Duration = time.Duration(*w.DurationSeconds) * time.Second
+	}
+	return window, nil
+}
+
+// CalendarRequest is the body for creating or replacing a calendar, with its
+// windows and attachments
+type CalendarRequest struct {
+	CalendarName string `json:"calendar_name" binding:"required"`
+	Description  string `json:"description"`
+	// Action is skip (default) or defer
+	Action      string           `json:"action" binding:"omitempty,oneof=skip defer"`
+	IsActive    *bool            `json:"is_active"`
+	Windows     []CalendarWindow `json:"windows" binding:"dive"`
+	ScheduleIDs []string         `json:"schedule_ids" binding:"dive,uuid"`
+	JobIDs      []string         `json:"job_ids" binding:"dive,uuid"`
+}
+
+// BlockedFire is an upcoming schedule fire that lands in a blackout window
+type BlockedFire struct {
+	ScheduleID   string    `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	JobID        string    `json:"job_id"`
+	JobName      string    `json:"job_name"`
+	FireTime     time.Time `json:"fire_time"`
+	CalendarID   string    `json:"calendar_id"`
+	CalendarName string    `json:"calendar_name"`
+	WindowID     string    `json:"window_id"`
+	WindowName   string    `json:"window_name"`
+	Reason       *string   `json:"reason,omitempty"`
+	Action       string    `json:"action"`
+	// Merged is set on a fire of a defer calendar that lands while an
+	// earlier deferred run still waits: it is skipped in that run's favour
+	Merged bool `json:"merged,omitempty"`
+	// Until is when the window closes for a skipped fire, or when a deferred
+	// run, or the run a merged fire joins, is queued once every window has
+	// closed
+	Until time.Time `json:"until"`
+}
+
+// blackoutSchedule is a schedule with the windows of every calendar attached
+// to it or its job
+type blackoutSchedule struct {
+	timezone string
+	nextRun  time.Time
+	spec     cron.Spec
+	// deferredUntil is when the schedule's run deferred by a blackout, if
+	// one is waiting, is queued
+	deferredUntil *time.Time
+	windows       []cron.Window
+	details       []BlockedFire
+	seen          map[string]bool
+}
+
+// blockedFires walks the schedule's fires from next_run up to until the way
+// the scheduler will, returning those that land in a window
+func (bs *blackoutSchedule) blockedFires(parser *cron.Parser, until time.Time) ([]BlockedFire, error) {
+	loc, err := cron.LoadLocation(bs.timezone)
+	if err != nil {
+		return nil, nil
+	}
+
+	var blocked []BlockedFire
+	var deferredUntil time.Time
+	if bs.deferredUntil != nil {
+		deferredUntil = *bs.deferredUntil
+	}
+	// Pending fires start at next_run, even if the scheduler is behind
+	fire := bs.nextRun
+	for n := 0; n < maxScannedFires && !fire.IsZero() && !fire.After(until); n++ {
+		index, closes, err := parser.Blocked(bs.windows, fire)
+		if err != nil {
+			return nil, err
+		}
+		if index >= 0 {
+			entry := bs.details[index]
+			entry.FireTime = fire.In(loc)
+			entry.Until = closes
+			if entry.Action == "defer" {
+				if fire.Before(deferredUntil) {
+					// The scheduler merges it into the run still waiting
+					entry.Action, entry.Merged, entry.Until = "skip", true, deferredUntil
+				} else if entry.Until, err = parser.Clear(bs.windows, closes); err != nil {
+					return nil, err
+				} else {
+					deferredUntil = entry.Until
+				}
+			}
+			blocked = append(blocked, entry)
+		}
+		if fire, err = parser.NextExecution(bs.spec, fire, loc); err != nil {
+			// The scheduler can't fire it either; nothing more to list
+			break
+		}
+	}
+	return blocked, nil
+}
+
+// GetCalendars returns the blackout calendars with their windows and
+// attachments
+func (h *ETLHandler) GetCalendars(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT calendar_id, calendar_name, description, action, is_active, created_by, created_at, updated_at
+		FROM aquaflow.etl_calendars
+		ORDER BY calendar_name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	calendars := []Calendar{}
+	index := map[string]int{}
+	for rows.Next() {
+		calendar := Calendar{Windows: []CalendarWindow{}, ScheduleIDs: []string{}, JobIDs: []string{}}
+		if err := rows.Scan(&calendar.CalendarID, &calendar.CalendarName, &calendar.Description, &calendar.Action,
+			&calendar.IsActive, &calendar.CreatedBy, &calendar.CreatedAt, &calendar.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		index[calendar.CalendarID] = len(calendars)
+		calendars = append(calendars, calendar)
+	}
+
+	windowRows, err := h.db.QueryContext(ctx, `
+		SELECT calendar_id, window_id, window_name, reason, starts_at, ends_at, recurrence, duration_seconds, timezone
+		FROM aquaflow.etl_calendar_windows
+		ORDER BY COALESCE(starts_at, '-infinity'::timestamptz), window_name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer windowRows.Close()
+
+	for windowRows.Next() {
+		var calendarID string
+		var w CalendarWindow
+		if err := windowRows.Scan(&calendarID, &w.WindowID, &w.WindowName, &w.Reason, &w.StartsAt, &w.EndsAt,
+			&w.Recurrence, &w.DurationSeconds, &w.Timezone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if i, ok := index[calendarID]; ok {
+			calendars[i].Windows = append(calendars[i].Windows, w)
+		}
+	}
+
+	attachmentRows, err := h.db.QueryContext(ctx, `
+		SELECT calendar_id, schedule_id, job_id
+		FROM aquaflow.etl_calendar_attachments
+		ORDER BY created_at
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer attachmentRows.Close()
+
+	for attachmentRows.Next() {
+		var calendarID string
+		var scheduleID, jobID *string
+		if err := attachmentRows.Scan(&calendarID, &scheduleID, &jobID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		i, ok := index[calendarID]
+		if !ok {
+			continue
+		}
+		if scheduleID != nil {
+			calendars[i].ScheduleIDs = append(calendars[i].ScheduleIDs, *scheduleID)
+		}
+		if jobID != nil {
+			calendars[i].JobIDs = append(calendars[i].JobIDs, *jobID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calendars": calendars,
+		"count":     len(calendars),
+	})
+}
+
+// CreateCalendar creates a blackout calendar with its windows and attachments
+func (h *ETLHandler) CreateCalendar(c *gin.Context) {
+	var req CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	if !validateCalendarWindows(c, req.Windows) {
+		return
+	}
+
+	user := c.GetString("userID")
+	if user == "" {
+		user = "system"
+	}
+
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	var calendarID string
+	err = tx.QueryRowContext(c.Request.Context(), `
+		INSERT INTO aquaflow.etl_calendars (calendar_name, description, action, is_active, created_by)
+		VALUES ($1, NULLIF($2, ''), COALESCE(NULLIF($3, ''), 'skip'), COALESCE($4, true), $5)
+		RETURNING calendar_id
+	`, req.CalendarName, req.Description, req.Action, req.IsActive, user).Scan(&calendarID)
+	if err != nil {
+		calendarWriteError(c, err)
+		return
+	}
+
+	if err := insertCalendarContents(c.Request.Context(), tx, calendarID, req); err != nil {
+		calendarWriteError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Blackout calendar created",
+		"calendar_id", calendarID, "calendar_name", req.CalendarName, "windows", len(req.Windows))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Calendar created successfully",
+		"calendar_id": calendarID,
+	})
+}
+
+// UpdateCalendar replaces a blackout calendar, its windows and attachments
+func (h *ETLHandler) UpdateCalendar(c *gin.Context) {
+	calendarID := c.Param("id")
+	if _, err := uuid.Parse(calendarID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid calendar ID"})
+		return
+	}
+
+	var req CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	if !validateCalendarWindows(c, req.Windows) {
+		return
+	}
+
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(c.Request.Context(), `
+		UPDATE aquaflow.etl_calendars
+		SET calendar_name = $2, description = NULLIF($3, ''), action = COALESCE(NULLIF($4, ''), 'skip'),
+			is_active = COALESCE($5, is_active), updated_at = NOW()
+		WHERE calendar_id = $1
+	`, calendarID, req.CalendarName, req.Description, req.Action, req.IsActive)
+	if err != nil {
+		calendarWriteError(c, err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+		return
+	}
+
+	for _, table := range []string{"etl_calendar_windows", "etl_calendar_attachments"} {
+		if _, err := tx.ExecContext(c.Request.Context(), `DELETE FROM aquaflow.`+table+` WHERE calendar_id = $1`, calendarID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := insertCalendarContents(c.Request.Context(), tx, calendarID, req); err != nil {
+		calendarWriteError(c, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Blackout calendar updated",
+		"calendar_id", calendarID, "calendar_name", req.CalendarName, "windows", len(req.Windows))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Calendar updated successfully",
+		"calendar_id": calendarID,
+	})
+}
+
+// DeleteCalendar removes a blackout calendar, releasing the schedules and
+// jobs it was attached to. Runs it already deferred keep waiting until
+// their deferral ends.
+func (h *ETLHandler) DeleteCalendar(c *gin.Context) {
+	calendarID := c.Param("id")
+	if _, err := uuid.Parse(calendarID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid calendar ID"})
+		return
+	}
+
+	result, err := h.db.ExecContext(c.Request.Context(), `DELETE FROM aquaflow.etl_calendars WHERE calendar_id = $1`, calendarID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Blackout calendar deleted", "calendar_id", calendarID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Calendar deleted successfully",
+		"calendar_id": calendarID,
+	})
+}
+
+// GetBlockedFires lists the upcoming fires of active schedules that land in
+// a window of an active calendar, soonest first, with what the scheduler
+// will do about them. It looks ahead to until (RFC3339, default a week) and
+// can be narrowed to a schedule_id or job_id.
+func (h *ETLHandler) GetBlockedFires(c *gin.Context) {
+	scheduleID := c.Query("schedule_id")
+	jobID := c.Query("job_id")
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(defaultBlockedHorizon)
+	if v := c.Query("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 time"})
+			return
+		}
+	}
+	if until.Sub(now) > maxBlockedHorizon {
+		until = now.Add(maxBlockedHorizon)
+	}
+
+	query := `
+		SELECT s.schedule_id, s.schedule_name, s.job_id, j.job_name, s.timezone, s.next_run,
+		       s.schedule_kind, COALESCE(s.cron_expression, ''), COALESCE(s.interval_seconds, 0), s.anchor_at, s.run_at,
+		       (SELECT max(r.deferred_until) FROM aquaflow.etl_job_runs r
+		        WHERE r.schedule_id = s.schedule_id AND r.status = 'waiting'),
+		       c.calendar_id, c.calendar_name, c.action,
+		       w.window_id, w.window_name, w.reason, w.starts_at, w.ends_at, w.recurrence, w.duration_seconds, w.timezone
+		FROM aquaflow.etl_schedules s
+		JOIN aquaflow.etl_jobs_v2 j ON s.job_id = j.job_id
+		JOIN aquaflow.etl_calendar_attachments a ON a.schedule_id = s.schedule_id OR a.job_id = s.job_id
+		JOIN aquaflow.etl_calendars c ON a.calendar_id = c.calendar_id AND c.is_active = true
+		JOIN aquaflow.etl_calendar_windows w ON w.calendar_id = c.calendar_id
+		WHERE s.is_active = true AND j.is_active = true AND s.next_run IS NOT NULL
+	`
+	args := []interface{}{}
+	argCount := 0
+
+	if scheduleID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND s.schedule_id = $%d", argCount)
+		args = append(args, scheduleID)
+	}
+
+	if jobID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND s.job_id = $%d", argCount)
+		args = append(args, jobID)
+	}
+
+	query += " ORDER BY s.schedule_id, c.calendar_name, w.window_name"
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var schedules []*blackoutSchedule
+	byID := map[string]*blackoutSchedule{}
+	for rows.Next() {
+		var fire BlockedFire
+		var timezone string
+		var nextRun time.Time
+		var spec cron.Spec
+		var deferredUntil *time.Time
+		var w CalendarWindow
+		if err := rows.Scan(&fire.ScheduleID, &fire.ScheduleName, &fire.JobID, &fire.JobName, &timezone, &nextRun,
+			&spec.Kind, &spec.Expression, &spec.IntervalSeconds, &spec.AnchorAt, &spec.RunAt, &deferredUntil,
+			&fire.CalendarID, &fire.CalendarName, &fire.Action,
+			&w.WindowID, &w.WindowName, &w.Reason, &w.StartsAt, &w.EndsAt, &w.Recurrence, &w.DurationSeconds, &w.Timezone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		sw, ok := byID[fire.ScheduleID]
+		if !ok {
+			sw = &blackoutSchedule{timezone: timezone, nextRun: nextRun, spec: spec, deferredUntil: deferredUntil, seen: map[string]bool{}}
+			byID[fire.ScheduleID] = sw
+			schedules = append(schedules, sw)
+		}
+		// A calendar attached to both the schedule and its job counts once
+		if sw.seen[w.WindowID] {
+			continue
+		}
+		sw.seen[w.WindowID] = true
+
+		window, err := w.window()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		fire.WindowID, fire.WindowName, fire.Reason = w.WindowID, w.WindowName, w.Reason
+		sw.windows = append(sw.windows, window)
+		sw.details = append(sw.details, fire)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Walk each schedule's fires the way the scheduler will
+	parser := cron.NewParser()
+	blocked := []BlockedFire{}
+	for _, sw := range schedules {
+		fires, err := sw.blockedFires(parser, until)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		blocked = append(blocked, fires...)
+	}
+
+	sort.SliceStable(blocked, func(i, j int) bool { return blocked[i].FireTime.Before(blocked[j].FireTime) })
+	if len(blocked) > limit {
+		blocked = blocked[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blocked_fires": blocked,
+		"count":         len(blocked),
+		"until":         until,
+	})
+}
+
+// validateCalendarWindows writes a 400 response and returns false if any
+// window is invalid, as the scheduler would reject it
+func validateCalendarWindows(c *gin.Context, windows []CalendarWindow) bool {
+	parser := cron.NewParser()
+	for _, w := range windows {
+		window, err := w.window()
+		if err == nil {
+			err = parser.ValidateWindow(window)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "window_name": w.WindowName})
+			return false
+		}
+	}
+	return true
+}
+
+// insertCalendarContents writes a calendar's windows and attachments as part
+// of tx
+func insertCalendarContents(ctx context.Context, tx *sql.Tx, calendarID string, req CalendarRequest) error {
+	windowQuery := `
+		INSERT INTO aquaflow.etl_calendar_windows (calendar_id, window_name, reason, starts_at, ends_at,
+			recurrence, duration_seconds, timezone)
+		VALUES ($1, $2, $3, $4, $5, NULLIF(TRIM($6), ''), $7, COALESCE(NULLIF($8, ''), 'UTC'))
+	`
+	for _, w := range req.Windows {
+		if _, err := tx.ExecContext(ctx, windowQuery, calendarID, w.WindowName, w.Reason, w.StartsAt, w.EndsAt,
+			w.Recurrence, w.DurationSeconds, w.Timezone); err != nil {
+			return err
+		}
+	}
+
+	attachmentQuery := `
+		INSERT INTO aquaflow.etl_calendar_attachments (calendar_id, schedule_id, job_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	for _, scheduleID := range req.ScheduleIDs {
+		if _, err := tx.ExecContext(ctx, attachmentQuery, calendarID, scheduleID, nil); err != nil {
+			return err
+		}
+	}
+	for _, jobID := range req.JobIDs {
+		if _, err := tx.ExecContext(ctx, attachmentQuery, calendarID, nil, jobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calendarWriteError reports a failed calendar write, naming the constraint
+// that rejected it
+func calendarWriteError(c *gin.Context, err error) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			c.JSON(http.StatusConflict, gin.H{"error": "a calendar with this name already exists"})
+			return
+		case "23503":
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "schedule or job not found", "details": pqErr.Detail})
+			return
+		case "23514":
+			c.JSON(http.StatusBadRequest, gin.H{"error": pqErr.Message})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
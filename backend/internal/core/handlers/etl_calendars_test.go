@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+)
+
+func TestBlockedFiresPreview(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	oneOff := func(name, action string, from, to int) (cron.Window, BlockedFire) {
+		return cron.Window{Start: ptr(at(from)), End: ptr(at(to)), Location: time.UTC},
+			BlockedFire{WindowName: name, Action: action}
+	}
+
+	type want struct {
+		hour   int
+		action string
+		merged bool
+		until  int
+	}
+	tests := []struct {
+		name          string
+		windows       []string
+		action        string
+		deferredUntil *time.Time
+		want          []want
+	}{
+		{
+			name:    "skip calendar skips every fire in the window",
+			windows: []string{"maintenance"},
+			action:  "skip",
+			want:    []want{{2, "skip", false, 5}, {3, "skip", false, 5}, {4, "skip", false, 5}},
+		},
+		{
+			name:    "defer calendar merges later fires into the first",
+			windows: []string{"maintenance"},
+			action:  "defer",
+			want:    []want{{2, "defer", false, 5}, {3, "skip", true, 5}, {4, "skip", true, 5}},
+		},
+		{
+			name:    "back-to-back windows defer until both have closed",
+			windows: []string{"maintenance", "follow-up"},
+			action:  "defer",
+			want: []want{{2, "defer", false, 7}, {3, "skip", true, 7}, {4, "skip", true, 7},
+				{5, "skip", true, 7}, {6, "skip", true, 7}},
+		},
+		{
+			name:          "fires merge into a run already waiting",
+			windows:       []string{"maintenance"},
+			action:        "defer",
+			deferredUntil: ptr(at(5)),
+			want:          []want{{2, "skip", true, 5}, {3, "skip", true, 5}, {4, "skip", true, 5}},
+		},
+	}
+
+	spans := map[string][2]int{"maintenance": {2, 5}, "follow-up": {5, 7}}
+	parser := cron.NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := &blackoutSchedule{
+				timezone:      "UTC",
+				nextRun:       at(0),
+				spec:          cron.Spec{Kind: cron.KindDescriptor, Expression: "@hourly"},
+				deferredUntil: tt.deferredUntil,
+			}
+			for _, name := range tt.windows {
+				window, detail := oneOff(name, tt.action, spans[name][0], spans[name][1])
+				bs.windows = append(bs.windows, window)
+				bs.details = append(bs.details, detail)
+			}
+
+			fires, err := bs.blockedFires(parser, at(10))
+			if err != nil {
+				t.Fatalf("blockedFires: %v", err)
+			}
+			if len(fires) != len(tt.want) {
+				t.Fatalf("got %d blocked fires, want %d: %+v", len(fires), len(tt.want), fires)
+			}
+			for i, w := range tt.want {
+				got := fires[i]
+				if !got.FireTime.Equal(at(w.hour)) || got.Action != w.action || got.Merged != w.merged || !got.Until.Equal(at(w.until)) {
+					t.Errorf("fire %d = %s %s merged=%v until %s, want %02d:00 %s merged=%v until %02d:00",
+						i, got.FireTime.Format("15:04"), got.Action, got.Merged, got.Until.Format("15:04"),
+						w.hour, w.action, w.merged, w.until)
+				}
+			}
+		})
+	}
+}
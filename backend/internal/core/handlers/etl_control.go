@@ -50,7 +50,7 @@ func (h *ETLControlHandler) PauseJob(c *gin.Context) {
 		user = "system"
 	}
 	
-	_, err := h.db.Exec(query, jobName, user, req.Reason)
+	_, err := h.db.ExecContext(c.Request.Context(), query, jobName, user, req.Reason)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -79,7 +79,7 @@ func (h *ETLControlHandler) ResumeJob(c *gin.Context) {
 		WHERE job_name = $1
 	`
 	
-	result, err := h.db.Exec(query, jobName)
+	result, err := h.db.ExecContext(c.Request.Context(), query, jobName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -105,7 +105,7 @@ func (h *ETLControlHandler) GetJobControls(c *gin.Context) {
 		ORDER BY job_name
 	`
 	
-	rows, err := h.db.Query(query)
+	rows, err := h.db.QueryContext(c.Request.Context(), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
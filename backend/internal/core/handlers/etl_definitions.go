@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aquaflow/etl-common/jobschema"
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type JobType struct {
+	JobType         string          `json:"job_type"`
+	Description     *string         `json:"description,omitempty"`
+	ParameterSchema json.RawMessage `json:"parameter_schema"`
+	RegisteredBy    *string         `json:"registered_by,omitempty"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// JobDefinitionRequest is the body for creating or updating a job definition
+type JobDefinitionRequest struct {
+	JobName     string                 `json:"job_name" binding:"required"`
+	JobType     string                 `json:"job_type" binding:"required"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	Tags        []string               `json:"tags"`
+	IsActive    *bool                  `json:"is_active"`
+	LogLevel    *string                `json:"log_level" binding:"omitempty,oneof=DEBUG INFO WARN ERROR"`
+	// OverlapPolicy decides what happens when a run is due while an earlier
+	// one is active; defaults to allow
+	OverlapPolicy *string `json:"overlap_policy" binding:"omitempty,oneof=allow skip queue replace"`
+	// MaxConcurrentRuns caps the runs workers execute at once; nil for no limit
+	MaxConcurrentRuns *int `json:"max_concurrent_runs" binding:"omitempty,min=1"`
+	// Priority of the job's runs, 1 (lowest) to 10 (highest); defaults to 5
+	Priority *int `json:"priority" binding:"omitempty,min=1,max=10"`
+}
+
+// ManualRunRequest is the body for triggering a job definition manually
+type ManualRunRequest struct {
+	RunName    string                 `json:"run_name"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// DryRun fetches, maps and validates data without writing it
+	DryRun bool `json:"dry_run"`
+	// LogLevel overrides the job's minimum log level for this run
+	LogLevel *string `json:"log_level" binding:"omitempty,oneof=DEBUG INFO WARN ERROR"`
+	// Priority overrides the job's priority for this run
+	Priority *int `json:"priority" binding:"omitempty,min=1,max=10"`
+}
+
+// GetJobTypes returns the registered job types and their parameter schemas
+func (h *ETLHandler) GetJobTypes(c *gin.Context) {
+	query := `
+		SELECT job_type, description, parameter_schema, registered_by, updated_at
+		FROM aquaflow.etl_job_types
+		ORDER BY job_type
+	`
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	types := []JobType{}
+	for rows.Next() {
+		var jobType JobType
+		var schemaJSON []byte
+
+		if err := rows.Scan(&jobType.JobType, &jobType.Description, &schemaJSON, &jobType.RegisteredBy, &jobType.UpdatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		jobType.ParameterSchema = json.RawMessage(schemaJSON)
+
+		types = append(types, jobType)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_types": types,
+		"count":     len(types),
+	})
+}
+
+// CreateJobDefinition creates a job definition after validating its parameters
+func (h *ETLHandler) CreateJobDefinition(c *gin.Context) {
+	var req JobDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !h.validateParameters(c, req.JobType, req.Parameters, jobschema.ModeDefinition) {
+		return
+	}
+
+	paramsJSON, err := json.Marshal(nonNilParams(req.Parameters))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	user := c.GetString("userID")
+	if user == "" {
+		user = "system"
+	}
+
+	var jobID string
+	query := `
+		INSERT INTO aquaflow.etl_jobs_v2 (job_name, job_type, description, parameters, tags, is_active, created_by, log_level,
+			overlap_policy, max_concurrent_runs, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, 'allow'), $10, COALESCE($11, 5))
+		RETURNING job_id
+	`
+	err = h.db.QueryRowContext(c.Request.Context(), query, req.JobName, req.JobType, req.Description, paramsJSON,
+		pq.Array(nonNilTags(req.Tags)), isActive, user, req.LogLevel,
+		req.OverlapPolicy, req.MaxConcurrentRuns, req.Priority).Scan(&jobID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "a job with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Job definition created successfully",
+		"job_id":  jobID,
+	})
+}
+
+// UpdateJobDefinition replaces a job definition after validating its parameters
+func (h *ETLHandler) UpdateJobDefinition(c *gin.Context) {
+	jobID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	var req JobDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !h.validateParameters(c, req.JobType, req.Parameters, jobschema.ModeDefinition) {
+		return
+	}
+
+	paramsJSON, err := json.Marshal(nonNilParams(req.Parameters))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := `
+		UPDATE aquaflow.etl_jobs_v2
+		SET job_name = $2, job_type = $3, description = $4, parameters = $5,
+			tags = $6, is_active = COALESCE($7, is_active), log_level = $8,
+			overlap_policy = COALESCE($9, 'allow'), max_concurrent_runs = $10,
+			priority = COALESCE($11, 5), version = version + 1
+		WHERE job_id = $1
+	`
+	result, err := h.db.ExecContext(c.Request.Context(), query, jobID, req.JobName, req.JobType, req.Description, paramsJSON,
+		pq.Array(nonNilTags(req.Tags)), req.IsActive, req.LogLevel,
+		req.OverlapPolicy, req.MaxConcurrentRuns, req.Priority)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "a job with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job definition not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job definition updated successfully",
+		"job_id":  jobID,
+	})
+}
+
+// RunJobDefinition queues a manual run, merging any parameter overrides over
+// the job defaults and validating the result before it reaches a worker
+func (h *ETLHandler) RunJobDefinition(c *gin.Context) {
+	jobID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	var req ManualRunRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+			return
+		}
+	}
+
+	var jobName, jobType string
+	var paramsJSON []byte
+	var priority int
+	query := `SELECT job_name, job_type, parameters, priority FROM aquaflow.etl_jobs_v2 WHERE job_id = $1`
+	err := h.db.QueryRowContext(c.Request.Context(), query, jobID).Scan(&jobName, &jobType, &paramsJSON, &priority)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job definition not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := map[string]interface{}{}
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	for key, value := range req.Parameters {
+		params[key] = value
+	}
+
+	if !h.validateParameters(c, jobType, params, jobschema.ModeRun) {
+		return
+	}
+
+	runtimeJSON, err := json.Marshal(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	runName := req.RunName
+	if runName == "" {
+		trigger := "Manual"
+		if req.DryRun {
+			trigger = "Dry Run"
+		}
+		runName = jobName + " - " + time.Now().Format("2006-01-02 15:04") + " (" + trigger + ")"
+	}
+
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+
+	newRunID := uuid.New()
+	insertQuery := `
+		INSERT INTO aquaflow.etl_job_runs (run_id, job_id, run_name, runtime_parameters, status, trigger_type, dry_run, log_level, priority,
+			trace_context)
+		VALUES ($1, $2, $3, $4, 'queued', 'manual', $5, $6, $7, $8)
+	`
+	if _, err := h.db.ExecContext(c.Request.Context(), insertQuery, newRunID, jobID, runName, runtimeJSON, req.DryRun, req.LogLevel, priority,
+		tracing.Inject(c.Request.Context())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Run queued",
+		logging.KeyRunID, newRunID.String(), logging.KeyJobName, jobName, "dry_run", req.DryRun)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Job run queued successfully",
+		"run_id":   newRunID.String(),
+		"job_id":   jobID,
+		"dry_run":  req.DryRun,
+		"priority": priority,
+	})
+}
+
+// validateParameters checks parameters against the job type schema and writes
+// a 422 response with field-level errors when they are invalid
+func (h *ETLHandler) validateParameters(c *gin.Context, jobType string, params map[string]interface{}, mode jobschema.Mode) bool {
+	var schema []byte
+	err := h.db.QueryRowContext(c.Request.Context(), `SELECT parameter_schema FROM aquaflow.etl_job_types WHERE job_type = $1`, jobType).Scan(&schema)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "invalid job parameters",
+			"details": []jobschema.FieldError{
+				{Field: "job_type", Message: "unknown job type"},
+			},
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	err = jobschema.Validate(jobType, schema, params, mode)
+	var validationErr *jobschema.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "invalid job parameters",
+			"details": validationErr.Errors,
+		})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
+
+func nonNilParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return map[string]interface{}{}
+	}
+	return params
+}
+
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// defaultFreshnessSeconds is used when a dependency does not set a window
+const defaultFreshnessSeconds = 86400
+
+// JobDependency is an edge of the job DAG: JobID waits for UpstreamJobID
+type JobDependency struct {
+	JobID            string    `json:"job_id"`
+	JobName          string    `json:"job_name"`
+	UpstreamJobID    string    `json:"upstream_job_id"`
+	UpstreamJobName  string    `json:"upstream_job_name"`
+	Condition        string    `json:"condition"`
+	FreshnessSeconds int       `json:"freshness_seconds"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// DAGNode is a job in the dependency graph with its current run state
+type DAGNode struct {
+	JobID         string     `json:"job_id"`
+	JobName       string     `json:"job_name"`
+	JobType       string     `json:"job_type"`
+	IsActive      bool       `json:"is_active"`
+	LastRunStatus *string    `json:"last_run_status,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	WaitingRuns   []JobRun   `json:"waiting_runs"`
+}
+
+// DependencyRequest replaces the upstream jobs of a job definition
+type DependencyRequest struct {
+	Dependencies []DependencyInput `json:"dependencies"`
+}
+
+// DependencyInput declares one upstream job
+type DependencyInput struct {
+	UpstreamJobID string `json:"upstream_job_id" binding:"required,uuid"`
+	// Condition is "success" (default) or "always"
+	Condition string `json:"condition" binding:"omitempty,oneof=success always"`
+	// FreshnessSeconds is how long before the scheduled time the upstream
+	// run may have finished; defaults to one day
+	FreshnessSeconds int `json:"freshness_seconds" binding:"omitempty,min=1"`
+}
+
+const dependencyColumns = `
+		d.job_id, j.job_name, d.upstream_job_id, u.job_name,
+		d.condition, d.freshness_seconds, d.created_at
+`
+
+func scanDependencies(rows *sql.Rows) ([]JobDependency, error) {
+	deps := []JobDependency{}
+	for rows.Next() {
+		var dep JobDependency
+		if err := rows.Scan(&dep.JobID, &dep.JobName, &dep.UpstreamJobID, &dep.UpstreamJobName,
+			&dep.Condition, &dep.FreshnessSeconds, &dep.CreatedAt); err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, rows.Err()
+}
+
+// GetJobDAG returns every job with the dependency edges between them and the
+// runs currently waiting for their upstreams
+func (h *ETLHandler) GetJobDAG(c *gin.Context) {
+	nodeQuery := `
+		SELECT j.job_id, j.job_name, j.job_type, j.is_active, lr.status, lr.started_at
+		FROM aquaflow.etl_jobs_v2 j
+		LEFT JOIN LATERAL (
+			SELECT r.status, r.started_at
+			FROM aquaflow.etl_job_runs r
+			WHERE r.job_id = j.job_id AND r.status <> 'waiting'
+			ORDER BY r.started_at DESC
+			LIMIT 1
+		) lr ON true
+		ORDER BY j.job_name
+	`
+
+	rows, err := h.db.QueryContext(c.Request.Context(), nodeQuery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	nodes := []DAGNode{}
+	index := map[string]int{}
+	for rows.Next() {
+		node := DAGNode{WaitingRuns: []JobRun{}}
+		if err := rows.Scan(&node.JobID, &node.JobName, &node.JobType, &node.IsActive,
+			&node.LastRunStatus, &node.LastRunAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		index[node.JobID] = len(nodes)
+		nodes = append(nodes, node)
+	}
+
+	edgeRows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT `+dependencyColumns+`
+		FROM aquaflow.etl_job_dependencies d
+		JOIN aquaflow.etl_jobs_v2 j ON d.job_id = j.job_id
+		JOIN aquaflow.etl_jobs_v2 u ON d.upstream_job_id = u.job_id
+		ORDER BY j.job_name, u.job_name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer edgeRows.Close()
+
+	edges, err := scanDependencies(edgeRows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	waitingRows, err := h.db.QueryContext(c.Request.Context(), `
+		SELECT `+jobRunColumns+`
+		FROM aquaflow.etl_job_runs r
+		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
+		WHERE r.status = 'waiting'
+		ORDER BY r.started_at ASC
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer waitingRows.Close()
+
+	waitingCount := 0
+	for waitingRows.Next() {
+		run, err := scanJobRun(waitingRows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if i, ok := index[run.JobID]; ok {
+			nodes[i].WaitingRuns = append(nodes[i].WaitingRuns, run)
+			waitingCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"nodes":        nodes,
+		"edges":        edges,
+		"waiting_runs": waitingCount,
+	})
+}
+
+// GetJobDependencies returns the upstream and downstream jobs of a job definition
+func (h *ETLHandler) GetJobDependencies(c *gin.Context) {
+	jobID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	if !h.jobDefinitionExists(c, jobID) {
+		return
+	}
+
+	upstream, err := h.queryDependencies(c.Request.Context(), "d.job_id = $1", jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	downstream, err := h.queryDependencies(c.Request.Context(), "d.upstream_job_id = $1", jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":     jobID,
+		"upstream":   upstream,
+		"downstream": downstream,
+	})
+}
+
+// SetJobDependencies replaces the upstream jobs of a job definition. Changes
+// that would make the graph cyclic are rejected.
+func (h *ETLHandler) SetJobDependencies(c *gin.Context) {
+	jobID := c.Param("id")
+
+	// Validate UUID format
+	if _, err := uuid.Parse(jobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	var req DependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if !h.jobDefinitionExists(c, jobID) {
+		return
+	}
+
+	upstreams := make([]string, 0, len(req.Dependencies))
+	seen := map[string]bool{}
+	for i := range req.Dependencies {
+		dep := &req.Dependencies[i]
+		dep.UpstreamJobID = strings.ToLower(dep.UpstreamJobID)
+		if dep.UpstreamJobID == strings.ToLower(jobID) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "a job cannot depend on itself"})
+			return
+		}
+		if seen[dep.UpstreamJobID] {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "duplicate upstream job", "upstream_job_id": dep.UpstreamJobID})
+			return
+		}
+		seen[dep.UpstreamJobID] = true
+		if dep.Condition == "" {
+			dep.Condition = "success"
+		}
+		if dep.FreshnessSeconds == 0 {
+			dep.FreshnessSeconds = defaultFreshnessSeconds
+		}
+		upstreams = append(upstreams, dep.UpstreamJobID)
+	}
+
+	tx, err := h.db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	// Serialize graph changes so two concurrent updates can't close a cycle
+	if _, err := tx.ExecContext(c.Request.Context(), `LOCK TABLE aquaflow.etl_job_dependencies IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if cycle, err := findDependencyCycle(c.Request.Context(), tx, strings.ToLower(jobID), upstreams); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if cycle != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "dependencies would create a cycle",
+			"cycle": cycle,
+		})
+		return
+	}
+
+	if _, err := tx.ExecContext(c.Request.Context(), `DELETE FROM aquaflow.etl_job_dependencies WHERE job_id = $1`, jobID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := c.GetString("userID")
+	if user == "" {
+		user = "system"
+	}
+
+	insertQuery := `
+		INSERT INTO aquaflow.etl_job_dependencies (job_id, upstream_job_id, condition, freshness_seconds, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, dep := range req.Dependencies {
+		if _, err := tx.ExecContext(c.Request.Context(), insertQuery, jobID, dep.UpstreamJobID, dep.Condition, dep.FreshnessSeconds, user); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "upstream job not found", "upstream_job_id": dep.UpstreamJobID})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	upstream, err := h.queryDependencies(c.Request.Context(), "d.job_id = $1", jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Job dependencies updated successfully",
+		"job_id":   jobID,
+		"upstream": upstream,
+	})
+}
+
+func (h *ETLHandler) queryDependencies(ctx context.Context, where string, jobID string) ([]JobDependency, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT `+dependencyColumns+`
+		FROM aquaflow.etl_job_dependencies d
+		JOIN aquaflow.etl_jobs_v2 j ON d.job_id = j.job_id
+		JOIN aquaflow.etl_jobs_v2 u ON d.upstream_job_id = u.job_id
+		WHERE `+where+`
+		ORDER BY j.job_name, u.job_name
+	`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDependencies(rows)
+}
+
+// jobDefinitionExists writes a 404 response and returns false when the job
+// definition does not exist
+func (h *ETLHandler) jobDefinitionExists(c *gin.Context, jobID string) bool {
+	var exists bool
+	err := h.db.QueryRowContext(c.Request.Context(), `SELECT EXISTS (SELECT 1 FROM aquaflow.etl_jobs_v2 WHERE job_id = $1)`, jobID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job definition not found"})
+		return false
+	}
+	return true
+}
+
+// findDependencyCycle checks whether giving jobID the upstreams would make
+// the graph cyclic, and returns the job IDs along the cycle if so
+func findDependencyCycle(ctx context.Context, tx *sql.Tx, jobID string, upstreams []string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT job_id, upstream_job_id FROM aquaflow.etl_job_dependencies WHERE job_id <> $1`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := map[string][]string{jobID: upstreams}
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			return nil, err
+		}
+		graph[from] = append(graph[from], to)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return dependencyCycle(graph, jobID), nil
+}
+
+// dependencyCycle returns the job IDs along a cycle through jobID in graph,
+// which maps each job to its upstream jobs, or nil if there is none
+func dependencyCycle(graph map[string][]string, jobID string) []string {
+	// Walk upstream from jobID; reaching it again closes a cycle
+	visited := map[string]bool{}
+	var walk func(node string, path []string) []string
+	walk = func(node string, path []string) []string {
+		for _, next := range graph[node] {
+			if next == jobID {
+				return append(path, next)
+			}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if cycle := walk(next, append(path, next)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return walk(jobID, []string{jobID})
+}
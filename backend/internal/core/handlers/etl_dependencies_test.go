@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph map[string][]string
+		job   string
+		want  []string
+	}{
+		{"no upstreams", map[string][]string{"a": nil}, "a", nil},
+		{"chain", map[string][]string{"a": {"b"}, "b": {"c"}}, "a", nil},
+		{"diamond", map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, "a", nil},
+		{"self dependency", map[string][]string{"a": {"a"}}, "a", []string{"a", "a"}},
+		{"two jobs", map[string][]string{"a": {"b"}, "b": {"a"}}, "a", []string{"a", "b", "a"}},
+		{"long cycle", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"a"}}, "a", []string{"a", "b", "c", "d", "a"}},
+		{"cycle after a dead end", map[string][]string{"a": {"x", "b"}, "x": {"y"}, "b": {"a"}}, "a", []string{"a", "b", "a"}},
+		{"cycle not through the job", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}}, "a", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependencyCycle(tt.graph, tt.job); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencyCycle = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
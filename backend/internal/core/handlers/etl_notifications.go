@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/google/uuid"
+)
+
+// Notification is an event raised for someone to investigate, such as a
+// schedule disabled after repeated failures
+type Notification struct {
+	NotificationID string          `json:"notification_id"`
+	EventType      string          `json:"event_type"`
+	Severity       string          `json:"severity"`
+	Message        string          `json:"message"`
+	ScheduleID     *string         `json:"schedule_id,omitempty"`
+	JobID          *string         `json:"job_id,omitempty"`
+	RunID          *string         `json:"run_id,omitempty"`
+	Details        json.RawMessage `json:"details,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy *string         `json:"acknowledged_by,omitempty"`
+}
+
+// GetNotifications returns notifications, newest first. By default only
+// unacknowledged ones are returned; ?acknowledged=all returns every one.
+func (h *ETLHandler) GetNotifications(c *gin.Context) {
+	acknowledged := c.DefaultQuery("acknowledged", "false")
+	eventType := c.Query("event_type")
+	severity := c.Query("severity")
+	limit, ok := queryLimit(c, 100, 1000)
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT notification_id, event_type, severity, message, schedule_id, job_id, run_id,
+			   details, created_at, acknowledged_at, acknowledged_by
+		FROM aquaflow.etl_notifications
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+	argCount := 0
+
+	switch acknowledged {
+	case "false":
+		query += " AND acknowledged_at IS NULL"
+	case "true":
+		query += " AND acknowledged_at IS NOT NULL"
+	}
+
+	if eventType != "" {
+		argCount++
+		query += fmt.Sprintf(" AND event_type = $%d", argCount)
+		args = append(args, eventType)
+	}
+
+	if severity != "" {
+		argCount++
+		query += fmt.Sprintf(" AND severity = $%d", argCount)
+		args = append(args, severity)
+	}
+
+	argCount++
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		var details []byte
+
+		err := rows.Scan(
+			&n.NotificationID, &n.EventType, &n.Severity, &n.Message, &n.ScheduleID, &n.JobID, &n.RunID,
+			&details, &n.CreatedAt, &n.AcknowledgedAt, &n.AcknowledgedBy,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if details != nil {
+			n.Details = json.RawMessage(details)
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"count":         len(notifications),
+	})
+}
+
+// AcknowledgeNotification marks a notification as handled by the caller
+func (h *ETLHandler) AcknowledgeNotification(c *gin.Context) {
+	notificationID := c.Param("id")
+	if _, err := uuid.Parse(notificationID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification ID"})
+		return
+	}
+
+	user := c.GetString("userID")
+	if user == "" {
+		user = "system"
+	}
+
+	query := `
+		UPDATE aquaflow.etl_notifications
+		SET acknowledged_at = COALESCE(acknowledged_at, NOW()),
+			acknowledged_by = COALESCE(acknowledged_by, $2)
+		WHERE notification_id = $1
+		RETURNING acknowledged_at, acknowledged_by
+	`
+	var acknowledgedAt time.Time
+	var acknowledgedBy string
+	err := h.db.QueryRowContext(c.Request.Context(), query, notificationID, user).Scan(&acknowledgedAt, &acknowledgedBy)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Notification acknowledged", "notification_id", notificationID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"notification_id": notificationID,
+		"acknowledged_at": acknowledgedAt,
+		"acknowledged_by": acknowledgedBy,
+	})
+}
+
+// EnableSchedule turns a schedule back on, typically one the scheduler
+// disabled after repeated failures, and resets its failure streak. Fires
+// missed while it was off are handled by its misfire policy.
+func (h *ETLHandler) EnableSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule ID"})
+		return
+	}
+
+	query := `
+		UPDATE aquaflow.etl_schedules
+		SET is_active = true,
+			consecutive_failures = 0,
+			disabled_at = NULL,
+			disabled_reason = NULL,
+			updated_at = NOW()
+		WHERE schedule_id = $1
+		RETURNING schedule_name
+	`
+	var scheduleName string
+	err := h.db.QueryRowContext(c.Request.Context(), query, scheduleID).Scan(&scheduleName)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Schedule enabled", "schedule_id", scheduleID, "schedule_name", scheduleName)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("Schedule '%s' has been enabled", scheduleName),
+		"schedule_id":   scheduleID,
+		"schedule_name": scheduleName,
+	})
+}
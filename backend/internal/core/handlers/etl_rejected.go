@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aquaflow/etl-common/jobschema"
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/tracing"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type RejectedRecord struct {
+	RecordID        int64           `json:"record_id"`
+	RunID           string          `json:"run_id"`
+	SeriesID        *int            `json:"series_id,omitempty"`
+	TimePoint       *time.Time      `json:"time_point,omitempty"`
+	RawPayload      json.RawMessage `json:"raw_payload"`
+	Reason          string          `json:"reason"`
+	ErrorCategory   string          `json:"error_category"`
+	Status          string          `json:"status"`
+	ReplayAttempts  int             `json:"replay_attempts"`
+	LastReplayAt    *time.Time      `json:"last_replay_at,omitempty"`
+	LastReplayError *string         `json:"last_replay_error,omitempty"`
+	ReplayRunID     *string         `json:"replay_run_id,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	RunName         string          `json:"run_name"`
+}
+
+// ReplayRequest selects rejected records to replay, either by ID or by filter
+type ReplayRequest struct {
+	RecordIDs     []int64 `json:"record_ids"`
+	RunID         string  `json:"run_id"`
+	SeriesID      *int    `json:"series_id"`
+	ErrorCategory string  `json:"error_category"`
+	Limit         int     `json:"limit"`
+}
+
+// queryLimit reads the limit query parameter, defaulting to def and capped
+// at max. It writes a 400 response and returns false if limit is not a
+// positive integer.
+func queryLimit(c *gin.Context, def, max int) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return def, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return 0, false
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit, true
+}
+
+// GetRejectedRecords returns dead-letter records with optional filtering
+func (h *ETLHandler) GetRejectedRecords(c *gin.Context) {
+	runID := c.Query("run_id")
+	seriesID := c.Query("series_id")
+	category := c.Query("category")
+	status := c.DefaultQuery("status", "pending")
+	limit, ok := queryLimit(c, 100, 1000)
+	if !ok {
+		return
+	}
+
+	query := `
+		SELECT rr.record_id, rr.run_id, rr.series_id, rr.time_point, rr.raw_payload, rr.reason,
+			   rr.error_category, rr.status, rr.replay_attempts, rr.last_replay_at,
+			   rr.last_replay_error, rr.replay_run_id, rr.created_at, r.run_name
+		FROM aquaflow.etl_rejected_records rr
+		JOIN aquaflow.etl_job_runs r ON rr.run_id = r.run_id
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+	argCount := 0
+
+	if runID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND rr.run_id = $%d", argCount)
+		args = append(args, runID)
+	}
+
+	if seriesID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND rr.series_id = $%d", argCount)
+		args = append(args, seriesID)
+	}
+
+	if category != "" {
+		argCount++
+		query += fmt.Sprintf(" AND rr.error_category = $%d", argCount)
+		args = append(args, category)
+	}
+
+	if status != "all" {
+		argCount++
+		query += fmt.Sprintf(" AND rr.status = $%d", argCount)
+		args = append(args, status)
+	}
+
+	argCount++
+	query += fmt.Sprintf(" ORDER BY rr.created_at DESC, rr.record_id DESC LIMIT $%d", argCount)
+	args = append(args, limit)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	records := []RejectedRecord{}
+	for rows.Next() {
+		var record RejectedRecord
+		var payload []byte
+
+		err := rows.Scan(
+			&record.RecordID, &record.RunID, &record.SeriesID, &record.TimePoint, &payload,
+			&record.Reason, &record.ErrorCategory, &record.Status, &record.ReplayAttempts,
+			&record.LastReplayAt, &record.LastReplayError, &record.ReplayRunID,
+			&record.CreatedAt, &record.RunName,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		record.RawPayload = json.RawMessage(payload)
+
+		records = append(records, record)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"count":   len(records),
+	})
+}
+
+// ReplayRejectedRecords queues a replay run for the selected pending records.
+// The worker re-runs them through the same validation and insert path.
+func (h *ETLHandler) ReplayRejectedRecords(c *gin.Context) {
+	var req ReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	if len(req.RecordIDs) == 0 && req.RunID == "" && req.SeriesID == nil && req.ErrorCategory == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "select records by record_ids, run_id, series_id or error_category"})
+		return
+	}
+	if req.RunID != "" {
+		if _, err := uuid.Parse(req.RunID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run ID format"})
+			return
+		}
+	}
+	if req.Limit <= 0 || req.Limit > 10000 {
+		req.Limit = 10000
+	}
+
+	query := `
+		SELECT record_id FROM aquaflow.etl_rejected_records
+		WHERE status = 'pending'
+	`
+
+	args := []interface{}{}
+	argCount := 0
+
+	if len(req.RecordIDs) > 0 {
+		argCount++
+		query += fmt.Sprintf(" AND record_id = ANY($%d)", argCount)
+		args = append(args, pq.Array(req.RecordIDs))
+	}
+
+	if req.RunID != "" {
+		argCount++
+		query += fmt.Sprintf(" AND run_id = $%d", argCount)
+		args = append(args, req.RunID)
+	}
+
+	if req.SeriesID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND series_id = $%d", argCount)
+		args = append(args, *req.SeriesID)
+	}
+
+	if req.ErrorCategory != "" {
+		argCount++
+		query += fmt.Sprintf(" AND error_category = $%d", argCount)
+		args = append(args, req.ErrorCategory)
+	}
+
+	argCount++
+	query += fmt.Sprintf(" ORDER BY record_id LIMIT $%d", argCount)
+	args = append(args, req.Limit)
+
+	rows, err := h.db.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	recordIDs := []interface{}{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		recordIDs = append(recordIDs, id)
+	}
+
+	if len(recordIDs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no pending rejected records match the selection"})
+		return
+	}
+
+	// Replays run as the system replay job so they show up like any other run
+	var jobID string
+	err = h.db.QueryRowContext(c.Request.Context(), `
+		SELECT job_id FROM aquaflow.etl_jobs_v2
+		WHERE job_type = 'replay_rejected' AND is_active = true
+		ORDER BY created_at LIMIT 1
+	`).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no active replay_rejected job is defined"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := map[string]interface{}{"record_ids": recordIDs}
+	if !h.validateParameters(c, "replay_rejected", params, jobschema.ModeRun) {
+		return
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	newRunID := uuid.New()
+	runName := fmt.Sprintf("Rejected Record Replay - %s (%d records)", time.Now().Format("2006-01-02 15:04"), len(recordIDs))
+	insertQuery := `
+		INSERT INTO aquaflow.etl_job_runs (run_id, job_id, run_name, runtime_parameters, status, trigger_type, trace_context)
+		VALUES ($1, $2, $3, $4, 'queued', 'manual', $5)
+	`
+	if _, err := h.db.ExecContext(c.Request.Context(), insertQuery, newRunID, jobID, runName, paramsJSON,
+		tracing.Inject(c.Request.Context())); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Replay run queued",
+		logging.KeyRunID, newRunID.String(), "record_count", len(recordIDs))
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Replay run queued successfully",
+		"run_id":       newRunID.String(),
+		"record_count": len(recordIDs),
+	})
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		query  string
+		want   int
+		wantOK bool
+	}{
+		{"", 100, true},
+		{"?limit=25", 25, true},
+		{"?limit=5000", 1000, true},
+		{"?limit=0", 0, false},
+		{"?limit=-1", 0, false},
+		{"?limit=10%3BDROP%20TABLE%20aquaflow.etl_job_runs", 0, false},
+		{"?limit=ALL", 0, false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/rejected"+tt.query, nil)
+
+		got, ok := queryLimit(c, 100, 1000)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("queryLimit(%q) = %d, %v; want %d, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+		if !ok && rec.Code != http.StatusBadRequest {
+			t.Errorf("queryLimit(%q) wrote status %d, want 400", tt.query, rec.Code)
+		}
+	}
+}
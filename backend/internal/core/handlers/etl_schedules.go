@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ScheduleRequest is the body for creating or updating a schedule. Which
+// timing fields apply depends on ScheduleKind, as in the scheduler.
+type ScheduleRequest struct {
+	JobID        string `json:"job_id"`
+	ScheduleName string `json:"schedule_name" binding:"required"`
+	// ScheduleKind is cron, descriptor, interval or once; defaults to cron
+	ScheduleKind   string `json:"schedule_kind" binding:"omitempty,oneof=cron descriptor interval once"`
+	CronExpression string `json:"cron_expression"`
+	// IntervalSeconds and AnchorAt belong to interval schedules; AnchorAt
+	// defaults to now
+	IntervalSeconds int        `json:"interval_seconds"`
+	AnchorAt        *time.Time `json:"anchor_at"`
+	// RunAt is the fire time of a one-time schedule
+	RunAt    *time.Time `json:"run_at"`
+	Timezone string     `json:"timezone"`
+	IsActive *bool      `json:"is_active"`
+
+	MisfirePolicy       *string `json:"misfire_policy" binding:"omitempty,oneof=fire_once fire_all skip"`
+	MaxCatchupRuns      *int    `json:"max_catchup_runs" binding:"omitempty,min=1"`
+	MisfireGraceSeconds *int    `json:"misfire_grace_seconds" binding:"omitempty,min=0"`
+	// AutoDisableAfter opts the schedule into being disabled after that many
+	// failures in a row; 0 opts out, and nil leaves it off on create and as
+	// it was on update
+	AutoDisableAfter *int `json:"auto_disable_after" binding:"omitempty,min=0"`
+}
+
+// spec returns the request's timing fields, applying the defaults
+func (req *ScheduleRequest) spec(now time.Time) cron.Spec {
+	if req.ScheduleKind == "" {
+		req.ScheduleKind = cron.KindCron
+	}
+	if req.ScheduleKind == cron.KindInterval && req.AnchorAt == nil {
+		anchor := now.Truncate(time.Second)
+		req.AnchorAt = &anchor
+	}
+	return cron.Spec{
+		Kind:            req.ScheduleKind,
+		Expression:      strings.TrimSpace(req.CronExpression),
+		IntervalSeconds: req.IntervalSeconds,
+		AnchorAt:        req.AnchorAt,
+		RunAt:           req.RunAt,
+	}
+}
+
+// nextRun validates the request's timing and returns the schedule's first
+// fire after now, or nil for a one-time schedule already past. It writes a
+// 400 response and returns false if the timing is invalid.
+func (req *ScheduleRequest) nextRun(c *gin.Context, now time.Time) (*time.Time, bool) {
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	loc, err := cron.LoadLocation(req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	spec := req.spec(now)
+	parser := cron.NewParser()
+	if err := parser.ValidateSpec(spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	next, err := parser.NextExecution(spec, now, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if next.IsZero() {
+		return nil, true
+	}
+	return &next, true
+}
+
+// CreateSchedule adds a schedule to a job definition
+func (h *ETLHandler) CreateSchedule(c *gin.Context) {
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	if _, err := uuid.Parse(req.JobID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job ID format"})
+		return
+	}
+
+	now := time.Now()
+	nextRun, ok := req.nextRun(c, now)
+	if !ok {
+		return
+	}
+	if nextRun == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "run_at must be in the future"})
+		return
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var scheduleID string
+	query := `
+		INSERT INTO aquaflow.etl_schedules (job_id, schedule_name, schedule_kind, cron_expression,
+			interval_seconds, anchor_at, run_at, timezone, is_active, next_run,
+			misfire_policy, max_catchup_runs, misfire_grace_seconds, auto_disable_after)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, 0), $6, $7, $8, $9, $10,
+			COALESCE($11, 'fire_once'), COALESCE($12, 24), COALESCE($13, 300),
+			NULLIF($14::int, 0))
+		RETURNING schedule_id
+	`
+	err := h.db.QueryRowContext(c.Request.Context(), query, req.JobID, req.ScheduleName, req.ScheduleKind,
+		strings.TrimSpace(req.CronExpression), req.IntervalSeconds, req.AnchorAt, req.RunAt, req.Timezone,
+		isActive, nextRun, req.MisfirePolicy, req.MaxCatchupRuns, req.MisfireGraceSeconds,
+		req.AutoDisableAfter).Scan(&scheduleID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				c.JSON(http.StatusConflict, gin.H{"error": "the job already has a schedule with this name"})
+				return
+			case "23503":
+				c.JSON(http.StatusNotFound, gin.H{"error": "job definition not found"})
+				return
+			case "23514":
+				c.JSON(http.StatusBadRequest, gin.H{"error": pqErr.Message})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Schedule created",
+		"schedule_id", scheduleID, "schedule_name", req.ScheduleName, "schedule_kind", req.ScheduleKind)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Schedule created successfully",
+		"schedule_id": scheduleID,
+		"next_run":    nextRun,
+	})
+}
+
+// UpdateSchedule replaces a schedule's name, timing and policies, and
+// recomputes its next run. The schedule keeps its job.
+func (h *ETLHandler) UpdateSchedule(c *gin.Context) {
+	scheduleID := c.Param("id")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule ID"})
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	nextRun, ok := req.nextRun(c, time.Now())
+	if !ok {
+		return
+	}
+
+	query := `
+		UPDATE aquaflow.etl_schedules
+		SET schedule_name = $2, schedule_kind = $3, cron_expression = NULLIF($4, ''),
+			interval_seconds = NULLIF($5, 0), anchor_at = $6, run_at = $7, timezone = $8,
+			is_active = COALESCE($9, is_active), next_run = $10,
+			misfire_policy = COALESCE($11, misfire_policy),
+			max_catchup_runs = COALESCE($12, max_catchup_runs),
+			misfire_grace_seconds = COALESCE($13, misfire_grace_seconds),
+			auto_disable_after = CASE WHEN $14::int IS NULL THEN auto_disable_after ELSE NULLIF($14::int, 0) END,
+			updated_at = NOW()
+		WHERE schedule_id = $1
+		RETURNING schedule_id
+	`
+	err := h.db.QueryRowContext(c.Request.Context(), query, scheduleID, req.ScheduleName, req.ScheduleKind,
+		strings.TrimSpace(req.CronExpression), req.IntervalSeconds, req.AnchorAt, req.RunAt, req.Timezone,
+		req.IsActive, nextRun, req.MisfirePolicy, req.MaxCatchupRuns, req.MisfireGraceSeconds,
+		req.AutoDisableAfter).Scan(&scheduleID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return
+	}
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				c.JSON(http.StatusConflict, gin.H{"error": "the job already has a schedule with this name"})
+				return
+			case "23514":
+				c.JSON(http.StatusBadRequest, gin.H{"error": pqErr.Message})
+				return
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Schedule updated",
+		"schedule_id", scheduleID, "schedule_name", req.ScheduleName, "schedule_kind", req.ScheduleKind)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Schedule updated successfully",
+		"schedule_id": scheduleID,
+		"next_run":    nextRun,
+	})
+}
@@ -0,0 +1,75 @@
+// Package logging sets up the API's process logger. It writes JSON lines to
+// stdout with the field names the scheduler and workers use, so a request
+// can be followed from the handler to the runs it created.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Field names shared by all services
+const (
+	KeyService   = "service"
+	KeyRunID     = "run_id"
+	KeyJobName   = "job_name"
+	KeySeriesID  = "series_id"
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+)
+
+type ctxKey struct{}
+
+// Init installs a JSON logger tagged with service as the slog default, which
+// also routes the standard log package through it. level is debug, info,
+// warn or error; empty means info.
+func Init(service, level string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl})
+	logger := slog.New(handler).With(KeyService, service)
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return logger, nil
+}
+
+// ParseLevel parses a LOG_LEVEL value; empty means info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid LOG_LEVEL %q", name)
+}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, which carries
+// request_id and user_id, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -53,6 +54,11 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 
 		c.Set("userID", userID)
 		c.Set("userRole", claims["role"])
+
+		// Later log entries for this request name the user
+		ctx := c.Request.Context()
+		logger := logging.FromContext(ctx).With(logging.KeyUserID, userID)
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, logger))
 		c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestDuration uses the label names shared with the scheduler and
+// worker metrics
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "aquaflow",
+	Subsystem: "http",
+	Name:      "request_duration_seconds",
+	Help:      "Latency of backend HTTP requests, by route.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "route", "code"})
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+}
+
+// MetricsMiddleware records the latency of every request under its route
+// template, so /api/etl/runs/:id is one series rather than one per run
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
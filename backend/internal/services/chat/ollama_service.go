@@ -4,11 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/gkalyan/aquaflow-analytics/internal/core/logging"
 	"github.com/google/uuid"
 	"github.com/ollama/ollama/api"
 )
@@ -83,7 +84,7 @@ func (s *OllamaService) ProcessMessage(ctx context.Context, req ChatRequest) (Ch
 	// Enrich context with relevant data based on user query
 	dataContext, err := s.enrichWithDataContext(ctx, req.Message)
 	if err != nil {
-		log.Printf("Warning: Failed to enrich data context: %v", err)
+		logging.FromContext(ctx).Warn("Failed to enrich data context", "error", err)
 	}
 
 
@@ -145,7 +146,7 @@ func (s *OllamaService) ProcessMessage(ctx context.Context, req ChatRequest) (Ch
 
 	// Save session
 	if err := s.saveSession(session); err != nil {
-		log.Printf("Failed to save session: %v", err)
+		logging.FromContext(ctx).Error("Failed to save session", "session_id", session.SessionID, "error", err)
 	}
 
 	// Check for clarification needs
@@ -341,18 +342,18 @@ func (s *OllamaService) getSession(sessionID string) (*ChatSession, error) {
 func (s *OllamaService) saveSession(session *ChatSession) error {
 	// For now, do nothing (in-memory only)
 	// TODO: Implement actual database storage
-	log.Printf("Session %s saved (in-memory)", session.SessionID)
+	slog.Debug("Session saved (in-memory)", "session_id", session.SessionID)
 	return nil
 }
 
 func (s *OllamaService) ProcessFeedback(ctx context.Context, feedback FeedbackRequest) error {
 	// For now, just log feedback
 	// TODO: Implement learning from feedback
-	log.Printf("Feedback received for session %s: helpful=%v", 
-		feedback.SessionID, feedback.IsHelpful)
+	logger := logging.FromContext(ctx).With("session_id", feedback.SessionID)
+	logger.Info("Feedback received", "helpful", feedback.IsHelpful)
 	
 	if feedback.UserCorrection != "" {
-		log.Printf("User correction: %s", feedback.UserCorrection)
+		logger.Info("User correction", "correction", feedback.UserCorrection)
 	}
 	
 	return nil
@@ -375,7 +376,7 @@ func (s *OllamaService) GetSessionInfo(sessionID string) (map[string]interface{}
 func (s *OllamaService) ClearSession(sessionID string) error {
 	// For now, do nothing (in-memory only)
 	// TODO: Implement actual session clearing
-	log.Printf("Session %s cleared", sessionID)
+	slog.Info("Session cleared", "session_id", sessionID)
 	return nil
 }
 
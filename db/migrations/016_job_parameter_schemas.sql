@@ -0,0 +1,34 @@
+-- =====================================================
+-- JOB TYPES AND PARAMETER SCHEMAS
+-- =====================================================
+-- Each job type publishes a JSON Schema describing its parameters.
+-- The ETL workers upsert their schemas on startup; the backend validates
+-- job definitions and manual runs against them, and the scheduler
+-- validates runtime parameters after dynamic placeholder expansion.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_job_types (
+    job_type VARCHAR(50) PRIMARY KEY,
+    description TEXT,
+    parameter_schema JSONB NOT NULL DEFAULT '{}',
+    registered_by VARCHAR(100) DEFAULT 'system',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_etl_job_types_updated_at BEFORE UPDATE ON aquaflow.etl_job_types FOR EACH ROW EXECUTE FUNCTION aquaflow.update_updated_at_column();
+
+-- Seed the job types allowed by etl_jobs_v2. Workers replace the empty
+-- schemas with their published ones when they start.
+INSERT INTO aquaflow.etl_job_types (job_type, description) VALUES
+('historical_load', 'Paged load of historical values for a date range'),
+('realtime_sync', 'Fetch of the latest value for each series'),
+('data_validation', 'Data validation (no worker implementation yet)'),
+('cleanup', 'Cleanup (no worker implementation yet)')
+ON CONFLICT (job_type) DO NOTHING;
+
+-- Job definitions must reference a known job type
+ALTER TABLE aquaflow.etl_jobs_v2
+ADD CONSTRAINT fk_etl_jobs_v2_job_type FOREIGN KEY (job_type) REFERENCES aquaflow.etl_job_types(job_type);
+
+COMMENT ON TABLE aquaflow.etl_job_types IS 'Job types and the JSON Schema of their parameters - published by ETL workers';
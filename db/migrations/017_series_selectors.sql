@@ -0,0 +1,40 @@
+-- =====================================================
+-- DYNAMIC SERIES SELECTORS
+-- =====================================================
+-- Jobs can select series by dataset, parameter, dimensions and tags
+-- instead of hard-coding series_ids. Workers resolve the selector when a
+-- run starts and record the resolved list on the run.
+-- =====================================================
+
+-- Free-form tags on series, matched by the "tags" selector field
+ALTER TABLE aquaflow.series
+ADD COLUMN IF NOT EXISTS tags TEXT[] DEFAULT ARRAY[]::TEXT[];
+
+CREATE INDEX IF NOT EXISTS idx_series_tags ON aquaflow.series USING GIN(tags);
+
+-- Series a run actually processed after selector resolution
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS resolved_series_ids INTEGER[];
+
+-- Replace the drifted hard-coded IDs on the seeded jobs with selectors
+UPDATE aquaflow.etl_jobs_v2
+SET parameters = (parameters - 'series_ids') || '{
+        "series_selector": [
+            {"dataset": "Main Canal"},
+            {"dataset": "Don Pedro Reservoir"},
+            {"dataset": "Pump Stations"},
+            {"dataset": "Lateral Canals"}
+        ]
+    }'::jsonb
+WHERE job_name IN ('Real-time Data Sync', 'Hourly Flow Sync', 'Daily System Health Check');
+
+UPDATE aquaflow.etl_jobs_v2
+SET parameters = (parameters - 'series_ids') || '{
+        "series_selector": [
+            {"dataset": "Pump Stations"},
+            {"dataset": "Don Pedro Reservoir"}
+        ]
+    }'::jsonb
+WHERE job_name = 'Weekly Infrastructure Check';
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.resolved_series_ids IS 'Series IDs resolved from series_ids and series_selector when the run started';
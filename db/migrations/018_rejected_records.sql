@@ -0,0 +1,48 @@
+-- =====================================================
+-- DEAD-LETTER STORE FOR REJECTED RECORDS
+-- =====================================================
+-- Records that fail parsing, validation or insertion are kept here with
+-- their raw payload instead of being dropped with the rest of the page.
+-- A replay run re-submits selected records through the worker insert path
+-- once the underlying problem (missing series, unit mapping, ...) is fixed.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_rejected_records (
+    record_id BIGSERIAL PRIMARY KEY,
+    run_id UUID NOT NULL REFERENCES aquaflow.etl_job_runs(run_id) ON DELETE CASCADE,
+    series_id INTEGER, -- no FK: a missing series is a common rejection reason
+    time_point TIMESTAMP WITH TIME ZONE,
+    raw_payload JSONB NOT NULL,
+    reason TEXT NOT NULL,
+    error_category VARCHAR(50) NOT NULL CHECK (error_category IN ('parse', 'validation', 'unknown_series', 'invalid_value', 'constraint', 'database')),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'replayed', 'discarded')),
+    replay_attempts INTEGER NOT NULL DEFAULT 0,
+    last_replay_at TIMESTAMP WITH TIME ZONE,
+    last_replay_error TEXT,
+    replay_run_id UUID REFERENCES aquaflow.etl_job_runs(run_id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_etl_rejected_records_run_id ON aquaflow.etl_rejected_records(run_id);
+CREATE INDEX idx_etl_rejected_records_series ON aquaflow.etl_rejected_records(series_id, time_point);
+CREATE INDEX idx_etl_rejected_records_pending ON aquaflow.etl_rejected_records(error_category, created_at DESC) WHERE status = 'pending';
+
+-- Job types are now governed by the etl_job_types foreign key
+ALTER TABLE aquaflow.etl_jobs_v2 DROP CONSTRAINT IF EXISTS etl_jobs_v2_job_type_check;
+
+INSERT INTO aquaflow.etl_job_types (job_type, description) VALUES
+('replay_rejected', 'Re-insert rejected records from the dead-letter store')
+ON CONFLICT (job_type) DO NOTHING;
+
+-- System job used by the replay API; it has no schedule
+INSERT INTO aquaflow.etl_jobs_v2 (job_name, job_type, description, parameters, tags) VALUES
+(
+    'Rejected Record Replay',
+    'replay_rejected',
+    'Replays selected dead-letter records through the worker insert path',
+    '{}'::jsonb,
+    ARRAY['system']
+)
+ON CONFLICT (job_name) DO NOTHING;
+
+COMMENT ON TABLE aquaflow.etl_rejected_records IS 'Dead-letter store - records rejected during ETL runs, with raw payload for replay';
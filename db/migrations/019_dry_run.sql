@@ -0,0 +1,13 @@
+-- =====================================================
+-- DRY-RUN MODE
+-- =====================================================
+-- Dry runs execute the full fetch, mapping and validation pipeline but do
+-- not write to numeric_values or the dead-letter store. The worker stores a
+-- report of what would have been written on the run.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS dry_run_report JSONB;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.dry_run_report IS 'Per-series counts, time span, sample rows, duplicates and rejects of a dry run';
@@ -0,0 +1,18 @@
+-- =====================================================
+-- STREAMING RUNS
+-- =====================================================
+-- A realtime_sync run with "streaming": true stays active and polls its
+-- source every sync_interval seconds. The worker heartbeats the run and
+-- stores a checkpoint (last timestamp per series and running totals) so a
+-- run abandoned by a crashed worker can be reclaimed and resumed.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS checkpoint JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_heartbeat ON aquaflow.etl_job_runs(heartbeat_at)
+WHERE status = 'running' AND heartbeat_at IS NOT NULL;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.heartbeat_at IS 'Last heartbeat of a streaming run; stale heartbeats let another worker reclaim the run';
+COMMENT ON COLUMN aquaflow.etl_job_runs.checkpoint IS 'Resume state of a streaming run';
@@ -0,0 +1,16 @@
+-- =====================================================
+-- MINIMUM LOG LEVELS
+-- =====================================================
+-- Jobs and individual runs can raise the minimum level the worker writes to
+-- etl_job_logs_v2. A run's level overrides its job's; when neither is set
+-- the worker default (ETL_LOG_LEVEL) applies.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_jobs_v2
+ADD COLUMN IF NOT EXISTS log_level VARCHAR(10) CHECK (log_level IN ('DEBUG', 'INFO', 'WARN', 'ERROR'));
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS log_level VARCHAR(10) CHECK (log_level IN ('DEBUG', 'INFO', 'WARN', 'ERROR'));
+
+COMMENT ON COLUMN aquaflow.etl_jobs_v2.log_level IS 'Minimum log level written for runs of this job';
+COMMENT ON COLUMN aquaflow.etl_job_runs.log_level IS 'Minimum log level written for this run; overrides the job level';
@@ -0,0 +1,18 @@
+-- =====================================================
+-- LOG COMPONENTS AND CORRELATION IDS
+-- =====================================================
+-- Every run carries a correlation ID. The scheduler, worker and backend
+-- write it to etl_job_logs_v2 together with their component, and a
+-- restarted run keeps the correlation ID of the run it replaces, so one
+-- scheduled run can be followed across services and restarts.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS correlation_id UUID NOT NULL DEFAULT gen_random_uuid();
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_correlation ON aquaflow.etl_job_runs(correlation_id);
+CREATE INDEX IF NOT EXISTS idx_etl_job_logs_v2_correlation ON aquaflow.etl_job_logs_v2(correlation_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_etl_job_logs_v2_component ON aquaflow.etl_job_logs_v2(component);
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.correlation_id IS 'Shared by all log entries of a run and of the runs restarted from it';
+COMMENT ON COLUMN aquaflow.etl_job_logs_v2.component IS 'scheduler, worker, processor, validator or backend';
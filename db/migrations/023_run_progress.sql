@@ -0,0 +1,12 @@
+-- =====================================================
+-- RUN PROGRESS
+-- =====================================================
+-- Structured progress maintained by the worker while a run executes:
+-- expected and processed records, current series and page, throughput
+-- and estimated completion.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS progress JSONB;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.progress IS 'Progress of a running run, updated by the worker';
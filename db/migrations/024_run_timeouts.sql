@@ -0,0 +1,12 @@
+-- =====================================================
+-- RUN TIMEOUTS
+-- =====================================================
+-- The worker applies a deadline to every run: the timeout_seconds job
+-- parameter, or the default of the job type. A run that exceeds it is
+-- marked failed with error_category 'timeout'.
+-- =====================================================
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.error_category IS 'transient, data, system, configuration or timeout';
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_error_category ON aquaflow.etl_job_runs(error_category)
+WHERE error_category IS NOT NULL;
@@ -0,0 +1,43 @@
+-- =====================================================
+-- JOB DEPENDENCIES
+-- =====================================================
+-- A job can declare upstream jobs that must have run before its scheduled
+-- runs are released to the workers. The condition is either 'success' (the
+-- upstream completed) or 'always' (the upstream finished in any state), and
+-- the upstream run must have finished within freshness_seconds before the
+-- downstream run's scheduled time.
+--
+-- The scheduler creates runs with unmet dependencies in the 'waiting' state
+-- with a blocked_reason, and queues them once every upstream is satisfied.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_job_dependencies (
+    job_id UUID NOT NULL REFERENCES aquaflow.etl_jobs_v2(job_id) ON DELETE CASCADE,
+    upstream_job_id UUID NOT NULL REFERENCES aquaflow.etl_jobs_v2(job_id) ON DELETE CASCADE,
+    condition VARCHAR(20) NOT NULL DEFAULT 'success' CHECK (condition IN ('success', 'always')),
+    freshness_seconds INTEGER NOT NULL DEFAULT 86400 CHECK (freshness_seconds > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    created_by VARCHAR(255),
+
+    PRIMARY KEY (job_id, upstream_job_id),
+    CONSTRAINT chk_no_self_dependency CHECK (job_id <> upstream_job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_dependencies_upstream ON aquaflow.etl_job_dependencies(upstream_job_id);
+
+COMMENT ON TABLE aquaflow.etl_job_dependencies IS 'Upstream jobs a job waits for before its scheduled runs are queued';
+COMMENT ON COLUMN aquaflow.etl_job_dependencies.condition IS 'success: upstream must have completed; always: any finished run counts';
+COMMENT ON COLUMN aquaflow.etl_job_dependencies.freshness_seconds IS 'How long before the downstream scheduled time the upstream run may have finished';
+
+-- Runs held for their upstreams
+ALTER TABLE aquaflow.etl_job_runs DROP CONSTRAINT IF EXISTS etl_job_runs_status_check;
+ALTER TABLE aquaflow.etl_job_runs ADD CONSTRAINT etl_job_runs_status_check
+CHECK (status IN ('waiting', 'queued', 'running', 'completed', 'failed', 'cancelled', 'completed_with_errors'));
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS blocked_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_waiting ON aquaflow.etl_job_runs(started_at)
+WHERE status = 'waiting';
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.blocked_reason IS 'Why a waiting run is held: the upstream jobs that are not yet satisfied';
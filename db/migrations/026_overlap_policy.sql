@@ -0,0 +1,38 @@
+-- =====================================================
+-- OVERLAP AND CONCURRENCY POLICY
+-- =====================================================
+-- overlap_policy decides what the scheduler does when a job is due while an
+-- earlier run of it is still waiting, queued or running:
+--   allow   - queue the new run alongside the others
+--   skip    - record the new run as skipped, with the reason
+--   queue   - queue the new run; workers start it once the earlier runs end
+--   replace - cancel the earlier runs and queue the new one
+--
+-- max_concurrent_runs caps how many runs of a job workers execute at once;
+-- NULL means no limit.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_jobs_v2
+ADD COLUMN IF NOT EXISTS overlap_policy VARCHAR(20) NOT NULL DEFAULT 'allow'
+    CHECK (overlap_policy IN ('allow', 'skip', 'queue', 'replace')),
+ADD COLUMN IF NOT EXISTS max_concurrent_runs INTEGER CHECK (max_concurrent_runs > 0);
+
+ALTER TABLE aquaflow.etl_job_runs DROP CONSTRAINT IF EXISTS etl_job_runs_status_check;
+ALTER TABLE aquaflow.etl_job_runs ADD CONSTRAINT etl_job_runs_status_check
+CHECK (status IN ('waiting', 'queued', 'running', 'completed', 'failed', 'cancelled', 'completed_with_errors', 'skipped'));
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS skip_reason TEXT;
+
+-- Active runs per job, used by the scheduler overlap check and worker claiming
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_active ON aquaflow.etl_job_runs(job_id, status)
+WHERE status IN ('waiting', 'queued', 'running');
+
+COMMENT ON COLUMN aquaflow.etl_jobs_v2.overlap_policy IS 'allow, skip, queue or replace when a run is due while an earlier one is active';
+COMMENT ON COLUMN aquaflow.etl_jobs_v2.max_concurrent_runs IS 'Most runs of this job workers execute at once; NULL for no limit';
+COMMENT ON COLUMN aquaflow.etl_job_runs.skip_reason IS 'Why the scheduler skipped this run';
+
+-- The realtime syncs pile up after outages; one run at a time is enough
+UPDATE aquaflow.etl_jobs_v2
+SET overlap_policy = 'skip', max_concurrent_runs = 1
+WHERE job_name IN ('Real-time Data Sync', 'Hourly Flow Sync');
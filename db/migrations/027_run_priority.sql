@@ -0,0 +1,35 @@
+-- =====================================================
+-- RUN PRIORITY
+-- =====================================================
+-- Jobs and runs carry a priority from 1 (lowest) to 10 (highest). Runs
+-- inherit their job's priority unless a manual trigger overrides it.
+--
+-- Workers claim queued runs in order of effective priority: the run's
+-- priority plus one point for every aging interval it has waited, capped at
+-- 10, so low-priority work cannot starve. Worker slots reserved for a
+-- priority only take runs whose own priority reaches it; aging does not
+-- count towards reservations.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_jobs_v2
+ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 5 CHECK (priority BETWEEN 1 AND 10);
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 5 CHECK (priority BETWEEN 1 AND 10);
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_queue ON aquaflow.etl_job_runs(priority DESC, started_at ASC)
+WHERE status = 'queued';
+
+COMMENT ON COLUMN aquaflow.etl_jobs_v2.priority IS 'Default priority of runs of this job, 1 (lowest) to 10 (highest)';
+COMMENT ON COLUMN aquaflow.etl_job_runs.priority IS 'Priority the worker claims this run with, before aging';
+
+-- Jobs already tagged high-priority get ahead of backfills
+UPDATE aquaflow.etl_jobs_v2
+SET priority = 8
+WHERE 'high-priority' = ANY(tags);
+
+UPDATE aquaflow.etl_job_runs r
+SET priority = j.priority
+FROM aquaflow.etl_jobs_v2 j
+WHERE r.job_id = j.job_id
+  AND r.status IN ('waiting', 'queued');
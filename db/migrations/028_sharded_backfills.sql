@@ -0,0 +1,110 @@
+-- =====================================================
+-- SHARDED BACKFILLS
+-- =====================================================
+-- A backfill is a parent run with shard_by set. The worker that claims it
+-- splits it into child runs, one per calendar month of the date range or
+-- one per series, and workers then process the children in parallel like
+-- any other queued run.
+--
+-- The parent never executes itself: its status, record counts and progress
+-- are rolled up from its children by rollup_parent_run() whenever a child
+-- changes. A failed child can be requeued on its own.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS parent_run_id UUID REFERENCES aquaflow.etl_job_runs(run_id) ON DELETE CASCADE,
+ADD COLUMN IF NOT EXISTS shard_by VARCHAR(20) CHECK (shard_by IN ('month', 'series')),
+ADD COLUMN IF NOT EXISTS shard_key VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_parent ON aquaflow.etl_job_runs(parent_run_id)
+WHERE parent_run_id IS NOT NULL;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.parent_run_id IS 'Backfill run this shard belongs to';
+COMMENT ON COLUMN aquaflow.etl_job_runs.shard_by IS 'Set on a backfill parent: month or series';
+COMMENT ON COLUMN aquaflow.etl_job_runs.shard_key IS 'The month (YYYY-MM) or series ID a shard covers';
+
+-- Roll a parent run up from its children
+CREATE OR REPLACE FUNCTION aquaflow.rollup_parent_run()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_parent_status VARCHAR(50);
+    v_total INTEGER;
+    v_completed INTEGER;
+    v_with_errors INTEGER;
+    v_failed INTEGER;
+    v_running INTEGER;
+    v_pending INTEGER;
+    v_processed BIGINT;
+    v_records_failed BIGINT;
+    v_percent NUMERIC;
+    v_status VARCHAR(50);
+BEGIN
+    SELECT status INTO v_parent_status
+    FROM aquaflow.etl_job_runs
+    WHERE run_id = NEW.parent_run_id
+    FOR UPDATE;
+
+    -- A cancelled backfill stays cancelled
+    IF v_parent_status IS NULL OR v_parent_status = 'cancelled' THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT COUNT(*),
+           COUNT(*) FILTER (WHERE status = 'completed'),
+           COUNT(*) FILTER (WHERE status = 'completed_with_errors'),
+           COUNT(*) FILTER (WHERE status IN ('failed', 'cancelled', 'skipped')),
+           COUNT(*) FILTER (WHERE status = 'running'),
+           COUNT(*) FILTER (WHERE status IN ('waiting', 'queued')),
+           COALESCE(SUM(records_processed), 0),
+           COALESCE(SUM(records_failed), 0),
+           COALESCE(AVG(CASE
+               WHEN status IN ('completed', 'completed_with_errors', 'failed', 'cancelled', 'skipped') THEN 100
+               ELSE COALESCE((progress->>'percent_complete')::NUMERIC, 0)
+           END), 0)
+    INTO v_total, v_completed, v_with_errors, v_failed, v_running, v_pending,
+         v_processed, v_records_failed, v_percent
+    FROM aquaflow.etl_job_runs
+    WHERE parent_run_id = NEW.parent_run_id;
+
+    IF v_running + v_pending > 0 THEN
+        v_status := 'running';
+    ELSIF v_failed > 0 THEN
+        v_status := 'failed';
+    ELSIF v_with_errors > 0 THEN
+        v_status := 'completed_with_errors';
+    ELSE
+        v_status := 'completed';
+    END IF;
+
+    UPDATE aquaflow.etl_job_runs
+    SET status = v_status,
+        records_processed = v_processed,
+        records_failed = v_records_failed,
+        progress = jsonb_build_object(
+            'shards_total', v_total,
+            'shards_completed', v_completed,
+            'shards_completed_with_errors', v_with_errors,
+            'shards_failed', v_failed,
+            'shards_running', v_running,
+            'shards_queued', v_pending,
+            'records_processed', v_processed,
+            'records_failed', v_records_failed,
+            'percent_complete', ROUND(v_percent, 2),
+            'updated_at', NOW()
+        ),
+        error_message = CASE WHEN v_failed > 0 AND v_status <> 'running'
+                             THEN format('%s of %s shards failed', v_failed, v_total) END,
+        completed_at = CASE WHEN v_status = 'running' THEN NULL
+                            ELSE COALESCE(completed_at, NOW()) END
+    WHERE run_id = NEW.parent_run_id;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS rollup_etl_parent_run ON aquaflow.etl_job_runs;
+CREATE TRIGGER rollup_etl_parent_run
+AFTER INSERT OR UPDATE OF status, records_processed, records_failed, progress ON aquaflow.etl_job_runs
+FOR EACH ROW
+WHEN (NEW.parent_run_id IS NOT NULL)
+EXECUTE FUNCTION aquaflow.rollup_parent_run();
@@ -0,0 +1,16 @@
+-- =====================================================
+-- EXEC JOB TYPE
+-- =====================================================
+-- exec runs a script from the worker's scripts directory with a JSON
+-- parameters file, a clean environment, a memory limit and the run's
+-- timeout. Its stdout and stderr are stored in etl_job_logs_v2 with
+-- component 'script'; with output 'ndjson', stdout lines that are JSON
+-- data points are loaded like any other source.
+--
+-- The worker publishes the parameter schema on startup; registering the
+-- type here lets job definitions be created before then.
+-- =====================================================
+
+INSERT INTO aquaflow.etl_job_types (job_type, description) VALUES
+('exec', 'Run a script from the scripts directory, optionally loading NDJSON data points from its output')
+ON CONFLICT (job_type) DO NOTHING;
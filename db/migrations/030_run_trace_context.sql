@@ -0,0 +1,13 @@
+-- =====================================================
+-- RUN TRACE CONTEXT
+-- =====================================================
+-- Runs store the W3C trace context (traceparent, tracestate) of the API
+-- request or scheduling cycle that created them. The worker continues that
+-- trace when it executes the run, so one trace covers the request, the
+-- run and each series and page it loads.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS trace_context JSONB;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.trace_context IS 'W3C trace context of the request or scheduling cycle that created or last retried the run';
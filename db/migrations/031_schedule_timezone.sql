@@ -0,0 +1,14 @@
+-- =====================================================
+-- SCHEDULE TIMEZONE
+-- =====================================================
+-- The scheduler evaluates each cron expression in its schedule's IANA
+-- timezone (e.g. America/Los_Angeles), following DST changes, and resolves
+-- dynamic date placeholders in that zone. Schedules without one use UTC.
+-- =====================================================
+
+UPDATE aquaflow.etl_schedules SET timezone = 'UTC' WHERE timezone IS NULL;
+
+ALTER TABLE aquaflow.etl_schedules
+ALTER COLUMN timezone SET NOT NULL;
+
+COMMENT ON COLUMN aquaflow.etl_schedules.timezone IS 'IANA timezone the cron expression and dynamic date placeholders are evaluated in';
@@ -0,0 +1,24 @@
+-- =====================================================
+-- SCHEDULE MISFIRE POLICY
+-- =====================================================
+-- A fire is missed when the scheduler reaches it more than
+-- misfire_grace_seconds late, e.g. after an outage. Fires within the grace
+-- period always run; misfire_policy decides what happens to missed ones:
+--   fire_once - one run, for the latest missed fire (the default)
+--   fire_all  - one run per missed fire, at most max_catchup_runs, keeping
+--               the latest
+--   skip      - no runs for missed fires
+--
+-- Each run is created for its own fire time, so started_at and the dynamic
+-- date parameters cover that fire's window.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_schedules
+ADD COLUMN IF NOT EXISTS misfire_policy VARCHAR(20) NOT NULL DEFAULT 'fire_once'
+    CHECK (misfire_policy IN ('fire_once', 'fire_all', 'skip')),
+ADD COLUMN IF NOT EXISTS max_catchup_runs INTEGER NOT NULL DEFAULT 24 CHECK (max_catchup_runs > 0),
+ADD COLUMN IF NOT EXISTS misfire_grace_seconds INTEGER NOT NULL DEFAULT 300 CHECK (misfire_grace_seconds >= 0);
+
+COMMENT ON COLUMN aquaflow.etl_schedules.misfire_policy IS 'fire_once, fire_all or skip for fires missed while the scheduler was behind';
+COMMENT ON COLUMN aquaflow.etl_schedules.max_catchup_runs IS 'Most runs fire_all creates for missed fires at once';
+COMMENT ON COLUMN aquaflow.etl_schedules.misfire_grace_seconds IS 'How late a fire may be reached before it counts as missed';
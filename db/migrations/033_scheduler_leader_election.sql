@@ -0,0 +1,33 @@
+-- =====================================================
+-- SCHEDULER LEADER ELECTION AND IDEMPOTENT RUNS
+-- =====================================================
+-- Several scheduler replicas may run; only the holder of the 'etl-scheduler'
+-- lease creates runs. The leader renews its lease every few seconds and
+-- releases it on shutdown. A replica takes over once the lease has expired.
+-- Lease times use the database clock, so replica clock skew doesn't matter.
+--
+-- Scheduled runs record the fire time they were created for. started_at
+-- is reset when a worker claims the run, so it can't serve as the fire time.
+-- A schedule has at most one run per fire time, so two replicas that both
+-- believe they lead (split brain) still can't create a run twice. Runs from
+-- before this migration have no scheduled_for and are not covered.
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_scheduler_leases (
+    name VARCHAR(50) PRIMARY KEY,
+    holder_id VARCHAR(255) NOT NULL,
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    renewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_etl_job_runs_schedule_fire
+ON aquaflow.etl_job_runs(schedule_id, scheduled_for)
+WHERE schedule_id IS NOT NULL AND scheduled_for IS NOT NULL;
+
+COMMENT ON TABLE aquaflow.etl_scheduler_leases IS 'Leadership leases held by scheduler replicas';
+COMMENT ON COLUMN aquaflow.etl_scheduler_leases.holder_id IS 'Instance ID of the replica holding the lease';
+COMMENT ON COLUMN aquaflow.etl_job_runs.scheduled_for IS 'Fire time a scheduled run was created for; unique per schedule';
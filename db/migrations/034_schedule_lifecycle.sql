@@ -0,0 +1,54 @@
+-- =====================================================
+-- SCHEDULE LIFECYCLE AND AUTO-DISABLE
+-- =====================================================
+-- The scheduler sets last_run when it creates a run, and records the run's
+-- outcome once the run has finished. A failed run extends the schedule's
+-- streak of consecutive failures; a completed one, with or without errors,
+-- ends it. Cancelled and skipped runs leave the streak as it is.
+--
+-- When the streak reaches auto_disable_after, the scheduler disables the
+-- schedule. It logs the decision against the failed run and raises an
+-- etl_notifications row, which is also published on the etl_notifications
+-- channel (LISTEN etl_notifications). auto_disable_after is NULL, never
+-- disabling, unless a schedule opts in.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_schedules
+ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS auto_disable_after INTEGER CHECK (auto_disable_after > 0),
+ADD COLUMN IF NOT EXISTS last_run_id UUID,
+ADD COLUMN IF NOT EXISTS last_run_status VARCHAR(50),
+ADD COLUMN IF NOT EXISTS last_run_completed_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS disabled_reason TEXT;
+
+-- Finished scheduled runs whose outcome the schedule has not yet recorded
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS outcome_recorded_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_unrecorded_outcome ON aquaflow.etl_job_runs(completed_at)
+WHERE schedule_id IS NOT NULL AND scheduled_for IS NOT NULL AND outcome_recorded_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_notifications (
+    notification_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_type VARCHAR(50) NOT NULL,
+    severity VARCHAR(10) NOT NULL CHECK (severity IN ('info', 'warning', 'critical')),
+    message TEXT NOT NULL,
+    schedule_id UUID REFERENCES aquaflow.etl_schedules(schedule_id) ON DELETE CASCADE,
+    job_id UUID REFERENCES aquaflow.etl_jobs_v2(job_id) ON DELETE CASCADE,
+    run_id UUID REFERENCES aquaflow.etl_job_runs(run_id) ON DELETE SET NULL,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    acknowledged_at TIMESTAMP WITH TIME ZONE,
+    acknowledged_by VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_etl_notifications_open ON aquaflow.etl_notifications(created_at DESC)
+WHERE acknowledged_at IS NULL;
+
+COMMENT ON COLUMN aquaflow.etl_schedules.consecutive_failures IS 'Failed runs in a row since the last completed run';
+COMMENT ON COLUMN aquaflow.etl_schedules.auto_disable_after IS 'Consecutive failures after which the schedule is disabled; NULL never disables';
+COMMENT ON COLUMN aquaflow.etl_schedules.last_run_status IS 'Final status of the latest finished scheduled run';
+COMMENT ON COLUMN aquaflow.etl_schedules.disabled_reason IS 'Why the scheduler disabled the schedule';
+COMMENT ON COLUMN aquaflow.etl_job_runs.outcome_recorded_at IS 'When the scheduler recorded this run''s outcome on its schedule';
+COMMENT ON TABLE aquaflow.etl_notifications IS 'Events that need someone to investigate, such as schedules disabled after repeated failures';
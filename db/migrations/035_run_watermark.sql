@@ -0,0 +1,17 @@
+-- =====================================================
+-- RUN WATERMARKS FOR PARAMETER TEMPLATES
+-- =====================================================
+-- The worker records the latest data timestamp each run wrote. The
+-- scheduler exposes it to parameter templates, so an incremental job can
+-- start where the last completed run stopped:
+--   "since": "{{ .Watermark | default (.ScheduledFor | addDays -1) | format `rfc3339` }}"
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS watermark TIMESTAMP WITH TIME ZONE;
+
+-- Latest watermark of a job's completed runs
+CREATE INDEX IF NOT EXISTS idx_etl_job_runs_job_watermark ON aquaflow.etl_job_runs(job_id, watermark DESC)
+WHERE watermark IS NOT NULL;
+
+COMMENT ON COLUMN aquaflow.etl_job_runs.watermark IS 'Latest timestamp of the data points the run wrote';
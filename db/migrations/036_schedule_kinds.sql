@@ -0,0 +1,50 @@
+-- =====================================================
+-- SCHEDULE KINDS
+-- =====================================================
+-- A schedule fires in one of four ways, set by schedule_kind:
+--   cron        cron_expression with 5 fields, or 6 with a leading seconds
+--               field ('*/30 * * * * *' fires every 30 seconds)
+--   descriptor  cron_expression is @yearly, @annually, @monthly, @weekly,
+--               @daily, @midnight, @hourly or '@every <duration>'
+--   interval    every interval_seconds, counted from anchor_at, regardless
+--               of daylight saving changes
+--   once        a single fire at run_at; next_run is cleared afterwards
+-- Cron and descriptor expressions may start with CRON_TZ=<zone>, which
+-- takes precedence over the schedule's timezone. Columns of other kinds must
+-- be NULL. The scheduler and the API apply the same rules, and additionally
+-- parse each field and require at least one second between fires.
+-- =====================================================
+
+ALTER TABLE aquaflow.etl_schedules
+ADD COLUMN IF NOT EXISTS schedule_kind VARCHAR(20) NOT NULL DEFAULT 'cron',
+ADD COLUMN IF NOT EXISTS interval_seconds INTEGER,
+ADD COLUMN IF NOT EXISTS anchor_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS run_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE aquaflow.etl_schedules ALTER COLUMN cron_expression DROP NOT NULL;
+
+-- The five-field check rejected descriptors, seconds and the other kinds
+ALTER TABLE aquaflow.etl_schedules DROP CONSTRAINT IF EXISTS chk_cron_expression_format;
+
+ALTER TABLE aquaflow.etl_schedules DROP CONSTRAINT IF EXISTS chk_schedule_kind;
+ALTER TABLE aquaflow.etl_schedules
+ADD CONSTRAINT chk_schedule_kind CHECK (COALESCE(
+    CASE schedule_kind
+    WHEN 'cron' THEN
+        cron_expression ~ '^\s*((CRON_)?TZ=\S+\s+)?[*?,/0-9A-Za-z-]+(\s+[*?,/0-9A-Za-z-]+){4,5}\s*$'
+        AND interval_seconds IS NULL AND anchor_at IS NULL AND run_at IS NULL
+    WHEN 'descriptor' THEN
+        cron_expression ~ '^\s*((CRON_)?TZ=\S+\s+)?@(yearly|annually|monthly|weekly|daily|midnight|hourly|every\s+([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)\s*$'
+        AND interval_seconds IS NULL AND anchor_at IS NULL AND run_at IS NULL
+    WHEN 'interval' THEN
+        cron_expression IS NULL AND interval_seconds >= 1 AND anchor_at IS NOT NULL AND run_at IS NULL
+    WHEN 'once' THEN
+        cron_expression IS NULL AND interval_seconds IS NULL AND anchor_at IS NULL AND run_at IS NOT NULL
+    ELSE false
+    END, false));
+
+COMMENT ON COLUMN aquaflow.etl_schedules.schedule_kind IS 'cron, descriptor, interval or once';
+COMMENT ON COLUMN aquaflow.etl_schedules.cron_expression IS 'Cron expression (5 or 6 fields) or descriptor; NULL for interval and once schedules';
+COMMENT ON COLUMN aquaflow.etl_schedules.interval_seconds IS 'Period of an interval schedule';
+COMMENT ON COLUMN aquaflow.etl_schedules.anchor_at IS 'Instant an interval schedule counts its periods from';
+COMMENT ON COLUMN aquaflow.etl_schedules.run_at IS 'Fire time of a one-time schedule';
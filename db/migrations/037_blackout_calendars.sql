@@ -0,0 +1,73 @@
+-- =====================================================
+-- BLACKOUT CALENDARS
+-- =====================================================
+-- A calendar is a named set of blackout windows, such as SCADA maintenance
+-- or the irrigation season shutdown, attached to schedules or to jobs (and
+-- so to all of a job's schedules). A window is either one-off, from
+-- starts_at to ends_at, or recurring: it opens each time recurrence (a cron
+-- expression or descriptor, evaluated in timezone) fires and stays open for
+-- duration_seconds, optionally only between starts_at and ends_at.
+--
+-- When a fire lands inside a window of an active calendar, the scheduler
+-- applies the calendar's action:
+--   skip  - record the run as skipped, with the window as the reason
+--   defer - create the run waiting until every window has closed; further
+--           fires while it waits are skipped in its favour
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_calendars (
+    calendar_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    calendar_name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    action VARCHAR(10) NOT NULL DEFAULT 'skip' CHECK (action IN ('skip', 'defer')),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS aquaflow.etl_calendar_windows (
+    window_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    calendar_id UUID NOT NULL REFERENCES aquaflow.etl_calendars(calendar_id) ON DELETE CASCADE,
+    window_name VARCHAR(255) NOT NULL,
+    reason TEXT,
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    recurrence VARCHAR(100),
+    duration_seconds INTEGER,
+    timezone VARCHAR(50) NOT NULL DEFAULT 'UTC',
+    CONSTRAINT chk_calendar_window CHECK (
+        CASE WHEN recurrence IS NULL
+        THEN starts_at IS NOT NULL AND ends_at > starts_at AND duration_seconds IS NULL
+        ELSE duration_seconds >= 1 AND (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+        END)
+);
+
+CREATE INDEX IF NOT EXISTS idx_etl_calendar_windows_calendar ON aquaflow.etl_calendar_windows(calendar_id);
+
+-- Each row attaches a calendar to either a schedule or a job
+CREATE TABLE IF NOT EXISTS aquaflow.etl_calendar_attachments (
+    calendar_id UUID NOT NULL REFERENCES aquaflow.etl_calendars(calendar_id) ON DELETE CASCADE,
+    schedule_id UUID REFERENCES aquaflow.etl_schedules(schedule_id) ON DELETE CASCADE,
+    job_id UUID REFERENCES aquaflow.etl_jobs_v2(job_id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_calendar_attachment CHECK (num_nonnulls(schedule_id, job_id) = 1)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_etl_calendar_attachments_schedule ON aquaflow.etl_calendar_attachments(schedule_id, calendar_id)
+WHERE schedule_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_etl_calendar_attachments_job ON aquaflow.etl_calendar_attachments(job_id, calendar_id)
+WHERE job_id IS NOT NULL;
+
+-- Deferred runs wait until the blackout is over
+ALTER TABLE aquaflow.etl_job_runs
+ADD COLUMN IF NOT EXISTS deferred_until TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON TABLE aquaflow.etl_calendars IS 'Named sets of blackout windows in which scheduled fires are skipped or deferred';
+COMMENT ON COLUMN aquaflow.etl_calendars.action IS 'skip or defer fires that land in one of the calendar''s windows';
+COMMENT ON COLUMN aquaflow.etl_calendar_windows.recurrence IS 'Cron expression or descriptor the window opens on; NULL for a one-off window';
+COMMENT ON COLUMN aquaflow.etl_calendar_windows.duration_seconds IS 'How long a recurring window stays open';
+COMMENT ON COLUMN aquaflow.etl_calendar_windows.starts_at IS 'Start of a one-off window, or earliest opening of a recurring one';
+COMMENT ON COLUMN aquaflow.etl_calendar_windows.ends_at IS 'End of a one-off window, or the time after which a recurring one no longer opens';
+COMMENT ON TABLE aquaflow.etl_calendar_attachments IS 'Calendars applied to a schedule, or to every schedule of a job';
+COMMENT ON COLUMN aquaflow.etl_job_runs.deferred_until IS 'When a run deferred by a blackout window may be queued';
@@ -0,0 +1,3 @@
+// Package cron evaluates schedules and blackout windows. The backend and the
+// scheduler both use it, so both compute the same fire times.
+package cron
@@ -0,0 +1,86 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxFireWalk bounds how many fire times of a schedule without a fixed
+// period are walked one by one before skipping ahead
+const maxFireWalk = 10000
+
+// fixedPeriod returns the period of an interval or @every schedule
+func fixedPeriod(schedule cron.Schedule) (time.Duration, bool) {
+	switch s := schedule.(type) {
+	case intervalSchedule:
+		return s.period, true
+	case cron.ConstantDelaySchedule:
+		return s.Delay, true
+	}
+	return 0, false
+}
+
+// periodicFires returns the latest keep fire times from first up to and
+// including until, with their total, for a schedule that fires at first,
+// then at second and every period after it
+func periodicFires(first, second, until time.Time, period time.Duration, keep int) ([]time.Time, int) {
+	total := 1
+	if !second.IsZero() && !second.After(until) {
+		total += int(until.Sub(second)/period) + 1
+	}
+
+	fireAt := func(i int) time.Time {
+		if i == 0 {
+			return first
+		}
+		return second.Add(time.Duration(i-1) * period)
+	}
+	var fires []time.Time
+	for i := max(total-keep, 0); i < total; i++ {
+		fires = append(fires, fireAt(i))
+	}
+	return fires, total
+}
+
+// walkFires returns the latest keep fire times from first up to and
+// including until, with their total, by walking the schedule. After
+// maxFireWalk fires it skips ahead to a stretch before until wide enough to
+// hold keep fires, assuming they come no further apart than any walked so
+// far, and estimates the skipped fires from the rate of the walked ones.
+func walkFires(schedule cron.Schedule, first, until time.Time, loc *time.Location, keep int) ([]time.Time, int) {
+	var fires []time.Time
+	total := 0
+	var widest time.Duration
+	prev := first
+	for fire := first; !fire.IsZero() && !fire.After(until); fire = next(schedule, fire, loc) {
+		if total == maxFireWalk {
+			walked := fire.Sub(first)
+			if resume, ok := skipTo(fire, until, widest, keep); ok && walked > 0 {
+				total += int(float64(resume.Sub(fire))/float64(walked)*float64(total)) + 1
+				fires = fires[:0]
+				if fire = next(schedule, resume, loc); fire.IsZero() || fire.After(until) {
+					break
+				}
+			}
+		}
+		widest = max(widest, fire.Sub(prev))
+		prev = fire
+
+		total++
+		fires = append(fires, fire)
+		if len(fires) > keep {
+			fires = fires[1:]
+		}
+	}
+	return fires, total
+}
+
+// skipTo returns where to resume a walk at fire so that keep fires at most
+// widest apart still lie between it and until, if that is after fire
+func skipTo(fire, until time.Time, widest time.Duration, keep int) (time.Time, bool) {
+	if widest <= 0 || int64(keep) >= int64(until.Sub(fire)/widest)-1 {
+		return time.Time{}, false
+	}
+	return until.Add(-time.Duration(keep+1) * widest), true
+}
@@ -0,0 +1,94 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+// walkAll returns every fire time from first up to until, the slow way
+func walkAll(t *testing.T, p *Parser, spec Spec, first, until time.Time, loc *time.Location) []time.Time {
+	t.Helper()
+	var fires []time.Time
+	for fire := first; !fire.IsZero() && !fire.After(until); {
+		fires = append(fires, fire)
+		var err error
+		if fire, err = p.NextExecution(spec, fire, loc); err != nil {
+			t.Fatalf("NextExecution: %v", err)
+		}
+	}
+	return fires
+}
+
+func TestFireTimesMatchesWalk(t *testing.T) {
+	anchor := mustParse(t, "2024-01-01T00:00:00Z")
+	tests := []struct {
+		name  string
+		spec  Spec
+		first string
+		until string
+	}{
+		{"interval", Spec{Kind: KindInterval, IntervalSeconds: 900, AnchorAt: &anchor}, "2024-01-01T00:15:00Z", "2024-01-02T03:10:00Z"},
+		{"every", Spec{Kind: KindDescriptor, Expression: "@every 90s"}, "2024-01-01T00:00:00Z", "2024-01-01T06:00:00Z"},
+		{"cron", Spec{Kind: KindCron, Expression: "*/10 9-17 * * 1-5"}, "2024-01-01T09:00:00Z", "2024-01-15T12:00:00Z"},
+		{"once", Spec{Kind: KindOnce, RunAt: &anchor}, "2024-01-01T00:00:00Z", "2024-01-03T00:00:00Z"},
+		{"first after until", Spec{Kind: KindDescriptor, Expression: "@hourly"}, "2024-01-02T00:00:00Z", "2024-01-01T00:00:00Z"},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		for _, keep := range []int{0, 1, 3, 1 << 20} {
+			first, until := mustParse(t, tt.first), mustParse(t, tt.until)
+			want := walkAll(t, p, tt.spec, first, until, time.UTC)
+
+			fires, total, err := p.FireTimes(tt.spec, first, until, time.UTC, keep)
+			if err != nil {
+				t.Fatalf("%s: FireTimes: %v", tt.name, err)
+			}
+			if total != len(want) {
+				t.Errorf("%s keep %d: total = %d, want %d", tt.name, keep, total, len(want))
+			}
+			want = want[max(len(want)-keep, 0):]
+			if len(fires) != len(want) {
+				t.Fatalf("%s keep %d: got %d fires, want %d", tt.name, keep, len(fires), len(want))
+			}
+			for i := range want {
+				if !fires[i].Equal(want[i]) {
+					t.Errorf("%s keep %d: fire %d = %s, want %s", tt.name, keep, i, fires[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestFireTimesLongOutage(t *testing.T) {
+	p := NewParser()
+	first := mustParse(t, "2024-01-01T00:00:00Z")
+	until := mustParse(t, "2024-03-01T00:00:00Z")
+
+	// Every second for two months: walked up to maxFireWalk, then estimated
+	spec := Spec{Kind: KindCron, Expression: "* * * * * *"}
+	start := time.Now()
+	fires, total, err := p.FireTimes(spec, first, until, time.UTC, 2)
+	if err != nil {
+		t.Fatalf("FireTimes: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("FireTimes took %s", elapsed)
+	}
+	if want := int(until.Sub(first)/time.Second) + 1; total != want {
+		t.Errorf("total = %d, want %d", total, want)
+	}
+	if len(fires) != 2 || !fires[0].Equal(until.Add(-time.Second)) || !fires[1].Equal(until) {
+		t.Errorf("fires = %v, want the last two seconds before %s", fires, until)
+	}
+
+	// Bursts of fires a day apart still keep the latest ones
+	spec = Spec{Kind: KindCron, Expression: "* 9 * * *"}
+	fires, _, err = p.FireTimes(spec, first, mustParse(t, "2024-12-01T20:00:00Z"), time.UTC, 1)
+	if err != nil {
+		t.Fatalf("FireTimes: %v", err)
+	}
+	if want := mustParse(t, "2024-12-01T09:59:00Z"); len(fires) != 1 || !fires[0].Equal(want) {
+		t.Errorf("fires = %v, want %s", fires, want)
+	}
+}
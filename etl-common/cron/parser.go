@@ -0,0 +1,116 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type Parser struct {
+	cronParser cron.Parser
+}
+
+func NewParser() *Parser {
+	// Standard five-field cron, with an optional leading seconds field and
+	// descriptors such as @hourly and @every 30s
+	return &Parser{
+		cronParser: cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
+	}
+}
+
+// ParseSchedule validates a schedule spec and compiles it
+func (p *Parser) ParseSchedule(spec Spec) (cron.Schedule, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := parse(p.cronParser, spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression '%s': %w", spec.Expression, err)
+	}
+	return schedule, nil
+}
+
+// NextExecution calculates the next fire time of a schedule after the given
+// time, evaluating it in loc. It returns the zero time if the schedule
+// never fires again.
+func (p *Parser) NextExecution(spec Spec, from time.Time, loc *time.Location) (time.Time, error) {
+	schedule, err := p.ParseSchedule(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+	
+	return next(schedule, from, loc), nil
+}
+
+// FireTimes returns the fire times of a schedule from first, which is
+// included as is, up to and including until, evaluated in loc. Only the
+// latest keep times are returned, along with the total number of fires.
+// Schedules with a fixed period are counted without walking every fire; for
+// others, the total is estimated past maxFireWalk fires.
+func (p *Parser) FireTimes(spec Spec, first, until time.Time, loc *time.Location, keep int) ([]time.Time, int, error) {
+	schedule, err := p.ParseSchedule(spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if first.IsZero() || first.After(until) {
+		return nil, 0, nil
+	}
+
+	if period, ok := fixedPeriod(schedule); ok {
+		fires, total := periodicFires(first, next(schedule, first, loc), until, period, keep)
+		return fires, total, nil
+	}
+	fires, total := walkFires(schedule, first, until, loc, keep)
+	return fires, total, nil
+}
+
+// IsScheduleDue checks if a scheduled job is due for execution
+func (p *Parser) IsScheduleDue(spec Spec, lastRun time.Time, now time.Time, loc *time.Location) (bool, error) {
+	schedule, err := p.ParseSchedule(spec)
+	if err != nil {
+		return false, err
+	}
+	
+	// Get the next scheduled time after the last run
+	nextScheduled := next(schedule, lastRun, loc)
+	
+	// Job is due if the next scheduled time is now or in the past
+	return !nextScheduled.IsZero() && !nextScheduled.After(now), nil
+}
+
+// GetNextRun calculates the next run time after the current time
+func (p *Parser) GetNextRun(spec Spec, loc *time.Location) (time.Time, error) {
+	return p.NextExecution(spec, time.Now(), loc)
+}
+
+// ValidateSpec checks that a schedule spec is complete for its kind and
+// syntactically correct
+func (p *Parser) ValidateSpec(spec Spec) error {
+	_, err := p.ParseSchedule(spec)
+	return err
+}
+
+// GetHumanReadableSchedule describes a schedule in words
+func (p *Parser) GetHumanReadableSchedule(spec Spec) string {
+	if spec.Kind != KindCron {
+		return describe(spec)
+	}
+	cronExpr := spec.Expression
+	scheduleMap := map[string]string{
+		"*/15 * * * *": "Every 15 minutes",
+		"*/30 * * * *": "Every 30 minutes", 
+		"0 * * * *":    "Every hour",
+		"0 */2 * * *":  "Every 2 hours",
+		"0 */6 * * *":  "Every 6 hours",
+		"0 2 * * *":    "Daily at 2:00 AM",
+		"0 6 * * *":    "Daily at 6:00 AM",
+		"0 0 * * 0":    "Weekly on Sunday",
+		"0 0 1 * *":    "Monthly on 1st",
+	}
+	
+	if readable, exists := scheduleMap[cronExpr]; exists {
+		return readable
+	}
+	return cronExpr // Return original if no mapping found
+}
\ No newline at end of file
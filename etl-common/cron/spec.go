@@ -0,0 +1,197 @@
+package cron
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule kinds
+const (
+	// KindCron fires on a cron expression of five fields, or six with a
+	// leading seconds field
+	KindCron = "cron"
+	// KindDescriptor fires on @yearly, @monthly, @weekly, @daily, @hourly
+	// and the like, or @every with a duration
+	KindDescriptor = "descriptor"
+	// KindInterval fires every IntervalSeconds, counted from AnchorAt
+	KindInterval = "interval"
+	// KindOnce fires once, at RunAt
+	KindOnce = "once"
+)
+
+// MinInterval is the shortest period a schedule may fire at
+const MinInterval = time.Second
+
+// Spec is when a schedule fires. Cron and descriptor schedules use
+// Expression; the other fields belong to the interval and once kinds.
+type Spec struct {
+	Kind            string
+	Expression      string
+	IntervalSeconds int
+	AnchorAt        *time.Time
+	RunAt           *time.Time
+}
+
+// descriptors are the named schedules accepted by the descriptor kind
+var descriptors = map[string]string{
+	"@yearly":   "Yearly on January 1st",
+	"@annually": "Yearly on January 1st",
+	"@monthly":  "Monthly on 1st",
+	"@weekly":   "Weekly on Sunday",
+	"@daily":    "Daily at midnight",
+	"@midnight": "Daily at midnight",
+	"@hourly":   "Every hour",
+}
+
+// cronFieldPattern matches one cron field; names such as MON and JAN are
+// allowed
+var cronFieldPattern = regexp.MustCompile(`^[*?,/0-9A-Za-z-]+$`)
+
+// splitTimezone separates a CRON_TZ= or TZ= prefix from an expression
+func splitTimezone(expr string) (prefix, rest string) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		if i := strings.IndexAny(expr, " \t"); i > 0 {
+			return expr[:i], strings.TrimSpace(expr[i:])
+		}
+	}
+	return "", expr
+}
+
+// validate checks that spec is complete for its kind and carries no fields
+// of another kind
+func (spec Spec) validate() error {
+	_, expr := splitTimezone(spec.Expression)
+	hasExpr := strings.TrimSpace(spec.Expression) != ""
+
+	switch spec.Kind {
+	case KindCron, KindDescriptor:
+		if !hasExpr {
+			return fmt.Errorf("%s schedule needs an expression", spec.Kind)
+		}
+		if spec.IntervalSeconds != 0 || spec.AnchorAt != nil || spec.RunAt != nil {
+			return fmt.Errorf("%s schedule takes only an expression", spec.Kind)
+		}
+	case KindInterval:
+		if hasExpr || spec.RunAt != nil {
+			return fmt.Errorf("interval schedule takes only interval_seconds and anchor_at")
+		}
+		if time.Duration(spec.IntervalSeconds)*time.Second < MinInterval {
+			return fmt.Errorf("interval_seconds must be at least %d", int(MinInterval.Seconds()))
+		}
+		if spec.AnchorAt == nil {
+			return fmt.Errorf("interval schedule needs anchor_at")
+		}
+		return nil
+	case KindOnce:
+		if hasExpr || spec.IntervalSeconds != 0 || spec.AnchorAt != nil {
+			return fmt.Errorf("one-time schedule takes only run_at")
+		}
+		if spec.RunAt == nil {
+			return fmt.Errorf("one-time schedule needs run_at")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown schedule kind '%s'", spec.Kind)
+	}
+
+	if spec.Kind == KindCron {
+		if strings.HasPrefix(expr, "@") {
+			return fmt.Errorf("'%s' is a descriptor, not a cron expression", expr)
+		}
+		fields := strings.Fields(expr)
+		if len(fields) != 5 && len(fields) != 6 {
+			return fmt.Errorf("cron expression needs 5 fields, or 6 with seconds, got %d", len(fields))
+		}
+		for _, field := range fields {
+			if !cronFieldPattern.MatchString(field) {
+				return fmt.Errorf("invalid cron field '%s'", field)
+			}
+		}
+		return nil
+	}
+
+	if _, ok := descriptors[expr]; ok {
+		return nil
+	}
+	every, ok := strings.CutPrefix(expr, "@every ")
+	if !ok {
+		return fmt.Errorf("unknown descriptor '%s'", expr)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(every))
+	if err != nil {
+		return fmt.Errorf("invalid @every duration: %w", err)
+	}
+	if d < MinInterval {
+		return fmt.Errorf("@every duration must be at least %s", MinInterval)
+	}
+	return nil
+}
+
+// intervalSchedule fires every period, counted from anchor. It follows
+// elapsed time, so daylight saving changes don't move it.
+type intervalSchedule struct {
+	anchor time.Time
+	period time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.anchor) {
+		return s.anchor.In(t.Location())
+	}
+	n := t.Sub(s.anchor)/s.period + 1
+	return s.anchor.Add(n * s.period).In(t.Location())
+}
+
+// onceSchedule fires at a single instant
+type onceSchedule struct {
+	at time.Time
+}
+
+func (s onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at.In(t.Location())
+	}
+	return time.Time{}
+}
+
+// describe returns a human-readable description of spec
+func describe(spec Spec) string {
+	switch spec.Kind {
+	case KindInterval:
+		if spec.AnchorAt == nil {
+			break
+		}
+		period := time.Duration(spec.IntervalSeconds) * time.Second
+		return fmt.Sprintf("Every %s from %s", period, spec.AnchorAt.Format(time.RFC3339))
+	case KindOnce:
+		if spec.RunAt == nil {
+			break
+		}
+		return fmt.Sprintf("Once at %s", spec.RunAt.Format(time.RFC3339))
+	case KindDescriptor:
+		_, expr := splitTimezone(spec.Expression)
+		if readable, ok := descriptors[expr]; ok {
+			return readable
+		}
+		if every, ok := strings.CutPrefix(expr, "@every "); ok {
+			return "Every " + strings.TrimSpace(every)
+		}
+	}
+	return spec.Expression
+}
+
+// parse compiles a validated spec
+func parse(parser cron.Parser, spec Spec) (cron.Schedule, error) {
+	switch spec.Kind {
+	case KindInterval:
+		return intervalSchedule{anchor: *spec.AnchorAt, period: time.Duration(spec.IntervalSeconds) * time.Second}, nil
+	case KindOnce:
+		return onceSchedule{at: *spec.RunAt}, nil
+	}
+	return parser.Parse(strings.TrimSpace(spec.Expression))
+}
@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// allHours is the hour bitmask of a schedule that fires every hour
+const allHours = 1<<24 - 1
+
+// LoadLocation resolves a schedule's IANA timezone; empty means UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %w", name, err)
+	}
+	return loc, nil
+}
+
+// next returns the first fire time of schedule after from, in loc. A
+// CRON_TZ= prefix on the expression takes precedence over loc.
+//
+// Schedules with fixed hours follow the wall clock, so a DST change neither
+// skips nor repeats them: a time in the spring-forward gap fires just after
+// it (02:30 becomes 03:30), and a time in the repeated fall-back hour fires
+// once, at its first occurrence. Schedules that fire every hour follow
+// elapsed time instead, so an hourly job still runs once per real hour.
+func next(schedule cron.Schedule, from time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&allHours == allHours {
+		return schedule.Next(from.In(loc))
+	}
+	if spec.Location != time.Local {
+		loc = spec.Location
+		local := *spec
+		local.Location = time.Local
+		spec = &local
+	}
+
+	// Walk wall-clock times, held as UTC so they have no DST, until one
+	// resolves to an instant after from
+	wall := wallClock(from.In(loc))
+	for {
+		wall = spec.Next(wall)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+		if t := resolve(wall, loc); t.After(from) {
+			return t
+		}
+	}
+}
+
+// wallClock returns t's wall-clock reading as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// resolve returns the instant at which loc's clocks read wall. An ambiguous
+// reading resolves to its first occurrence; one in a spring-forward gap
+// resolves to the instant the same distance past the start of the gap.
+func resolve(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+	if d := wall.Sub(wallClock(t)); d > 0 {
+		return t.Add(d)
+	}
+	// time.Date doesn't promise which occurrence it picks; prefer the first,
+	// which is on the far side of a change that set the clocks back
+	_, offset := t.Zone()
+	if _, before := t.Add(-24 * time.Hour).Zone(); before > offset {
+		if earlier := t.Add(-time.Duration(before-offset) * time.Second); wallClock(earlier).Equal(wall) {
+			return earlier
+		}
+	}
+	return t
+}
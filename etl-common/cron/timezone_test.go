@@ -0,0 +1,152 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+// 2024 DST changes in America/Los_Angeles: clocks go from 02:00 PST to
+// 03:00 PDT on March 10 and from 02:00 PDT back to 01:00 PST on November 3
+func TestNextAcrossDST(t *testing.T) {
+	la := mustLoad(t, "America/Los_Angeles")
+
+	tests := []struct {
+		name       string
+		expression string
+		loc        *time.Location
+		from       string
+		want       []string
+	}{
+		{
+			name:       "spring forward, time in the gap",
+			expression: "30 2 * * *",
+			loc:        la,
+			from:       "2024-03-09T10:30:00Z",
+			// 02:30 does not exist on March 10 and fires at 03:30 PDT
+			want: []string{"2024-03-10T10:30:00Z", "2024-03-11T09:30:00Z"},
+		},
+		{
+			name:       "fall back, time after the repeated hour",
+			expression: "30 2 * * *",
+			loc:        la,
+			from:       "2024-11-02T09:30:00Z",
+			want:       []string{"2024-11-03T10:30:00Z", "2024-11-04T10:30:00Z"},
+		},
+		{
+			name:       "fall back, time in the repeated hour",
+			expression: "30 1 * * *",
+			loc:        la,
+			from:       "2024-11-02T08:30:00Z",
+			// 01:30 fires once, at 01:30 PDT, not again at 01:30 PST
+			want: []string{"2024-11-03T08:30:00Z", "2024-11-04T09:30:00Z"},
+		},
+		{
+			name:       "spring forward, hour range",
+			expression: "0 1-3 * * *",
+			loc:        la,
+			from:       "2024-03-10T08:00:00Z",
+			// 02:00 falls in the gap and lands on 03:00 PDT, which fires once
+			want: []string{"2024-03-10T09:00:00Z", "2024-03-10T10:00:00Z", "2024-03-11T08:00:00Z", "2024-03-11T09:00:00Z", "2024-03-11T10:00:00Z"},
+		},
+		{
+			name:       "fall back, hour range",
+			expression: "0 1-3 * * *",
+			loc:        la,
+			from:       "2024-11-03T07:00:00Z",
+			// 01:00 fires at 01:00 PDT only
+			want: []string{"2024-11-03T08:00:00Z", "2024-11-03T10:00:00Z", "2024-11-03T11:00:00Z", "2024-11-04T09:00:00Z"},
+		},
+		{
+			name:       "spring forward, every 15 minutes",
+			expression: "*/15 * * * *",
+			loc:        la,
+			from:       "2024-03-10T09:30:00Z",
+			// Elapsed time: 01:45 PST is followed by 03:00 PDT
+			want: []string{"2024-03-10T09:45:00Z", "2024-03-10T10:00:00Z", "2024-03-10T10:15:00Z"},
+		},
+		{
+			name:       "fall back, every 15 minutes",
+			expression: "*/15 * * * *",
+			loc:        la,
+			from:       "2024-11-03T08:30:00Z",
+			// Elapsed time: the repeated hour fires again in PST
+			want: []string{"2024-11-03T08:45:00Z", "2024-11-03T09:00:00Z", "2024-11-03T09:15:00Z"},
+		},
+		{
+			name:       "CRON_TZ prefix, spring forward",
+			expression: "CRON_TZ=America/Los_Angeles 30 2 * * *",
+			loc:        time.UTC,
+			from:       "2024-03-09T10:30:00Z",
+			want:       []string{"2024-03-10T10:30:00Z", "2024-03-11T09:30:00Z"},
+		},
+		{
+			name:       "CRON_TZ prefix overrides the schedule timezone",
+			expression: "CRON_TZ=America/Los_Angeles 30 1 * * *",
+			loc:        mustLoad(t, "Europe/Berlin"),
+			from:       "2024-11-02T08:30:00Z",
+			want:       []string{"2024-11-03T08:30:00Z", "2024-11-04T09:30:00Z"},
+		},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{Kind: KindCron, Expression: tt.expression}
+			from := mustParse(t, tt.from)
+			for _, want := range tt.want {
+				got, err := p.NextExecution(spec, from, tt.loc)
+				if err != nil {
+					t.Fatalf("NextExecution: %v", err)
+				}
+				if !got.Equal(mustParse(t, want)) {
+					t.Fatalf("next after %s = %s, want %s", from.UTC().Format(time.RFC3339), got.UTC().Format(time.RFC3339), want)
+				}
+				from = got
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	la := mustLoad(t, "America/Los_Angeles")
+
+	tests := []struct {
+		name string
+		wall string
+		want string
+	}{
+		{"ordinary time", "2024-06-01T12:00:00Z", "2024-06-01T19:00:00Z"},
+		{"start of the gap", "2024-03-10T02:00:00Z", "2024-03-10T10:00:00Z"},
+		{"inside the gap", "2024-03-10T02:30:00Z", "2024-03-10T10:30:00Z"},
+		{"first of the gap's far side", "2024-03-10T03:00:00Z", "2024-03-10T10:00:00Z"},
+		{"ambiguous time resolves to its first occurrence", "2024-11-03T01:30:00Z", "2024-11-03T08:30:00Z"},
+		{"after the repeated hour", "2024-11-03T02:00:00Z", "2024-11-03T10:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolve(mustParse(t, tt.wall), la)
+			if !got.Equal(mustParse(t, tt.want)) {
+				t.Errorf("resolve(%s) = %s, want %s", tt.wall, got.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%s): %v", name, err)
+	}
+	return loc
+}
+
+func mustParse(t *testing.T, value string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("time.Parse(%s): %v", value, err)
+	}
+	return ts
+}
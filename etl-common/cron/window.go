@@ -0,0 +1,124 @@
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxWindowHops bounds how many back-to-back windows Clear walks through
+// before giving up
+const maxWindowHops = 1000
+
+// Window is a span of time in which fires are blocked. A one-off window runs
+// from Start to End. A recurring window opens each time Recurrence, a cron
+// expression or descriptor, fires and stays open for Duration; Start and
+// End, if set, limit when it may open. Windows are half-open: a fire at the
+// moment one closes is not blocked.
+type Window struct {
+	Start      *time.Time
+	End        *time.Time
+	Recurrence string
+	Duration   time.Duration
+	// Location is the timezone Recurrence is evaluated in
+	Location *time.Location
+}
+
+// recurrence returns the spec of a recurring window's openings
+func (w Window) recurrence() Spec {
+	kind := KindCron
+	if _, expr := splitTimezone(w.Recurrence); strings.HasPrefix(expr, "@") {
+		kind = KindDescriptor
+	}
+	return Spec{Kind: kind, Expression: w.Recurrence}
+}
+
+// ValidateWindow checks that a window is either one-off or recurring, and
+// that its recurrence parses
+func (p *Parser) ValidateWindow(w Window) error {
+	if strings.TrimSpace(w.Recurrence) == "" {
+		if w.Start == nil || w.End == nil {
+			return fmt.Errorf("one-off window needs starts_at and ends_at")
+		}
+		if !w.End.After(*w.Start) {
+			return fmt.Errorf("window must end after it starts")
+		}
+		if w.Duration != 0 {
+			return fmt.Errorf("one-off window takes no duration")
+		}
+		return nil
+	}
+	if w.Duration < MinInterval {
+		return fmt.Errorf("recurring window needs a duration of at least %s", MinInterval)
+	}
+	if w.Start != nil && w.End != nil && !w.End.After(*w.Start) {
+		return fmt.Errorf("window must end after it starts")
+	}
+	if _, err := p.ParseSchedule(w.recurrence()); err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+	return nil
+}
+
+// WindowAt returns when the opening of w that contains t closes, or the zero
+// time if w is not open at t. Of overlapping openings, the one closing last
+// counts.
+func (p *Parser) WindowAt(w Window, t time.Time) (time.Time, error) {
+	if strings.TrimSpace(w.Recurrence) == "" {
+		if w.Start != nil && w.End != nil && !t.Before(*w.Start) && t.Before(*w.End) {
+			return *w.End, nil
+		}
+		return time.Time{}, nil
+	}
+
+	schedule, err := p.ParseSchedule(w.recurrence())
+	if err != nil {
+		return time.Time{}, err
+	}
+	var end time.Time
+	// Openings after t - Duration and no later than t contain t
+	for open := next(schedule, t.Add(-w.Duration-time.Nanosecond), w.Location); !open.IsZero() && !open.After(t); open = next(schedule, open, w.Location) {
+		if w.Start != nil && open.Before(*w.Start) {
+			continue
+		}
+		if w.End != nil && !open.Before(*w.End) {
+			break
+		}
+		if closes := open.Add(w.Duration); closes.After(t) && closes.After(end) {
+			end = closes
+		}
+	}
+	return end, nil
+}
+
+// Blocked returns the index of the window among windows that contains t and
+// closes last, with when it closes, or -1 if none does
+func (p *Parser) Blocked(windows []Window, t time.Time) (int, time.Time, error) {
+	index, end := -1, time.Time{}
+	for i, w := range windows {
+		closes, err := p.WindowAt(w, t)
+		if err != nil {
+			return -1, time.Time{}, err
+		}
+		if !closes.IsZero() && closes.After(end) {
+			index, end = i, closes
+		}
+	}
+	return index, end, nil
+}
+
+// Clear returns the first moment at or after t that no window blocks,
+// following windows that open as or before others close
+func (p *Parser) Clear(windows []Window, t time.Time) (time.Time, error) {
+	for hop := 0; hop < maxWindowHops; hop++ {
+		index, end, err := p.Blocked(windows, t)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if index < 0 {
+			return t, nil
+		}
+		t = end
+	}
+	return time.Time{}, fmt.Errorf("windows do not clear within %d openings after %s", maxWindowHops, t.Format(time.RFC3339))
+}
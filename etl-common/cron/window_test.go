@@ -0,0 +1,141 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowAtRecurring(t *testing.T) {
+	la := mustLoad(t, "America/Los_Angeles")
+	start := mustParse(t, "2024-05-03T00:00:00Z")
+	end := mustParse(t, "2024-05-06T00:00:00Z")
+
+	tests := []struct {
+		name   string
+		window Window
+		at     string
+		want   string
+	}{
+		// Opens at 01:00 Los Angeles time (08:00 UTC in May) for two hours
+		{"before opening", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la}, "2024-05-04T07:59:59Z", ""},
+		{"at opening", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la}, "2024-05-04T08:00:00Z", "2024-05-04T10:00:00Z"},
+		{"while open", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la}, "2024-05-04T09:30:00Z", "2024-05-04T10:00:00Z"},
+		{"at closing", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la}, "2024-05-04T10:00:00Z", ""},
+		{"open across midnight", Window{Recurrence: "0 23 * * *", Duration: 3 * time.Hour, Location: time.UTC}, "2024-05-05T01:00:00Z", "2024-05-05T02:00:00Z"},
+		{"descriptor", Window{Recurrence: "@daily", Duration: time.Hour, Location: time.UTC}, "2024-05-05T00:30:00Z", "2024-05-05T01:00:00Z"},
+		{"CRON_TZ prefix", Window{Recurrence: "CRON_TZ=America/Los_Angeles 0 1 * * *", Duration: time.Hour, Location: time.UTC}, "2024-05-04T08:30:00Z", "2024-05-04T09:00:00Z"},
+		{"overlapping openings close with the last", Window{Recurrence: "0 * * * *", Duration: 90 * time.Minute, Location: time.UTC}, "2024-05-04T10:15:00Z", "2024-05-04T11:30:00Z"},
+		{"opening before start", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la, Start: &start}, "2024-05-02T08:30:00Z", ""},
+		{"opening after start", Window{Recurrence: "0 1 * * *", Duration: 2 * time.Hour, Location: la, Start: &start}, "2024-05-04T08:30:00Z", "2024-05-04T10:00:00Z"},
+		{"opening at end", Window{Recurrence: "0 0 * * *", Duration: 2 * time.Hour, Location: time.UTC, End: &end}, "2024-05-06T01:00:00Z", ""},
+		{"last opening before end stays open past it", Window{Recurrence: "0 23 * * *", Duration: 2 * time.Hour, Location: time.UTC, End: &end}, "2024-05-06T00:30:00Z", "2024-05-06T01:00:00Z"},
+		{"one-off", Window{Start: &start, End: &end}, "2024-05-04T00:00:00Z", "2024-05-06T00:00:00Z"},
+		{"one-off end is open", Window{Start: &start, End: &end}, "2024-05-06T00:00:00Z", ""},
+	}
+
+	p := NewParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.ValidateWindow(tt.window); err != nil {
+				t.Fatalf("ValidateWindow: %v", err)
+			}
+			got, err := p.WindowAt(tt.window, mustParse(t, tt.at))
+			if err != nil {
+				t.Fatalf("WindowAt: %v", err)
+			}
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("WindowAt = %s, want closed", got.UTC().Format(time.RFC3339))
+				}
+				return
+			}
+			if !got.Equal(mustParse(t, tt.want)) {
+				t.Errorf("WindowAt = %s, want %s", got.UTC().Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedPicksLastClosing(t *testing.T) {
+	p := NewParser()
+	windows := []Window{
+		{Recurrence: "0 2 * * *", Duration: 2 * time.Hour, Location: time.UTC},
+		{Recurrence: "0 1 * * *", Duration: 4 * time.Hour, Location: time.UTC},
+		{Recurrence: "0 12 * * *", Duration: time.Hour, Location: time.UTC},
+	}
+
+	index, closes, err := p.Blocked(windows, mustParse(t, "2024-05-04T03:00:00Z"))
+	if err != nil {
+		t.Fatalf("Blocked: %v", err)
+	}
+	if index != 1 || !closes.Equal(mustParse(t, "2024-05-04T05:00:00Z")) {
+		t.Errorf("Blocked = %d, %s; want 1, 05:00", index, closes.Format(time.RFC3339))
+	}
+
+	if index, _, _ := p.Blocked(windows, mustParse(t, "2024-05-04T06:00:00Z")); index != -1 {
+		t.Errorf("Blocked outside every window = %d, want -1", index)
+	}
+}
+
+func TestClearChainsBackToBackWindows(t *testing.T) {
+	p := NewParser()
+	start := mustParse(t, "2024-05-04T06:00:00Z")
+	end := mustParse(t, "2024-05-04T08:00:00Z")
+	windows := []Window{
+		// 02:00-04:00, then 04:00-06:00 opens as the first closes, then a
+		// one-off 06:00-08:00
+		{Recurrence: "0 2 * * *", Duration: 2 * time.Hour, Location: time.UTC},
+		{Recurrence: "0 4 * * *", Duration: 2 * time.Hour, Location: time.UTC},
+		{Start: &start, End: &end},
+		// 09:00-10:00 leaves a gap, so it is not chained
+		{Recurrence: "0 9 * * *", Duration: time.Hour, Location: time.UTC},
+	}
+
+	tests := []struct{ at, want string }{
+		{"2024-05-04T03:00:00Z", "2024-05-04T08:00:00Z"},
+		{"2024-05-04T07:00:00Z", "2024-05-04T08:00:00Z"},
+		{"2024-05-04T08:30:00Z", "2024-05-04T08:30:00Z"},
+		{"2024-05-05T03:00:00Z", "2024-05-05T06:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := p.Clear(windows, mustParse(t, tt.at))
+		if err != nil {
+			t.Fatalf("Clear(%s): %v", tt.at, err)
+		}
+		if !got.Equal(mustParse(t, tt.want)) {
+			t.Errorf("Clear(%s) = %s, want %s", tt.at, got.UTC().Format(time.RFC3339), tt.want)
+		}
+	}
+}
+
+func TestClearNeverClears(t *testing.T) {
+	p := NewParser()
+	// Every opening lasts past the next one
+	windows := []Window{{Recurrence: "* * * * *", Duration: 2 * time.Minute, Location: time.UTC}}
+	if _, err := p.Clear(windows, mustParse(t, "2024-05-04T00:00:00Z")); err == nil {
+		t.Error("Clear of a window that never closes returned no error")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	start := mustParse(t, "2024-05-04T00:00:00Z")
+	end := mustParse(t, "2024-05-05T00:00:00Z")
+	invalid := []struct {
+		name   string
+		window Window
+	}{
+		{"one-off without end", Window{Start: &start}},
+		{"one-off ending before it starts", Window{Start: &end, End: &start}},
+		{"one-off with a duration", Window{Start: &start, End: &end, Duration: time.Hour}},
+		{"recurring without duration", Window{Recurrence: "@daily"}},
+		{"recurring ending before it starts", Window{Recurrence: "@daily", Duration: time.Hour, Start: &end, End: &start}},
+		{"invalid recurrence", Window{Recurrence: "61 * * * *", Duration: time.Hour}},
+	}
+
+	p := NewParser()
+	for _, tt := range invalid {
+		if err := p.ValidateWindow(tt.window); err == nil {
+			t.Errorf("%s: ValidateWindow accepted it", tt.name)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/aquaflow/etl-common
+
+go 1.22
+
+require (
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.3
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,4 @@
+// Package jobschema validates job parameters against the JSON Schemas
+// published by the workers. The backend and the scheduler both use it, so
+// both accept the same parameters.
+package jobschema
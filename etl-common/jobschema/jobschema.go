@@ -0,0 +1,145 @@
+package jobschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Mode controls how strictly parameters are checked
+type Mode int
+
+const (
+	// ModeDefinition validates stored job definitions. String formats such as
+	// dates are not asserted because values may still hold dynamic placeholders.
+	ModeDefinition Mode = iota
+	// ModeRun validates the final parameters of a run, including formats
+	ModeRun
+)
+
+var printer = message.NewPrinter(language.English)
+
+// FieldError describes a single invalid parameter
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when parameters do not match the job type schema
+type ValidationError struct {
+	JobType string       `json:"job_type"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		if fe.Field == "" {
+			parts = append(parts, fe.Message)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+		}
+	}
+	return fmt.Sprintf("invalid parameters for job type %s: %s", e.JobType, strings.Join(parts, "; "))
+}
+
+// Validate checks parameters against the JSON Schema published for a job type.
+// It returns a *ValidationError listing every invalid field, or another error
+// if the schema itself cannot be compiled.
+func Validate(jobType string, schema []byte, params map[string]interface{}, mode Mode) error {
+	if len(bytes.TrimSpace(schema)) == 0 {
+		return nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return fmt.Errorf("invalid parameter schema for job type %s: %w", jobType, err)
+	}
+
+	url := "job-types/" + jobType + ".json"
+	compiler := jsonschema.NewCompiler()
+	if mode == ModeRun {
+		compiler.AssertFormat()
+	}
+	if err := compiler.AddResource(url, doc); err != nil {
+		return fmt.Errorf("invalid parameter schema for job type %s: %w", jobType, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return fmt.Errorf("invalid parameter schema for job type %s: %w", jobType, err)
+	}
+
+	// Round-trip through JSON so numbers are represented the way the validator expects
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal parameters: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(paramsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to decode parameters: %w", err)
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	result := &ValidationError{JobType: jobType}
+	collectFieldErrors(verr, &result.Errors)
+	return result
+}
+
+// collectFieldErrors flattens the validator's error tree into one entry per leaf
+func collectFieldErrors(verr *jsonschema.ValidationError, out *[]FieldError) {
+	if len(verr.Causes) > 0 {
+		for _, cause := range verr.Causes {
+			collectFieldErrors(cause, out)
+		}
+		return
+	}
+
+	if required, ok := verr.ErrorKind.(*kind.Required); ok {
+		for _, missing := range required.Missing {
+			*out = append(*out, FieldError{
+				Field:   fieldPath(append(append([]string{}, verr.InstanceLocation...), missing)),
+				Message: "is required",
+			})
+		}
+		return
+	}
+
+	*out = append(*out, FieldError{
+		Field:   fieldPath(verr.InstanceLocation),
+		Message: verr.ErrorKind.LocalizedString(printer),
+	})
+}
+
+// fieldPath renders an instance location like ["series_ids", "2"] as "series_ids[2]"
+func fieldPath(location []string) string {
+	var sb strings.Builder
+	for _, token := range location {
+		if _, err := strconv.Atoi(token); err == nil && sb.Len() > 0 {
+			sb.WriteString("[" + token + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(token)
+	}
+	return sb.String()
+}
@@ -4,14 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/logging"
 	"github.com/aquaflow/etl-jobs-scheduler/internal/scheduler"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/tracing"
 	_ "github.com/lib/pq"
 )
 
@@ -23,13 +25,13 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 	maxBackoff := 30 * time.Second
 	
 	for i := 0; i <= maxRetries; i++ {
-		log.Printf("Attempting database connection (attempt %d/%d)...", i+1, maxRetries+1)
+		slog.Info("Attempting database connection", "attempt", i+1, "max_attempts", maxRetries+1)
 		
 		database, err = sql.Open("postgres", dbURL)
 		if err != nil {
-			log.Printf("Failed to open database connection: %v", err)
+			slog.Error("Failed to open database connection", "error", err)
 			if i < maxRetries {
-				log.Printf("Retrying in %v...", backoff)
+				slog.Info("Retrying database connection", "backoff", backoff.String())
 				time.Sleep(backoff)
 				backoff = backoff * 2
 				if backoff > maxBackoff {
@@ -46,10 +48,10 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 		cancel()
 		
 		if err != nil {
-			log.Printf("Failed to ping database: %v", err)
+			slog.Error("Failed to ping database", "error", err)
 			database.Close()
 			if i < maxRetries {
-				log.Printf("Retrying in %v...", backoff)
+				slog.Info("Retrying database connection", "backoff", backoff.String())
 				time.Sleep(backoff)
 				backoff = backoff * 2
 				if backoff > maxBackoff {
@@ -65,7 +67,7 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 		database.SetMaxIdleConns(2)
 		database.SetConnMaxLifetime(time.Hour)
 		
-		log.Println("Successfully connected to database")
+		slog.Info("Successfully connected to database")
 		return database, nil
 	}
 	
@@ -73,27 +75,63 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 }
 
 func main() {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile)
-	
-	log.Println("ETL Jobs Scheduler starting up...")
+	logger, err := logging.Init("etl-jobs-scheduler")
+	if err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("ETL Jobs Scheduler starting up")
 	
 	// Database connection
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+		logging.Fatal("DATABASE_URL environment variable is required")
 	}
 
 	// Connect with retry logic
 	database, err := connectWithRetry(dbURL, 10)
 	if err != nil {
-		log.Fatal("Failed to establish database connection:", err)
+		logging.Fatal("Failed to establish database connection", "error", err)
 	}
 	defer database.Close()
 
-	// Initialize components
+	// Tracing; OTEL_TRACES_EXPORTER selects the exporter
+	shutdownTracing, err := tracing.Init(context.Background(), "etl-jobs-scheduler")
+	if err != nil {
+		logging.Fatal("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
+	// Initialize components; replicas elect a leader through a lease, and
+	// only the leader creates runs
 	dbClient := db.NewClient(database)
-	schedulerLogger := log.New(os.Stdout, "[SCHEDULER] ", log.LstdFlags)
-	schedulerInstance := scheduler.NewScheduler(dbClient, schedulerLogger)
+	instanceID := os.Getenv("SCHEDULER_INSTANCE_ID")
+	if instanceID == "" {
+		hostname, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	leaseTTL := 15 * time.Second
+	if v := os.Getenv("SCHEDULER_LEASE_TTL"); v != "" {
+		if leaseTTL, err = time.ParseDuration(v); err != nil || leaseTTL < 3*time.Second {
+			logging.Fatal("Invalid SCHEDULER_LEASE_TTL, must be at least 3s", "value", v)
+		}
+	}
+	elector := scheduler.NewElector(dbClient, instanceID, leaseTTL, logger.With("component", "elector"))
+	// Runs waiting on upstream jobs fail after maxWait; 0 waits forever
+	maxWait := 24 * time.Hour
+	if v := os.Getenv("SCHEDULER_MAX_WAIT"); v != "" {
+		if maxWait, err = time.ParseDuration(v); err != nil || maxWait < 0 {
+			logging.Fatal("Invalid SCHEDULER_MAX_WAIT", "value", v)
+		}
+	}
+	schedulerInstance := scheduler.NewScheduler(dbClient, logger.With("component", "scheduler"), elector, maxWait)
 
 	// Setup graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -104,7 +142,7 @@ func main() {
 
 	go func() {
 		<-sigChan
-		log.Println("Shutdown signal received, stopping scheduler...")
+		slog.Info("Shutdown signal received, stopping scheduler")
 		cancel()
 	}()
 
@@ -114,12 +152,29 @@ func main() {
 		if parsed, err := time.ParseDuration(intervalEnv); err == nil {
 			checkInterval = parsed
 		} else {
-			log.Printf("WARNING: Invalid SCHEDULER_CHECK_INTERVAL '%s', using default %v", intervalEnv, checkInterval)
+			slog.Warn("Invalid SCHEDULER_CHECK_INTERVAL, using default", "value", intervalEnv, "default", checkInterval.String())
 		}
 	}
 
-	log.Printf("ETL Jobs Scheduler started successfully")
-	log.Printf("Configuration: CheckInterval=%v, MaxConnections=%d", checkInterval, 5)
+	// Metrics and leader status endpoints
+	httpAddr := ":8082"
+	if addr, ok := os.LookupEnv("SCHEDULER_HTTP_ADDR"); ok {
+		httpAddr = addr
+	}
+	if httpAddr != "" {
+		go serveHTTP(ctx, httpAddr, elector)
+	}
+
+	// The elector releases the lease on shutdown so a standby takes over
+	electorDone := make(chan struct{})
+	go func() {
+		defer close(electorDone)
+		elector.Run(ctx)
+	}()
+
+	slog.Info("ETL Jobs Scheduler started successfully",
+		"check_interval", checkInterval.String(), "max_connections", 5,
+		"instance_id", instanceID, "lease_ttl", leaseTTL.String(), "max_wait", maxWait.String())
 
 	// Health check goroutine
 	go func() {
@@ -133,11 +188,11 @@ func main() {
 			case <-healthTicker.C:
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				if err := database.PingContext(ctx); err != nil {
-					log.Printf("WARNING: Database health check failed: %v", err)
+					slog.Warn("Database health check failed", "error", err)
 				} else {
 					// Log active templates count
 					if count, err := dbClient.GetActiveSchedulesCount(); err == nil {
-						log.Printf("Health check OK - monitoring %d active schedules", count)
+						slog.Info("Health check OK", "active_schedules", count)
 					}
 				}
 				cancel()
@@ -148,9 +203,11 @@ func main() {
 	// Start the scheduler
 	if err := schedulerInstance.Start(ctx, checkInterval); err != nil {
 		if err == context.Canceled {
-			log.Println("Scheduler shutdown completed")
+			slog.Info("Scheduler shutdown completed")
 		} else {
-			log.Printf("Scheduler error: %v", err)
+			slog.Error("Scheduler error", "error", err)
 		}
 	}
+	cancel()
+	<-electorDone
 }
\ No newline at end of file
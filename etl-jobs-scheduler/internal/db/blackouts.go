@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/google/uuid"
+)
+
+// Blackout actions, set per calendar
+const (
+	BlackoutSkip  = "skip"
+	BlackoutDefer = "defer"
+)
+
+// BlackoutWindow is a window of an active calendar attached to a schedule or
+// to its job
+type BlackoutWindow struct {
+	CalendarID   uuid.UUID
+	CalendarName string
+	// Action is skip or defer
+	Action     string
+	WindowID   uuid.UUID
+	WindowName string
+	Reason     *string
+	Window     cron.Window
+}
+
+// Blackout is the window a fire landed in and what to do about it
+type Blackout struct {
+	BlackoutWindow
+	// Closes is when the window the fire landed in closes
+	Closes time.Time
+	// Until is when a deferred run may be queued, once every window has
+	// closed
+	Until time.Time
+}
+
+// reason describes the blackout for a run's skip or blocked reason
+func (b *Blackout) reason(verb string, until time.Time) string {
+	msg := fmt.Sprintf("%s by blackout window '%s' of calendar '%s' until %s",
+		verb, b.WindowName, b.CalendarName, until.Format(time.RFC3339))
+	if b.Reason != nil && *b.Reason != "" {
+		msg += ": " + *b.Reason
+	}
+	return msg
+}
+
+// GetBlackoutWindows returns the windows of the active calendars attached to
+// a schedule or to its job. Recurring windows are evaluated in their own
+// timezone.
+func (c *Client) GetBlackoutWindows(scheduleID, jobID uuid.UUID) ([]BlackoutWindow, error) {
+	query := `
+		SELECT DISTINCT c.calendar_id, c.calendar_name, c.action,
+			   w.window_id, w.window_name, w.reason, w.starts_at, w.ends_at,
+			   COALESCE(w.recurrence, ''), COALESCE(w.duration_seconds, 0), w.timezone
+		FROM aquaflow.etl_calendar_attachments a
+		JOIN aquaflow.etl_calendars c ON a.calendar_id = c.calendar_id
+		JOIN aquaflow.etl_calendar_windows w ON w.calendar_id = c.calendar_id
+		WHERE c.is_active = true
+		  AND (a.schedule_id = $1 OR a.job_id = $2)
+		ORDER BY c.calendar_name, w.window_name
+	`
+
+	rows, err := c.db.Query(query, scheduleID, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blackout windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []BlackoutWindow
+	for rows.Next() {
+		var bw BlackoutWindow
+		var startsAt, endsAt sql.NullTime
+		var durationSeconds int
+		var timezone string
+		if err := rows.Scan(&bw.CalendarID, &bw.CalendarName, &bw.Action,
+			&bw.WindowID, &bw.WindowName, &bw.Reason, &startsAt, &endsAt,
+			&bw.Window.Recurrence, &durationSeconds, &timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan blackout window: %w", err)
+		}
+		if startsAt.Valid {
+			bw.Window.Start = &startsAt.Time
+		}
+		if endsAt.Valid {
+			bw.Window.End = &endsAt.Time
+		}
+		bw.Window.Duration = time.Duration(durationSeconds) * time.Second
+		if bw.Window.Location, err = cron.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("blackout window '%s': %w", bw.WindowName, err)
+		}
+		windows = append(windows, bw)
+	}
+	return windows, rows.Err()
+}
+
+// deferredRun returns the schedule's run that is still waiting out a
+// blackout at scheduledFor, which a fire deferred then is merged into, or nil
+// if there is none
+func deferredRun(tx *sql.Tx, scheduleID uuid.UUID, scheduledFor time.Time) (*uuid.UUID, error) {
+	query := `
+		SELECT run_id
+		FROM aquaflow.etl_job_runs
+		WHERE schedule_id = $1 AND status = 'waiting' AND deferred_until > $2
+		ORDER BY scheduled_for ASC
+		LIMIT 1
+		FOR UPDATE
+	`
+	var runID uuid.UUID
+	err := tx.QueryRow(query, scheduleID, scheduledFor).Scan(&runID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deferred run: %w", err)
+	}
+	return &runID, nil
+}
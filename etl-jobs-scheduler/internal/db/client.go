@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/aquaflow/etl-common/jobschema"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/params"
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
@@ -16,6 +19,10 @@ type Client struct {
 	db *sql.DB
 }
 
+// ErrRunExists is returned by CreateJobRun when the schedule already has a
+// run for the fire time
+var ErrRunExists = errors.New("run already exists for this fire time")
+
 type Job struct {
 	JobID       uuid.UUID              `json:"job_id"`
 	JobName     string                 `json:"job_name"`
@@ -25,6 +32,11 @@ type Job struct {
 	IsActive    bool                   `json:"is_active"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+	// OverlapPolicy is allow, skip, queue or replace
+	OverlapPolicy     string `json:"overlap_policy"`
+	MaxConcurrentRuns *int   `json:"max_concurrent_runs"`
+	// Priority is copied to scheduled runs
+	Priority int `json:"priority"`
 }
 
 type Schedule struct {
@@ -38,6 +50,26 @@ type Schedule struct {
 	LastRun        *time.Time `json:"last_run"`
 	RunCount       int        `json:"run_count"`
 	FailureCount   int        `json:"failure_count"`
+	// MisfirePolicy is fire_once, fire_all or skip
+	MisfirePolicy       string `json:"misfire_policy"`
+	MaxCatchupRuns      int    `json:"max_catchup_runs"`
+	MisfireGraceSeconds int    `json:"misfire_grace_seconds"`
+	// Kind is cron, descriptor, interval or once; see cron.Spec
+	Kind            string     `json:"schedule_kind"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	AnchorAt        *time.Time `json:"anchor_at"`
+	RunAt           *time.Time `json:"run_at"`
+}
+
+// Spec returns when the schedule fires
+func (s Schedule) Spec() cron.Spec {
+	return cron.Spec{
+		Kind:            s.Kind,
+		Expression:      s.CronExpression,
+		IntervalSeconds: s.IntervalSeconds,
+		AnchorAt:        s.AnchorAt,
+		RunAt:           s.RunAt,
+	}
 }
 
 type JobRun struct {
@@ -59,6 +91,14 @@ type JobRun struct {
 	MaxRetries         int                    `json:"max_retries"`
 	RuntimeParameters  map[string]interface{} `json:"runtime_parameters"`
 	WorkerID           *string                `json:"worker_id"`
+	CorrelationID      uuid.UUID              `json:"correlation_id"`
+	BlockedReason      *string                `json:"blocked_reason"`
+	SkipReason         *string                `json:"skip_reason"`
+	Priority           int                    `json:"priority"`
+	// DeferredUntil is when a run deferred by a blackout may be queued
+	DeferredUntil *time.Time `json:"deferred_until"`
+	// ReplacedRuns are the earlier runs cancelled by the replace policy
+	ReplacedRuns []uuid.UUID `json:"-"`
 }
 
 func NewClient(db *sql.DB) *Client {
@@ -68,8 +108,10 @@ func NewClient(db *sql.DB) *Client {
 // GetDueSchedules returns all active schedules that are due for execution
 func (c *Client) GetDueSchedules(now time.Time) ([]Schedule, error) {
 	query := `
-		SELECT s.schedule_id, s.job_id, s.schedule_name, s.cron_expression, s.timezone,
-			   s.is_active, s.next_run, s.last_run, s.run_count, s.failure_count
+		SELECT s.schedule_id, s.job_id, s.schedule_name, COALESCE(s.cron_expression, ''), COALESCE(s.timezone, 'UTC'),
+			   s.schedule_kind, COALESCE(s.interval_seconds, 0), s.anchor_at, s.run_at,
+			   s.is_active, s.next_run, s.last_run, s.run_count, s.failure_count,
+			   s.misfire_policy, s.max_catchup_runs, s.misfire_grace_seconds
 		FROM aquaflow.etl_schedules s
 		JOIN aquaflow.etl_jobs_v2 j ON s.job_id = j.job_id
 		WHERE s.is_active = true 
@@ -91,8 +133,10 @@ func (c *Client) GetDueSchedules(now time.Time) ([]Schedule, error) {
 
 		err := rows.Scan(
 			&schedule.ScheduleID, &schedule.JobID, &schedule.ScheduleName, &schedule.CronExpression,
-			&schedule.Timezone, &schedule.IsActive, &schedule.NextRun, &schedule.LastRun,
+			&schedule.Timezone, &schedule.Kind, &schedule.IntervalSeconds, &schedule.AnchorAt, &schedule.RunAt,
+			&schedule.IsActive, &schedule.NextRun, &schedule.LastRun,
 			&schedule.RunCount, &schedule.FailureCount,
+			&schedule.MisfirePolicy, &schedule.MaxCatchupRuns, &schedule.MisfireGraceSeconds,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan schedule: %w", err)
@@ -108,7 +152,8 @@ func (c *Client) GetDueSchedules(now time.Time) ([]Schedule, error) {
 func (c *Client) GetJobForSchedule(scheduleID uuid.UUID) (*Job, error) {
 	query := `
 		SELECT j.job_id, j.job_name, j.job_type, j.description, j.parameters,
-			   j.is_active, j.created_at, j.updated_at
+			   j.is_active, j.created_at, j.updated_at, j.overlap_policy, j.max_concurrent_runs,
+			   j.priority
 		FROM aquaflow.etl_jobs_v2 j
 		JOIN aquaflow.etl_schedules s ON j.job_id = s.job_id
 		WHERE s.schedule_id = $1
@@ -120,6 +165,7 @@ func (c *Client) GetJobForSchedule(scheduleID uuid.UUID) (*Job, error) {
 	err := c.db.QueryRow(query, scheduleID).Scan(
 		&job.JobID, &job.JobName, &job.JobType, &job.Description,
 		&paramsJSON, &job.IsActive, &job.CreatedAt, &job.UpdatedAt,
+		&job.OverlapPolicy, &job.MaxConcurrentRuns, &job.Priority,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job for schedule: %w", err)
@@ -135,21 +181,134 @@ func (c *Client) GetJobForSchedule(scheduleID uuid.UUID) (*Job, error) {
 	return &job, nil
 }
 
-// CreateJobRun creates a new ETL job run from a schedule
-func (c *Client) CreateJobRun(schedule Schedule, job Job, scheduledFor time.Time) (*JobRun, error) {
+// GetJobTypeSchema returns the parameter schema published for a job type
+func (c *Client) GetJobTypeSchema(jobType string) ([]byte, error) {
+	var schema []byte
+	query := `SELECT parameter_schema FROM aquaflow.etl_job_types WHERE job_type = $1`
+	err := c.db.QueryRow(query, jobType).Scan(&schema)
+	if err == sql.ErrNoRows {
+		return nil, &jobschema.ValidationError{
+			JobType: jobType,
+			Errors:  []jobschema.FieldError{{Field: "job_type", Message: "unknown job type"}},
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job type schema: %w", err)
+	}
+	return schema, nil
+}
+
+// CreateJobRun creates a new ETL job run from a schedule. If the parameter
+// templates fail to expand, or the expanded parameters fail schema
+// validation, the run is recorded as failed with a configuration error, and
+// the *jobschema.ValidationError is returned alongside it. The job's overlap policy is applied to its active runs: the
+// new run may be recorded as skipped, or cancel the runs it replaces. A run
+// whose upstream jobs are not yet satisfied is created in the waiting state
+// with the reason it is blocked. traceContext is stored so the worker
+// continues the scheduling cycle's trace.
+//
+// blackout is the window the fire landed in, if any. Its calendar's action
+// records the run as skipped, or creates it waiting until blackout.Until; a
+// fire deferred while an earlier one still waits is skipped in its favour.
+func (c *Client) CreateJobRun(schedule Schedule, job Job, scheduledFor time.Time, blackout *Blackout, traceContext *string) (*JobRun, error) {
 	tx, err := c.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Create new run ID
+	// Create new run ID; the correlation ID follows the run through the worker and restarts
 	newRunID := uuid.New()
+	correlationID := uuid.New()
 
-	// Process dynamic parameters
-	processedParams, err := c.processDynamicParameters(job.Parameters, scheduledFor)
+	// Expand the parameter templates, then validate the result against the
+	// job type schema. A run whose templates fail keeps them unexpanded.
+	data, err := templateData(tx, schedule, job, newRunID, scheduledFor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process dynamic parameters: %w", err)
+		return nil, err
+	}
+	status := "queued"
+	var errorMessage, errorCategory *string
+	var validationErr *jobschema.ValidationError
+	processedParams, err := params.Render(job.Parameters, data)
+	if err != nil {
+		processedParams = job.Parameters
+		err = templateValidationError(job.JobType, err)
+	} else {
+		var schema []byte
+		schema, err = c.GetJobTypeSchema(job.JobType)
+		if err == nil {
+			err = jobschema.Validate(job.JobType, schema, processedParams, jobschema.ModeRun)
+		}
+	}
+	if err != nil {
+		if !errors.As(err, &validationErr) {
+			return nil, fmt.Errorf("failed to validate parameters: %w", err)
+		}
+		status = "failed"
+		msg, category := validationErr.Error(), "configuration"
+		errorMessage, errorCategory = &msg, &category
+	}
+
+	// Apply the blackout the fire landed in
+	var skipReason string
+	var skipContext map[string]interface{}
+	var deferredUntil *time.Time
+	if validationErr == nil && blackout != nil {
+		skipContext = map[string]interface{}{
+			"calendar": blackout.CalendarName,
+			"window":   blackout.WindowName,
+			"action":   blackout.Action,
+		}
+		if blackout.Action == BlackoutDefer {
+			waitingID, err := deferredRun(tx, schedule.ScheduleID, scheduledFor)
+			if err != nil {
+				return nil, err
+			}
+			if waitingID != nil {
+				skipReason = fmt.Sprintf("Merged into run %s, deferred by blackout window '%s' of calendar '%s'",
+					waitingID, blackout.WindowName, blackout.CalendarName)
+				skipContext["deferred_run_id"] = waitingID
+			} else {
+				deferredUntil = &blackout.Until
+			}
+		} else {
+			skipReason = blackout.reason("Skipped", blackout.Closes)
+		}
+		if skipReason != "" {
+			status = "skipped"
+		}
+	}
+
+	// Apply the overlap policy to the job's earlier runs
+	var replaced []activeRun
+	if validationErr == nil && skipReason == "" {
+		active, err := activeRuns(tx, job.JobID)
+		if err != nil {
+			return nil, err
+		}
+		skipReason, replaced = overlapDecision(job.OverlapPolicy, active)
+		if skipReason != "" {
+			status = "skipped"
+			skipContext = map[string]interface{}{"overlap_policy": job.OverlapPolicy}
+		}
+	}
+
+	// Hold a deferred run until the blackout is over, and any other run
+	// until its upstream jobs have run. A deferred run's dependencies are
+	// checked once it is released.
+	var blockedReason string
+	if status == "queued" && deferredUntil != nil {
+		blockedReason = blackout.reason("Deferred", *deferredUntil)
+		status = "waiting"
+	} else if status == "queued" {
+		blockedReason, err = c.CheckDependencies(job.JobID, scheduledFor)
+		if err != nil {
+			return nil, err
+		}
+		if blockedReason != "" {
+			status = "waiting"
+		}
 	}
 
 	paramsJSON, err := json.Marshal(processedParams)
@@ -157,32 +316,93 @@ func (c *Client) CreateJobRun(schedule Schedule, job Job, scheduledFor time.Time
 		return nil, fmt.Errorf("failed to marshal parameters: %w", err)
 	}
 
-	// Insert new job run
+	// Insert new job run. A failed or skipped run is finished as it is
+	// created; the flag is its own parameter so $5 is only ever a varchar.
+	finished := status == "failed" || status == "skipped"
 	insertQuery := `
 		INSERT INTO aquaflow.etl_job_runs (
 			run_id, job_id, schedule_id, run_name, status, trigger_type,
-			started_at, runtime_parameters
-		) VALUES ($1, $2, $3, $4, 'queued', 'scheduled', $5, $6)
+			started_at, runtime_parameters, error_message, error_category, completed_at,
+			correlation_id, blocked_reason, skip_reason, priority, trace_context, scheduled_for, deferred_until
+		) VALUES ($1, $2, $3, $4, $5, 'scheduled', $6, $7, $8, $9,
+			CASE WHEN $16 THEN NOW() END, $10, $11, $12, $13, $14, $6, $15)
+		ON CONFLICT (schedule_id, scheduled_for) WHERE schedule_id IS NOT NULL AND scheduled_for IS NOT NULL
+		DO NOTHING
 	`
 
 	runName := fmt.Sprintf("%s - %s", job.JobName, scheduledFor.Format("2006-01-02 15:04"))
 
-	_, err = tx.Exec(insertQuery,
-		newRunID, job.JobID, schedule.ScheduleID, runName, 
-		scheduledFor, paramsJSON,
+	result, err := tx.Exec(insertQuery,
+		newRunID, job.JobID, schedule.ScheduleID, runName, status,
+		scheduledFor, paramsJSON, errorMessage, errorCategory, correlationID,
+		nullableString(blockedReason), nullableString(skipReason), job.Priority, traceContext, deferredUntil, finished,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert job run: %w", err)
 	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to insert job run: %w", err)
+	} else if inserted == 0 {
+		// Another scheduler replica already created this fire's run
+		return nil, ErrRunExists
+	}
+
+	// Record the scheduling decision in the run's log
+	if validationErr != nil {
+		for _, fe := range validationErr.Errors {
+			if err := insertRunLog(tx, newRunID, correlationID, "validator", "ERROR", "Invalid job parameter", map[string]interface{}{
+				"field":   fe.Field,
+				"message": fe.Message,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if err := insertRunLog(tx, newRunID, correlationID, "scheduler", "INFO", "Run scheduled", map[string]interface{}{
+			"schedule_id":   schedule.ScheduleID,
+			"schedule_name": schedule.ScheduleName,
+			"scheduled_for": scheduledFor,
+		}); err != nil {
+			return nil, err
+		}
+		if skipReason != "" {
+			skipContext["skip_reason"] = skipReason
+			if err := insertRunLog(tx, newRunID, correlationID, "scheduler", "WARN", "Run skipped", skipContext); err != nil {
+				return nil, err
+			}
+		}
+		if len(replaced) > 0 {
+			if err := cancelReplacedRuns(tx, replaced, newRunID); err != nil {
+				return nil, err
+			}
+		}
+		if deferredUntil != nil {
+			if err := insertRunLog(tx, newRunID, correlationID, "scheduler", "WARN", "Run deferred by blackout window", map[string]interface{}{
+				"calendar":       blackout.CalendarName,
+				"window":         blackout.WindowName,
+				"deferred_until": *deferredUntil,
+				"blocked_reason": blockedReason,
+			}); err != nil {
+				return nil, err
+			}
+		} else if blockedReason != "" {
+			if err := insertRunLog(tx, newRunID, correlationID, "scheduler", "INFO", "Run waiting for dependencies", map[string]interface{}{
+				"blocked_reason": blockedReason,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	// Update schedule statistics
 	updateScheduleQuery := `
 		UPDATE aquaflow.etl_schedules 
 		SET run_count = run_count + 1,
+			last_run = GREATEST(last_run, $2),
 			updated_at = NOW()
 		WHERE schedule_id = $1
 	`
-	_, err = tx.Exec(updateScheduleQuery, schedule.ScheduleID)
+	_, err = tx.Exec(updateScheduleQuery, schedule.ScheduleID, scheduledFor)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update schedule: %w", err)
 	}
@@ -197,28 +417,67 @@ func (c *Client) CreateJobRun(schedule Schedule, job Job, scheduledFor time.Time
 		JobID:             job.JobID,
 		ScheduleID:        &schedule.ScheduleID,
 		RunName:           runName,
-		Status:            "queued",
+		Status:            status,
 		TriggerType:       "scheduled",
 		StartedAt:         scheduledFor,
 		RecordsProcessed:  0,
 		RecordsFailed:     0,
 		RecordsSkipped:    0,
+		ErrorMessage:      errorMessage,
+		ErrorCategory:     errorCategory,
 		RetryCount:        0,
 		MaxRetries:        3,
 		RuntimeParameters: processedParams,
+		CorrelationID:     correlationID,
+		Priority:          job.Priority,
+	}
+	if blockedReason != "" {
+		jobRun.BlockedReason = &blockedReason
+	}
+	if skipReason != "" {
+		jobRun.SkipReason = &skipReason
+	}
+	jobRun.DeferredUntil = deferredUntil
+	for _, run := range replaced {
+		jobRun.ReplacedRuns = append(jobRun.ReplacedRuns, run.RunID)
 	}
 
+	if validationErr != nil {
+		return jobRun, validationErr
+	}
 	return jobRun, nil
 }
 
-// UpdateScheduleNextRun updates the next_run time for a schedule
+// insertRunLog writes an entry to etl_job_logs_v2 as part of tx
+func insertRunLog(tx *sql.Tx, runID, correlationID uuid.UUID, component, level, message string, context map[string]interface{}) error {
+	contextJSON, err := json.Marshal(context)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log context: %w", err)
+	}
+
+	query := `
+		INSERT INTO aquaflow.etl_job_logs_v2 (run_id, log_level, message, context, component, correlation_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.Exec(query, runID, level, message, contextJSON, component, correlationID); err != nil {
+		return fmt.Errorf("failed to write run log: %w", err)
+	}
+	return nil
+}
+
+// UpdateScheduleNextRun updates the next_run time for a schedule. The zero
+// time clears it, for a schedule that never fires again.
 func (c *Client) UpdateScheduleNextRun(scheduleID uuid.UUID, nextRun time.Time) error {
 	query := `
 		UPDATE aquaflow.etl_schedules 
 		SET next_run = $1, updated_at = NOW()
 		WHERE schedule_id = $2
 	`
-	_, err := c.db.Exec(query, nextRun, scheduleID)
+	var next sql.NullTime
+	if !nextRun.IsZero() {
+		next = sql.NullTime{Time: nextRun, Valid: true}
+	}
+	_, err := c.db.Exec(query, next, scheduleID)
 	if err != nil {
 		return fmt.Errorf("failed to update schedule next run: %w", err)
 	}
@@ -230,6 +489,25 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 	return c.db.PingContext(ctx)
 }
 
+// GetNextFireTime returns the earliest next_run after now among active
+// schedules, or nil if none is pending
+func (c *Client) GetNextFireTime(ctx context.Context, now time.Time) (*time.Time, error) {
+	query := `
+		SELECT MIN(s.next_run)
+		FROM aquaflow.etl_schedules s
+		JOIN aquaflow.etl_jobs_v2 j ON s.job_id = j.job_id
+		WHERE s.is_active = true AND j.is_active = true AND s.next_run > $1
+	`
+	var next sql.NullTime
+	if err := c.db.QueryRowContext(ctx, query, now).Scan(&next); err != nil {
+		return nil, fmt.Errorf("failed to query next fire time: %w", err)
+	}
+	if !next.Valid {
+		return nil, nil
+	}
+	return &next.Time, nil
+}
+
 // GetActiveSchedulesCount returns the count of active schedules
 func (c *Client) GetActiveSchedulesCount() (int, error) {
 	var count int
@@ -242,41 +520,3 @@ func (c *Client) GetActiveSchedulesCount() (int, error) {
 	err := c.db.QueryRow(query).Scan(&count)
 	return count, err
 }
-
-// processDynamicParameters processes parameters with dynamic date placeholders
-func (c *Client) processDynamicParameters(params map[string]interface{}, scheduledFor time.Time) (map[string]interface{}, error) {
-	processed := make(map[string]interface{})
-
-	for key, value := range params {
-		switch v := value.(type) {
-		case string:
-			processed[key] = c.processDynamicDateString(v, scheduledFor)
-		default:
-			processed[key] = value
-		}
-	}
-
-	return processed, nil
-}
-
-// processDynamicDateString replaces dynamic date placeholders with actual dates
-func (c *Client) processDynamicDateString(input string, scheduledFor time.Time) string {
-	now := scheduledFor
-
-	replacements := map[string]string{
-		"DYNAMIC_WEEK_START": now.AddDate(0, 0, -int(now.Weekday())).Format("2006-01-02"),
-		"DYNAMIC_WEEK_END":   now.AddDate(0, 0, 6-int(now.Weekday())).Format("2006-01-02"),
-		"DYNAMIC_DAY_START":  now.Format("2006-01-02"),
-		"DYNAMIC_DAY_END":    now.Format("2006-01-02"),
-		"DYNAMIC_YESTERDAY":  now.AddDate(0, 0, -1).Format("2006-01-02"),
-		"DYNAMIC_MONTH_START": time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02"),
-		"DYNAMIC_MONTH_END":   time.Date(now.Year(), now.Month()+1, 0, 23, 59, 59, 0, now.Location()).Format("2006-01-02"),
-	}
-
-	result := input
-	for placeholder, replacement := range replacements {
-		result = strings.ReplaceAll(result, placeholder, replacement)
-	}
-
-	return result
-}
\ No newline at end of file
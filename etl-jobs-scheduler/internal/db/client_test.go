@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testClient connects to the database in TEST_DATABASE_URL, which must have
+// the migrations in db/migrations applied. Tests that need it are skipped
+// when it is not set.
+func testClient(t *testing.T) (*Client, *sql.DB) {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.Ping(); err != nil {
+		t.Fatalf("connect to database: %v", err)
+	}
+	return NewClient(conn), conn
+}
+
+// testJob creates a job type, a job of that type and an hourly schedule for
+// it, removed again with their runs when the test ends
+func testJob(t *testing.T, conn *sql.DB, overlapPolicy string, params map[string]interface{}) (Schedule, Job) {
+	t.Helper()
+	suffix := uuid.New().String()[:8]
+	jobType := "test_" + suffix
+	schema := `{"type": "object", "required": ["source_url"], "properties": {"source_url": {"type": "string"}}}`
+
+	if _, err := conn.Exec(`
+		INSERT INTO aquaflow.etl_job_types (job_type, description, parameter_schema)
+		VALUES ($1, 'scheduler test', $2)
+	`, jobType, schema); err != nil {
+		t.Fatalf("insert job type: %v", err)
+	}
+
+	job := Job{
+		JobName:       "scheduler test " + suffix,
+		JobType:       jobType,
+		Parameters:    params,
+		IsActive:      true,
+		OverlapPolicy: overlapPolicy,
+		Priority:      5,
+	}
+	if err := conn.QueryRow(`
+		INSERT INTO aquaflow.etl_jobs_v2 (job_name, job_type, parameters, overlap_policy)
+		VALUES ($1, $2, '{}', $3)
+		RETURNING job_id
+	`, job.JobName, jobType, overlapPolicy).Scan(&job.JobID); err != nil {
+		t.Fatalf("insert job: %v", err)
+	}
+
+	schedule := Schedule{
+		JobID:          job.JobID,
+		ScheduleName:   "hourly",
+		CronExpression: "0 * * * *",
+		Timezone:       "UTC",
+		IsActive:       true,
+		Kind:           "cron",
+	}
+	if err := conn.QueryRow(`
+		INSERT INTO aquaflow.etl_schedules (job_id, schedule_name, cron_expression)
+		VALUES ($1, $2, $3)
+		RETURNING schedule_id
+	`, job.JobID, schedule.ScheduleName, schedule.CronExpression).Scan(&schedule.ScheduleID); err != nil {
+		t.Fatalf("insert schedule: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM aquaflow.etl_job_runs WHERE job_id = $1`, job.JobID)
+		conn.Exec(`DELETE FROM aquaflow.etl_jobs_v2 WHERE job_id = $1`, job.JobID)
+		conn.Exec(`DELETE FROM aquaflow.etl_job_types WHERE job_type = $1`, jobType)
+	})
+	return schedule, job
+}
+
+// storedRun reads back the status and completed_at of a run
+func storedRun(t *testing.T, conn *sql.DB, runID uuid.UUID) (string, *time.Time) {
+	t.Helper()
+	var status string
+	var completedAt *time.Time
+	if err := conn.QueryRow(`
+		SELECT status, completed_at FROM aquaflow.etl_job_runs WHERE run_id = $1
+	`, runID).Scan(&status, &completedAt); err != nil {
+		t.Fatalf("read run %s: %v", runID, err)
+	}
+	return status, completedAt
+}
+
+func TestCreateJobRun(t *testing.T) {
+	client, conn := testClient(t)
+	fire := time.Now().UTC().Truncate(time.Hour)
+	valid := map[string]interface{}{"source_url": "http://demo-data-service:8080/api/values"}
+
+	t.Run("queued", func(t *testing.T) {
+		schedule, job := testJob(t, conn, "allow", valid)
+		run, err := client.CreateJobRun(schedule, job, fire, nil, nil)
+		if err != nil {
+			t.Fatalf("CreateJobRun: %v", err)
+		}
+		status, completedAt := storedRun(t, conn, run.RunID)
+		if status != "queued" || completedAt != nil {
+			t.Errorf("run stored as %s, completed_at %v; want queued without completed_at", status, completedAt)
+		}
+
+		if _, err := client.CreateJobRun(schedule, job, fire, nil, nil); err != ErrRunExists {
+			t.Errorf("second run for the same fire: err = %v, want ErrRunExists", err)
+		}
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		schedule, job := testJob(t, conn, "allow", map[string]interface{}{})
+		run, err := client.CreateJobRun(schedule, job, fire, nil, nil)
+		if err == nil {
+			t.Fatal("CreateJobRun: want a validation error")
+		}
+		if run == nil {
+			t.Fatalf("CreateJobRun: no run recorded: %v", err)
+		}
+		status, completedAt := storedRun(t, conn, run.RunID)
+		if status != "failed" || completedAt == nil {
+			t.Errorf("run stored as %s, completed_at %v; want failed with completed_at", status, completedAt)
+		}
+	})
+
+	t.Run("skipped by overlap policy", func(t *testing.T) {
+		schedule, job := testJob(t, conn, "skip", valid)
+		if _, err := client.CreateJobRun(schedule, job, fire.Add(-time.Hour), nil, nil); err != nil {
+			t.Fatalf("CreateJobRun: %v", err)
+		}
+		run, err := client.CreateJobRun(schedule, job, fire, nil, nil)
+		if err != nil {
+			t.Fatalf("CreateJobRun: %v", err)
+		}
+		status, completedAt := storedRun(t, conn, run.RunID)
+		if status != "skipped" || completedAt == nil {
+			t.Errorf("run stored as %s, completed_at %v; want skipped with completed_at", status, completedAt)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dependency is the state of one upstream job as seen from a downstream run
+type Dependency struct {
+	UpstreamJobID    uuid.UUID
+	UpstreamJobName  string
+	Condition        string
+	FreshnessSeconds int
+	LatestStatus     *string
+	// LastCompletedAt is when the latest completed run of the upstream
+	// finished, LastFinishedAt the latest run in any final status
+	LastCompletedAt *time.Time
+	LastFinishedAt  *time.Time
+}
+
+// satisfied reports whether the upstream has a run that counts for the
+// condition and finished within its freshness window before scheduledFor
+func (d Dependency) satisfied(scheduledFor time.Time) bool {
+	finished := d.LastCompletedAt
+	if d.Condition == "always" {
+		finished = d.LastFinishedAt
+	}
+	cutoff := scheduledFor.Add(-time.Duration(d.FreshnessSeconds) * time.Second)
+	return finished != nil && !finished.Before(cutoff)
+}
+
+// describe explains why an unsatisfied dependency holds the run
+func (d Dependency) describe() string {
+	latest := "no runs yet"
+	if d.LatestStatus != nil {
+		latest = "latest run " + *d.LatestStatus
+	}
+	requirement := "a completed run"
+	if d.Condition == "always" {
+		requirement = "a finished run"
+	}
+	freshness := time.Duration(d.FreshnessSeconds) * time.Second
+	return fmt.Sprintf("%s needs %s within %s (%s)", d.UpstreamJobName, requirement, freshness, latest)
+}
+
+// CheckDependencies evaluates the upstream jobs of jobID for a run scheduled
+// at scheduledFor. An upstream is satisfied by a non-dry run that finished
+// within its freshness window before scheduledFor: a completed run for the
+// 'success' condition, or any finished run for 'always'. The returned reason
+// is empty when the run may be queued.
+func (c *Client) CheckDependencies(jobID uuid.UUID, scheduledFor time.Time) (string, error) {
+	query := `
+		SELECT d.upstream_job_id, u.job_name, d.condition, d.freshness_seconds,
+			(SELECT r.status FROM aquaflow.etl_job_runs r
+			 WHERE r.job_id = d.upstream_job_id AND NOT r.dry_run
+			 ORDER BY r.started_at DESC LIMIT 1),
+			(SELECT max(r.completed_at) FROM aquaflow.etl_job_runs r
+			 WHERE r.job_id = d.upstream_job_id AND NOT r.dry_run AND r.status = 'completed'),
+			(SELECT max(r.completed_at) FROM aquaflow.etl_job_runs r
+			 WHERE r.job_id = d.upstream_job_id AND NOT r.dry_run
+			   AND r.status IN ('completed', 'completed_with_errors', 'failed', 'cancelled'))
+		FROM aquaflow.etl_job_dependencies d
+		JOIN aquaflow.etl_jobs_v2 u ON d.upstream_job_id = u.job_id
+		WHERE d.job_id = $1
+		ORDER BY u.job_name
+	`
+
+	rows, err := c.db.Query(query, jobID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query job dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var deps []Dependency
+	for rows.Next() {
+		var dep Dependency
+		if err := rows.Scan(&dep.UpstreamJobID, &dep.UpstreamJobName, &dep.Condition,
+			&dep.FreshnessSeconds, &dep.LatestStatus, &dep.LastCompletedAt, &dep.LastFinishedAt); err != nil {
+			return "", fmt.Errorf("failed to scan job dependency: %w", err)
+		}
+		deps = append(deps, dep)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read job dependencies: %w", err)
+	}
+	return blockedReason(deps, scheduledFor), nil
+}
+
+// blockedReason explains which upstream jobs hold a run scheduled at
+// scheduledFor, or returns an empty string if none does
+func blockedReason(deps []Dependency, scheduledFor time.Time) string {
+	var blocked []string
+	for _, dep := range deps {
+		if !dep.satisfied(scheduledFor) {
+			blocked = append(blocked, dep.describe())
+		}
+	}
+	if len(blocked) == 0 {
+		return ""
+	}
+	return "Waiting for upstream jobs: " + strings.Join(blocked, "; ")
+}
+
+// GetWaitingRuns returns the runs held for their upstream jobs or deferred by
+// a blackout, oldest first
+func (c *Client) GetWaitingRuns() ([]JobRun, error) {
+	query := `
+		SELECT run_id, job_id, schedule_id, run_name, started_at, correlation_id, blocked_reason, deferred_until
+		FROM aquaflow.etl_job_runs
+		WHERE status = 'waiting'
+		ORDER BY started_at ASC
+	`
+
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query waiting runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []JobRun
+	for rows.Next() {
+		run := JobRun{Status: "waiting"}
+		if err := rows.Scan(&run.RunID, &run.JobID, &run.ScheduleID, &run.RunName,
+			&run.StartedAt, &run.CorrelationID, &run.BlockedReason, &run.DeferredUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan waiting run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// ReleaseWaitingRun queues a waiting run whose dependencies are satisfied.
+// It returns false if the run was no longer waiting, e.g. it was cancelled.
+func (c *Client) ReleaseWaitingRun(run JobRun) (bool, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'queued', blocked_reason = NULL
+		WHERE run_id = $1 AND status = 'waiting'
+	`, run.RunID)
+	if err != nil {
+		return false, fmt.Errorf("failed to release waiting run: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := insertRunLog(tx, run.RunID, run.CorrelationID, "scheduler", "INFO", "Dependencies satisfied, run queued", map[string]interface{}{
+		"waited_seconds": int(time.Since(run.StartedAt).Seconds()),
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// ExpireWaitingRun fails a run that has waited longer than the scheduler's
+// maximum wait. It returns false if the run was no longer waiting.
+func (c *Client) ExpireWaitingRun(run JobRun, maxWait time.Duration) (bool, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	errMsg := fmt.Sprintf("Gave up waiting after %s", maxWait)
+	if run.BlockedReason != nil && *run.BlockedReason != "" {
+		errMsg += ": " + *run.BlockedReason
+	}
+	result, err := tx.Exec(`
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'failed', error_message = $2, error_category = 'system', completed_at = NOW()
+		WHERE run_id = $1 AND status = 'waiting'
+	`, run.RunID, errMsg)
+	if err != nil {
+		return false, fmt.Errorf("failed to expire waiting run: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := insertRunLog(tx, run.RunID, run.CorrelationID, "scheduler", "ERROR", "Run expired while waiting", map[string]interface{}{
+		"max_wait_seconds": int(maxWait.Seconds()),
+		"blocked_reason":   run.BlockedReason,
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// UpdateBlockedReason stores a new reason on a waiting run
+func (c *Client) UpdateBlockedReason(runID uuid.UUID, reason string) error {
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET blocked_reason = $2
+		WHERE run_id = $1 AND status = 'waiting'
+	`
+	if _, err := c.db.Exec(query, runID, reason); err != nil {
+		return fmt.Errorf("failed to update blocked reason: %w", err)
+	}
+	return nil
+}
+
+// nullableString maps an empty string to NULL
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
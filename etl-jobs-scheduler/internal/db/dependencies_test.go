@@ -0,0 +1,75 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockedReason(t *testing.T) {
+	scheduledFor := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		ts := scheduledFor.Add(-d)
+		return &ts
+	}
+	failed := "failed"
+
+	tests := []struct {
+		name        string
+		dep         Dependency
+		wantBlocked bool
+	}{
+		{"success met by a fresh completed run",
+			Dependency{Condition: "success", FreshnessSeconds: 3600, LastCompletedAt: ago(30 * time.Minute), LastFinishedAt: ago(30 * time.Minute)}, false},
+		{"success at the edge of the window",
+			Dependency{Condition: "success", FreshnessSeconds: 3600, LastCompletedAt: ago(time.Hour), LastFinishedAt: ago(time.Hour)}, false},
+		{"success blocked by a stale completed run",
+			Dependency{Condition: "success", FreshnessSeconds: 3600, LastCompletedAt: ago(2 * time.Hour), LastFinishedAt: ago(2 * time.Hour)}, true},
+		{"success blocked by a fresh failed run",
+			Dependency{Condition: "success", FreshnessSeconds: 3600, LatestStatus: &failed, LastCompletedAt: ago(26 * time.Hour), LastFinishedAt: ago(10 * time.Minute)}, true},
+		{"success blocked without runs",
+			Dependency{Condition: "success", FreshnessSeconds: 3600}, true},
+		{"always met by a fresh failed run",
+			Dependency{Condition: "always", FreshnessSeconds: 3600, LatestStatus: &failed, LastFinishedAt: ago(10 * time.Minute)}, false},
+		{"always blocked by a stale run",
+			Dependency{Condition: "always", FreshnessSeconds: 3600, LastFinishedAt: ago(90 * time.Minute)}, true},
+		{"run finished after the fire counts",
+			Dependency{Condition: "success", FreshnessSeconds: 60, LastCompletedAt: ago(-5 * time.Minute)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dep.UpstreamJobName = "upstream"
+			reason := blockedReason([]Dependency{tt.dep}, scheduledFor)
+			if blocked := reason != ""; blocked != tt.wantBlocked {
+				t.Errorf("blocked = %v (%q), want %v", blocked, reason, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestBlockedReasonListsUnmetUpstreams(t *testing.T) {
+	scheduledFor := time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)
+	fresh := scheduledFor.Add(-time.Minute)
+	deps := []Dependency{
+		{UpstreamJobName: "met", Condition: "success", FreshnessSeconds: 3600, LastCompletedAt: &fresh},
+		{UpstreamJobName: "never ran", Condition: "success", FreshnessSeconds: 3600},
+		{UpstreamJobName: "stale", Condition: "always", FreshnessSeconds: 30},
+	}
+
+	reason := blockedReason(deps, scheduledFor)
+	if !strings.HasPrefix(reason, "Waiting for upstream jobs: ") {
+		t.Fatalf("reason = %q", reason)
+	}
+	if strings.Contains(reason, "met needs") {
+		t.Errorf("satisfied upstream listed: %q", reason)
+	}
+	for _, want := range []string{"never ran needs a completed run within 1h0m0s (no runs yet)", "stale needs a finished run within 30s"} {
+		if !strings.Contains(reason, want) {
+			t.Errorf("reason %q does not mention %q", reason, want)
+		}
+	}
+	if got := blockedReason(deps[:1], scheduledFor); got != "" {
+		t.Errorf("reason with every upstream met = %q", got)
+	}
+}
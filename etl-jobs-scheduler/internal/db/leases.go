@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Lease is a leadership lease held by a scheduler replica
+type Lease struct {
+	Name       string    `json:"name"`
+	HolderID   string    `json:"holder_id"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Expired is true once ExpiresAt has passed on the database clock
+	Expired bool `json:"expired"`
+}
+
+// AcquireLease takes the named lease for holderID, or renews it if holderID
+// already holds it. It fails, returning false, while another holder's lease
+// is unexpired. Expiry is computed with the database clock.
+func (c *Client) AcquireLease(ctx context.Context, name, holderID string, ttl time.Duration) (bool, error) {
+	query := `
+		INSERT INTO aquaflow.etl_scheduler_leases (name, holder_id, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW(), NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE
+		SET holder_id = EXCLUDED.holder_id,
+			acquired_at = CASE WHEN etl_scheduler_leases.holder_id = EXCLUDED.holder_id
+				THEN etl_scheduler_leases.acquired_at ELSE NOW() END,
+			renewed_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE etl_scheduler_leases.holder_id = EXCLUDED.holder_id
+		   OR etl_scheduler_leases.expires_at < NOW()
+		RETURNING holder_id
+	`
+
+	var holder string
+	err := c.db.QueryRowContext(ctx, query, name, holderID, ttl.Milliseconds()).Scan(&holder)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if holderID holds it, so another
+// replica can take over without waiting for it to expire
+func (c *Client) ReleaseLease(ctx context.Context, name, holderID string) error {
+	query := `DELETE FROM aquaflow.etl_scheduler_leases WHERE name = $1 AND holder_id = $2`
+	if _, err := c.db.ExecContext(ctx, query, name, holderID); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetLease returns the named lease, or nil if nobody holds it. An expired
+// lease is still returned, with Expired set.
+func (c *Client) GetLease(ctx context.Context, name string) (*Lease, error) {
+	query := `
+		SELECT name, holder_id, acquired_at, renewed_at, expires_at, expires_at < NOW()
+		FROM aquaflow.etl_scheduler_leases
+		WHERE name = $1
+	`
+
+	var lease Lease
+	err := c.db.QueryRowContext(ctx, query, name).Scan(
+		&lease.Name, &lease.HolderID, &lease.AcquiredAt, &lease.RenewedAt, &lease.ExpiresAt, &lease.Expired)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
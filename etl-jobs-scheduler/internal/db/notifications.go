@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// NotificationChannel is the Postgres channel notifications are published on
+const NotificationChannel = "etl_notifications"
+
+// Notification severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is an event that needs someone to investigate
+type Notification struct {
+	EventType  string
+	Severity   string
+	Message    string
+	ScheduleID *uuid.UUID
+	JobID      *uuid.UUID
+	RunID      *uuid.UUID
+	Details    map[string]interface{}
+}
+
+// insertNotification stores n and publishes it on NotificationChannel. The
+// publication is delivered to listeners when tx commits.
+func insertNotification(tx *sql.Tx, n Notification) (uuid.UUID, error) {
+	detailsJSON, err := json.Marshal(n.Details)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal notification details: %w", err)
+	}
+
+	query := `
+		INSERT INTO aquaflow.etl_notifications (event_type, severity, message, schedule_id, job_id, run_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING notification_id
+	`
+	var notificationID uuid.UUID
+	if err := tx.QueryRow(query, n.EventType, n.Severity, n.Message, n.ScheduleID, n.JobID, n.RunID, detailsJSON).Scan(&notificationID); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert notification: %w", err)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"notification_id": notificationID,
+		"event_type":      n.EventType,
+		"severity":        n.Severity,
+		"message":         n.Message,
+		"schedule_id":     n.ScheduleID,
+		"job_id":          n.JobID,
+		"run_id":          n.RunID,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, NotificationChannel, string(payload)); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to publish notification: %w", err)
+	}
+	return notificationID, nil
+}
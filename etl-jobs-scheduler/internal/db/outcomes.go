@@ -0,0 +1,185 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RunOutcome is a finished scheduled run whose outcome its schedule has not
+// recorded yet
+type RunOutcome struct {
+	RunID         uuid.UUID
+	ScheduleID    uuid.UUID
+	ScheduleName  string
+	JobID         uuid.UUID
+	JobName       string
+	CorrelationID uuid.UUID
+	Status        string
+	CompletedAt   *time.Time
+	ErrorMessage  *string
+}
+
+// ScheduleOutcome is a schedule's state after recording a run's outcome
+type ScheduleOutcome struct {
+	ConsecutiveFailures int
+	AutoDisableAfter    *int
+	// Disabled is true when this outcome disabled the schedule
+	Disabled       bool
+	DisabledReason string
+	NotificationID uuid.UUID
+}
+
+// GetUnrecordedOutcomes returns up to limit finished scheduled runs whose
+// outcome has not been recorded, in the order they finished
+func (c *Client) GetUnrecordedOutcomes(limit int) ([]RunOutcome, error) {
+	query := `
+		SELECT r.run_id, r.schedule_id, s.schedule_name, r.job_id, j.job_name,
+			   r.correlation_id, r.status, r.completed_at, r.error_message
+		FROM aquaflow.etl_job_runs r
+		JOIN aquaflow.etl_schedules s ON r.schedule_id = s.schedule_id
+		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
+		WHERE r.schedule_id IS NOT NULL
+		  AND r.scheduled_for IS NOT NULL
+		  AND r.outcome_recorded_at IS NULL
+		  AND r.status IN ('completed', 'completed_with_errors', 'failed', 'cancelled', 'skipped')
+		ORDER BY r.completed_at ASC NULLS FIRST, r.scheduled_for ASC
+		LIMIT $1
+	`
+
+	rows, err := c.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unrecorded run outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []RunOutcome
+	for rows.Next() {
+		var o RunOutcome
+		if err := rows.Scan(&o.RunID, &o.ScheduleID, &o.ScheduleName, &o.JobID, &o.JobName,
+			&o.CorrelationID, &o.Status, &o.CompletedAt, &o.ErrorMessage); err != nil {
+			return nil, fmt.Errorf("failed to scan run outcome: %w", err)
+		}
+		outcomes = append(outcomes, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run outcomes: %w", err)
+	}
+	return outcomes, nil
+}
+
+// RecordRunOutcome records a finished run on its schedule: the schedule's
+// latest run, failure count and streak of consecutive failures. A failed run
+// that brings the streak to the schedule's auto_disable_after disables the
+// schedule, logs why against the run and raises a notification. It returns
+// nil if the outcome was already recorded.
+func (c *Client) RecordRunOutcome(o RunOutcome) (*ScheduleOutcome, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the run first so a concurrent cycle can't count it twice
+	res, err := tx.Exec(`
+		UPDATE aquaflow.etl_job_runs
+		SET outcome_recorded_at = NOW()
+		WHERE run_id = $1 AND outcome_recorded_at IS NULL
+	`, o.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark run outcome recorded: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to mark run outcome recorded: %w", err)
+	} else if n == 0 {
+		return nil, nil
+	}
+
+	// $3 is cast the same way everywhere so Postgres deduces one type for it
+	query := `
+		UPDATE aquaflow.etl_schedules
+		SET last_run_id = $2,
+			last_run_status = $3::text,
+			last_run_completed_at = $4,
+			consecutive_failures = CASE
+				WHEN $3::text = 'failed' THEN consecutive_failures + 1
+				WHEN $3::text IN ('completed', 'completed_with_errors') THEN 0
+				ELSE consecutive_failures END,
+			failure_count = COALESCE(failure_count, 0) + CASE WHEN $3::text = 'failed' THEN 1 ELSE 0 END,
+			updated_at = NOW()
+		WHERE schedule_id = $1
+		RETURNING consecutive_failures, auto_disable_after, is_active
+	`
+	var result ScheduleOutcome
+	var active bool
+	err = tx.QueryRow(query, o.ScheduleID, o.RunID, o.Status, o.CompletedAt).Scan(
+		&result.ConsecutiveFailures, &result.AutoDisableAfter, &active)
+	if err == sql.ErrNoRows {
+		// The schedule was deleted; the run is still marked
+		return &result, tx.Commit()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update schedule outcome: %w", err)
+	}
+
+	if o.Status == "failed" && active && result.AutoDisableAfter != nil &&
+		result.ConsecutiveFailures >= *result.AutoDisableAfter {
+		if err := disableSchedule(tx, o, &result); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &result, nil
+}
+
+// disableSchedule turns off the schedule of a run that ended a failure
+// streak, logs the decision against the run and notifies about it
+func disableSchedule(tx *sql.Tx, o RunOutcome, result *ScheduleOutcome) error {
+	reason := fmt.Sprintf("Disabled after %d consecutive failed runs", result.ConsecutiveFailures)
+	query := `
+		UPDATE aquaflow.etl_schedules
+		SET is_active = false, disabled_at = NOW(), disabled_reason = $2, updated_at = NOW()
+		WHERE schedule_id = $1
+	`
+	if _, err := tx.Exec(query, o.ScheduleID, reason); err != nil {
+		return fmt.Errorf("failed to disable schedule: %w", err)
+	}
+
+	details := map[string]interface{}{
+		"schedule_id":          o.ScheduleID,
+		"schedule_name":        o.ScheduleName,
+		"job_name":             o.JobName,
+		"consecutive_failures": result.ConsecutiveFailures,
+		"auto_disable_after":   *result.AutoDisableAfter,
+	}
+	if o.ErrorMessage != nil {
+		details["last_error"] = *o.ErrorMessage
+	}
+	if err := insertRunLog(tx, o.RunID, o.CorrelationID, "scheduler", "ERROR",
+		"Schedule disabled after repeated failures", details); err != nil {
+		return err
+	}
+
+	notificationID, err := insertNotification(tx, Notification{
+		EventType:  "schedule_disabled",
+		Severity:   SeverityCritical,
+		Message:    fmt.Sprintf("Schedule %s of job %s: %s", o.ScheduleName, o.JobName, reason),
+		ScheduleID: &o.ScheduleID,
+		JobID:      &o.JobID,
+		RunID:      &o.RunID,
+		Details:    details,
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Disabled = true
+	result.DisabledReason = reason
+	result.NotificationID = notificationID
+	return nil
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordRunOutcome(t *testing.T) {
+	client, conn := testClient(t)
+	schedule, job := testJob(t, conn, "allow", map[string]interface{}{"source_url": "http://demo-data-service:8080/api/values"})
+	if _, err := conn.Exec(`UPDATE aquaflow.etl_schedules SET auto_disable_after = 2 WHERE schedule_id = $1`, schedule.ScheduleID); err != nil {
+		t.Fatalf("set auto_disable_after: %v", err)
+	}
+
+	fire := time.Now().UTC().Truncate(time.Hour)
+	for i, wantDisabled := range []bool{false, true} {
+		run, err := client.CreateJobRun(schedule, job, fire.Add(time.Duration(i-2)*time.Hour), nil, nil)
+		if err != nil {
+			t.Fatalf("CreateJobRun: %v", err)
+		}
+		if _, err := conn.Exec(`
+			UPDATE aquaflow.etl_job_runs SET status = 'failed', completed_at = NOW() WHERE run_id = $1
+		`, run.RunID); err != nil {
+			t.Fatalf("fail run: %v", err)
+		}
+
+		outcomes, err := client.GetUnrecordedOutcomes(10)
+		if err != nil {
+			t.Fatalf("GetUnrecordedOutcomes: %v", err)
+		}
+		var outcome *RunOutcome
+		for i := range outcomes {
+			if outcomes[i].RunID == run.RunID {
+				outcome = &outcomes[i]
+			}
+		}
+		if outcome == nil {
+			t.Fatalf("run %s not listed as unrecorded", run.RunID)
+		}
+
+		result, err := client.RecordRunOutcome(*outcome)
+		if err != nil {
+			t.Fatalf("RecordRunOutcome: %v", err)
+		}
+		if result.ConsecutiveFailures != i+1 || result.Disabled != wantDisabled {
+			t.Errorf("failure %d: consecutive_failures %d, disabled %v; want %d, %v",
+				i+1, result.ConsecutiveFailures, result.Disabled, i+1, wantDisabled)
+		}
+
+		if again, err := client.RecordRunOutcome(*outcome); err != nil || again != nil {
+			t.Errorf("recording the outcome twice = %v, %v; want nil, nil", again, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// activeRun is a waiting, queued or running run of a job
+type activeRun struct {
+	RunID         uuid.UUID
+	Status        string
+	CorrelationID uuid.UUID
+}
+
+// activeRuns locks and returns the active runs of a job, oldest first. Dry
+// runs don't write data and are not considered.
+func activeRuns(tx *sql.Tx, jobID uuid.UUID) ([]activeRun, error) {
+	query := `
+		SELECT run_id, status, correlation_id
+		FROM aquaflow.etl_job_runs
+		WHERE job_id = $1
+		  AND status IN ('waiting', 'queued', 'running')
+		  AND NOT dry_run
+		ORDER BY started_at ASC
+		FOR UPDATE
+	`
+
+	rows, err := tx.Query(query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []activeRun
+	for rows.Next() {
+		var run activeRun
+		if err := rows.Scan(&run.RunID, &run.Status, &run.CorrelationID); err != nil {
+			return nil, fmt.Errorf("failed to scan active run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// overlapDecision applies a job's overlap policy to its active runs. It
+// returns the reason to skip the new run, or the runs the new one replaces.
+func overlapDecision(policy string, active []activeRun) (skipReason string, replaced []activeRun) {
+	if len(active) == 0 {
+		return "", nil
+	}
+	switch policy {
+	case "skip":
+		latest := active[len(active)-1]
+		return fmt.Sprintf("Skipped by overlap policy: run %s is still %s", latest.RunID, latest.Status), nil
+	case "replace":
+		return "", active
+	}
+	// allow and queue both queue the new run; workers serialize queued runs
+	return "", nil
+}
+
+// cancelReplacedRuns cancels the runs replaced by newRunID. Workers notice
+// the cancellation of a running run and stop it.
+func cancelReplacedRuns(tx *sql.Tx, replaced []activeRun, newRunID uuid.UUID) error {
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'cancelled', error_message = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE run_id = $1
+	`
+	for _, run := range replaced {
+		msg := fmt.Sprintf("Replaced by newer run %s", newRunID)
+		if _, err := tx.Exec(query, run.RunID, msg); err != nil {
+			return fmt.Errorf("failed to cancel replaced run: %w", err)
+		}
+		if err := insertRunLog(tx, run.RunID, run.CorrelationID, "scheduler", "WARN", "Run cancelled, replaced by a newer run", map[string]interface{}{
+			"replaced_by":     newRunID,
+			"previous_status": run.Status,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquaflow/etl-common/jobschema"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/params"
+	"github.com/google/uuid"
+)
+
+// templateData gathers what a run's parameter templates are evaluated
+// against. Times are given in the timezone of scheduledFor.
+func templateData(tx *sql.Tx, schedule Schedule, job Job, runID uuid.UUID, scheduledFor time.Time) (params.Data, error) {
+	loc := scheduledFor.Location()
+	data := params.Data{
+		ScheduledFor: scheduledFor,
+		Now:          time.Now().In(loc),
+		RunID:        runID.String(),
+		Schedule: params.Schedule{
+			ID:             schedule.ScheduleID.String(),
+			Name:           schedule.ScheduleName,
+			Kind:           schedule.Kind,
+			CronExpression: schedule.CronExpression,
+			Timezone:       schedule.Timezone,
+		},
+		Job: params.Job{
+			ID:   job.JobID.String(),
+			Name: job.JobName,
+			Type: job.JobType,
+		},
+	}
+
+	var err error
+	data.PrevRun, err = previousRun(tx, schedule.ScheduleID, scheduledFor, false, loc)
+	if err != nil {
+		return data, err
+	}
+	data.LastSuccess, err = previousRun(tx, schedule.ScheduleID, scheduledFor, true, loc)
+	if err != nil {
+		return data, err
+	}
+
+	// Any completed run of the job counts, including manual runs and backfills
+	query := `
+		SELECT MAX(watermark)
+		FROM aquaflow.etl_job_runs
+		WHERE job_id = $1
+		  AND status IN ('completed', 'completed_with_errors')
+		  AND NOT dry_run
+	`
+	var watermark sql.NullTime
+	if err := tx.QueryRow(query, job.JobID).Scan(&watermark); err != nil {
+		return data, fmt.Errorf("failed to query job watermark: %w", err)
+	}
+	if watermark.Valid {
+		t := watermark.Time.In(loc)
+		data.Watermark = &t
+	}
+	return data, nil
+}
+
+// previousRun returns the schedule's latest finished run scheduled before
+// scheduledFor, or its latest completed one if succeeded is set
+func previousRun(tx *sql.Tx, scheduleID uuid.UUID, scheduledFor time.Time, succeeded bool, loc *time.Location) (*params.Run, error) {
+	statuses := "'completed', 'completed_with_errors', 'failed', 'cancelled'"
+	if succeeded {
+		statuses = "'completed', 'completed_with_errors'"
+	}
+	query := `
+		SELECT run_id, status, scheduled_for, completed_at, runtime_parameters,
+			   COALESCE(records_processed, 0), watermark
+		FROM aquaflow.etl_job_runs
+		WHERE schedule_id = $1
+		  AND scheduled_for < $2
+		  AND status IN (` + statuses + `)
+		  AND NOT dry_run
+		ORDER BY scheduled_for DESC
+		LIMIT 1
+	`
+
+	var run params.Run
+	var runID uuid.UUID
+	var completedAt, watermark sql.NullTime
+	var paramsJSON []byte
+	err := tx.QueryRow(query, scheduleID, scheduledFor).Scan(
+		&runID, &run.Status, &run.ScheduledFor, &completedAt, &paramsJSON, &run.RecordsProcessed, &watermark)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query previous run: %w", err)
+	}
+
+	run.RunID = runID.String()
+	run.ScheduledFor = run.ScheduledFor.In(loc)
+	if completedAt.Valid {
+		t := completedAt.Time.In(loc)
+		run.CompletedAt = &t
+	}
+	if watermark.Valid {
+		t := watermark.Time.In(loc)
+		run.Watermark = &t
+	}
+	run.Params = map[string]interface{}{}
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &run.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode previous run parameters: %w", err)
+		}
+	}
+	return &run, nil
+}
+
+// templateValidationError reports parameter templates that failed to expand
+// the way schema violations are reported, so the run is recorded as failed
+// with a configuration error
+func templateValidationError(jobType string, err error) error {
+	var templateErrs params.Errors
+	if !errors.As(err, &templateErrs) {
+		return err
+	}
+	validationErr := &jobschema.ValidationError{JobType: jobType}
+	for _, fe := range templateErrs {
+		validationErr.Errors = append(validationErr.Errors, jobschema.FieldError{
+			Field:   fe.Field,
+			Message: "template error: " + fe.Err.Error(),
+		})
+	}
+	return validationErr
+}
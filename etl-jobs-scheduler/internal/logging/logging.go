@@ -0,0 +1,58 @@
+// Package logging sets up the scheduler's process logger. It writes JSON
+// lines to stdout with the field names the backend and workers use, so
+// entries can be joined on run_id, job_name and so on.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Field names shared by all services
+const (
+	KeyService   = "service"
+	KeyRunID     = "run_id"
+	KeyJobName   = "job_name"
+	KeySeriesID  = "series_id"
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+)
+
+// Init installs a JSON logger tagged with service as the slog default, which
+// also routes the standard log package through it. LOG_LEVEL sets the level:
+// debug, info (the default), warn or error.
+func Init(service string) (*slog.Logger, error) {
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return nil, err
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	logger := slog.New(handler).With(KeyService, service)
+	slog.SetDefault(logger)
+	log.SetFlags(0)
+	return logger, nil
+}
+
+// ParseLevel parses a LOG_LEVEL value; empty means info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid LOG_LEVEL %q", name)
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,113 @@
+// Package metrics holds the scheduler's Prometheus metrics. Label names are
+// shared with the backend and worker: job_name, job_type, status.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "aquaflow"
+
+var (
+	// CycleDuration is the time one scheduling cycle takes
+	CycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "cycle_duration_seconds",
+		Help:      "Duration of scheduling cycles.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// Lag is how late the most overdue schedule of the last cycle was
+	Lag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "lag_seconds",
+		Help:      "Delay between the scheduled time and the cycle that created the run, for the most overdue schedule of the last cycle.",
+	})
+
+	// LastCycle is when the last scheduling cycle started
+	LastCycle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "last_cycle_timestamp_seconds",
+		Help:      "Unix time the last scheduling cycle started.",
+	})
+
+	// RunsCreated counts runs created from schedules, by their initial status
+	RunsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_created_total",
+		Help:      "Runs created from schedules, by initial status.",
+	}, []string{"job_name", "job_type", "status"})
+
+	// RunsReleased counts waiting runs queued once their dependencies were met
+	RunsReleased = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_released_total",
+		Help:      "Waiting runs queued once their dependencies were satisfied.",
+	})
+
+	// RunsExpired counts waiting runs failed after the maximum wait
+	RunsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_expired_total",
+		Help:      "Waiting runs failed after waiting longer than the maximum wait for their dependencies.",
+	})
+
+	// RunsWaiting is the number of runs still waiting after the last cycle
+	RunsWaiting = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_waiting",
+		Help:      "Runs waiting for dependencies, or for a blackout to end, after the last cycle.",
+	})
+
+	// MisfiresDropped counts missed fires the misfire policy created no run for
+	MisfiresDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "misfires_dropped_total",
+		Help:      "Missed schedule fires dropped by the misfire policy.",
+	}, []string{"job_name"})
+
+	// FiresBlocked counts schedule fires that landed in a blackout window, by
+	// the calendar's action
+	FiresBlocked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "fires_blocked_total",
+		Help:      "Schedule fires that landed in a blackout window, by action (skip or defer).",
+	}, []string{"job_name", "action"})
+
+	// SchedulesDisabled counts schedules disabled after repeated failures
+	SchedulesDisabled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "schedules_disabled_total",
+		Help:      "Schedules disabled after reaching their consecutive failure limit.",
+	}, []string{"job_name"})
+
+	// Leader is 1 while this replica holds the scheduler lease
+	Leader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "leader",
+		Help:      "Whether this replica is the scheduler leader (1) or a standby (0).",
+	})
+
+	// Errors counts errors during scheduling cycles
+	Errors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scheduler",
+		Name:      "errors_total",
+		Help:      "Errors during scheduling cycles.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(CycleDuration, Lag, LastCycle, RunsCreated, RunsReleased, RunsExpired, RunsWaiting, MisfiresDropped, FiresBlocked, SchedulesDisabled, Leader, Errors)
+}
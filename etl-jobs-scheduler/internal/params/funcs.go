@@ -0,0 +1,254 @@
+package params
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// layouts are the named formats accepted by format and parseTime
+var layouts = map[string]string{
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04:05",
+	"rfc3339":  time.RFC3339,
+	"compact":  "20060102",
+	"hour":     "2006-01-02T15",
+}
+
+// inputLayouts are tried in order when a string is used as a time
+var inputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// funcs returns the template functions. Times are taken as values, *time.Time
+// or strings; strings without an offset are read in loc.
+//
+// Time functions take the time last, so they chain in pipelines:
+//
+//	addDays N, addHours N, addMinutes N, addMonths N, addYears N, add "1h30m"
+//	startOf UNIT, endOf UNIT (UNIT is minute, hour, day, week, month or year)
+//	truncate "15m", in "Europe/Berlin", utc
+//	format LAYOUT (a Go layout or date, datetime, rfc3339, compact, hour)
+//	unix, unixMilli, toTime, parseTime LAYOUT, earliest, latest
+//	default FALLBACK
+func funcs(loc *time.Location) template.FuncMap {
+	if loc == nil {
+		loc = time.UTC
+	}
+	toTime := func(v interface{}) (time.Time, error) {
+		return asTime(v, loc)
+	}
+	shift := func(apply func(time.Time) time.Time) func(interface{}) (time.Time, error) {
+		return func(v interface{}) (time.Time, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return apply(t), nil
+		}
+	}
+
+	return template.FuncMap{
+		"toTime": toTime,
+		"addDays": func(n int, v interface{}) (time.Time, error) {
+			return shift(func(t time.Time) time.Time { return t.AddDate(0, 0, n) })(v)
+		},
+		"addMonths": func(n int, v interface{}) (time.Time, error) {
+			return shift(func(t time.Time) time.Time { return t.AddDate(0, n, 0) })(v)
+		},
+		"addYears": func(n int, v interface{}) (time.Time, error) {
+			return shift(func(t time.Time) time.Time { return t.AddDate(n, 0, 0) })(v)
+		},
+		"addHours": func(n int, v interface{}) (time.Time, error) {
+			return shift(func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Hour) })(v)
+		},
+		"addMinutes": func(n int, v interface{}) (time.Time, error) {
+			return shift(func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Minute) })(v)
+		},
+		"add": func(d string, v interface{}) (time.Time, error) {
+			dur, err := time.ParseDuration(d)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return shift(func(t time.Time) time.Time { return t.Add(dur) })(v)
+		},
+		"truncate": func(d string, v interface{}) (time.Time, error) {
+			dur, err := time.ParseDuration(d)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return shift(func(t time.Time) time.Time { return t.Truncate(dur) })(v)
+		},
+		"startOf": func(unit string, v interface{}) (time.Time, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return startOf(unit, t)
+		},
+		"endOf": func(unit string, v interface{}) (time.Time, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return endOf(unit, t)
+		},
+		"in": func(name string, v interface{}) (time.Time, error) {
+			target, err := time.LoadLocation(name)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid timezone '%s': %w", name, err)
+			}
+			return shift(func(t time.Time) time.Time { return t.In(target) })(v)
+		},
+		"utc": shift(func(t time.Time) time.Time { return t.UTC() }),
+		"format": func(layout string, v interface{}) (string, error) {
+			t, err := toTime(v)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(namedLayout(layout)), nil
+		},
+		"parseTime": func(layout, s string) (time.Time, error) {
+			return time.ParseInLocation(namedLayout(layout), s, loc)
+		},
+		"unix": func(v interface{}) (int64, error) {
+			t, err := toTime(v)
+			return t.Unix(), err
+		},
+		"unixMilli": func(v interface{}) (int64, error) {
+			t, err := toTime(v)
+			return t.UnixMilli(), err
+		},
+		"earliest": func(first interface{}, rest ...interface{}) (time.Time, error) {
+			return pick(first, rest, loc, time.Time.Before)
+		},
+		"latest": func(first interface{}, rest ...interface{}) (time.Time, error) {
+			return pick(first, rest, loc, time.Time.After)
+		},
+		"default": func(fallback, v interface{}) interface{} {
+			if empty(v) {
+				return fallback
+			}
+			return v
+		},
+	}
+}
+
+// asTime converts a template value to a time
+func asTime(v interface{}, loc *time.Location) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("no time value")
+		}
+		return *v, nil
+	case string:
+		for _, layout := range inputLayouts {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot read %q as a time", v)
+	case nil:
+		return time.Time{}, fmt.Errorf("no time value")
+	}
+	return time.Time{}, fmt.Errorf("cannot use %T as a time", v)
+}
+
+// startOf returns the start of the calendar unit containing t, in t's
+// timezone. Weeks start on Sunday.
+func startOf(unit string, t time.Time) (time.Time, error) {
+	y, m, d := t.Date()
+	switch strings.ToLower(unit) {
+	case "minute":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location()), nil
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location()), nil
+	case "week":
+		return time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, t.Location()), nil
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()), nil
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown time unit %q", unit)
+}
+
+// endOf returns the end of the calendar unit containing t: the start of the
+// next one, so startOf..endOf is a half-open range covering the unit
+func endOf(unit string, t time.Time) (time.Time, error) {
+	start, err := startOf(unit, t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := start.Date()
+	switch strings.ToLower(unit) {
+	case "minute":
+		return start.Add(time.Minute), nil
+	case "hour":
+		return start.Add(time.Hour), nil
+	case "day":
+		return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()), nil
+	case "week":
+		return time.Date(y, m, d+7, 0, 0, 0, 0, t.Location()), nil
+	case "month":
+		return time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location()), nil
+	}
+	return time.Date(y+1, 1, 1, 0, 0, 0, 0, t.Location()), nil
+}
+
+// namedLayout resolves a named format, or returns layout as a Go layout
+func namedLayout(layout string) string {
+	if named, ok := layouts[strings.ToLower(layout)]; ok {
+		return named
+	}
+	return layout
+}
+
+// pick returns the time among first and rest for which better holds against
+// every other
+func pick(first interface{}, rest []interface{}, loc *time.Location, better func(time.Time, time.Time) bool) (time.Time, error) {
+	best, err := asTime(first, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, v := range rest {
+		t, err := asTime(v, loc)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if better(t, best) {
+			best = t
+		}
+	}
+	return best, nil
+}
+
+// empty reports whether v is unset: nil, a nil pointer, a zero time or an
+// empty string, map or slice
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	if t, ok := v.(time.Time); ok {
+		return t.IsZero()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	case reflect.String, reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package params
+
+import (
+	"strings"
+	"time"
+)
+
+// dateLayout is the format of the legacy placeholders
+const dateLayout = "2006-01-02"
+
+// replaceLegacy replaces the DYNAMIC_* date placeholders that predate
+// templates. Dates are taken in the timezone of scheduledFor; weeks start on
+// Sunday. Every *_END is the last day of its period, inclusive, as it was
+// before templates: DYNAMIC_DAY_END is the scheduled day itself. Templates
+// use endOf for an exclusive end.
+func replaceLegacy(s string, scheduledFor time.Time) string {
+	if !strings.Contains(s, "DYNAMIC_") {
+		return s
+	}
+
+	t := scheduledFor
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	weekStart := day.AddDate(0, 0, -int(t.Weekday()))
+
+	return strings.NewReplacer(
+		"DYNAMIC_WEEK_START", weekStart.Format(dateLayout),
+		"DYNAMIC_WEEK_END", weekStart.AddDate(0, 0, 6).Format(dateLayout),
+		"DYNAMIC_DAY_START", day.Format(dateLayout),
+		"DYNAMIC_DAY_END", day.Format(dateLayout),
+		"DYNAMIC_YESTERDAY", day.AddDate(0, 0, -1).Format(dateLayout),
+		"DYNAMIC_MONTH_START", month.Format(dateLayout),
+		"DYNAMIC_MONTH_END", month.AddDate(0, 1, -1).Format(dateLayout),
+	).Replace(s)
+}
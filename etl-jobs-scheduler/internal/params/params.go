@@ -0,0 +1,165 @@
+// Package params expands the templates in a job's parameters when the
+// scheduler creates a run.
+//
+// Any string value, at any depth of nested objects and arrays, may hold Go
+// text/template actions evaluated against Data: the scheduled time, the
+// schedule and job, and the schedule's previous runs. For example
+//
+//	"start_date": "{{ .ScheduledFor | addDays -1 | format `date` }}"
+//	"since":      "{{ .Watermark | default (.ScheduledFor | startOf `day`) | format `rfc3339` }}"
+//
+// Missing map keys are an error; use index for optional ones. The legacy
+// DYNAMIC_* placeholders are still replaced.
+package params
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data is what parameter templates are evaluated against. Times are in the
+// schedule's timezone.
+type Data struct {
+	// ScheduledFor is the fire time the run is created for
+	ScheduledFor time.Time
+	// Now is when the scheduler created the run
+	Now      time.Time
+	RunID    string
+	Schedule Schedule
+	Job      Job
+	// PrevRun is the schedule's latest finished run before this fire,
+	// whatever its status; nil if there is none
+	PrevRun *Run
+	// LastSuccess is the schedule's latest completed run before this fire
+	LastSuccess *Run
+	// Watermark is the latest data timestamp written by any completed run of
+	// the job; nil until one has written data
+	Watermark *time.Time
+}
+
+// Schedule describes the schedule a run is created from
+type Schedule struct {
+	ID   string
+	Name string
+	// Kind is cron, descriptor, interval or once
+	Kind           string
+	CronExpression string
+	Timezone       string
+}
+
+// Job describes the job a run is created for
+type Job struct {
+	ID   string
+	Name string
+	Type string
+}
+
+// Run is an earlier run of the schedule
+type Run struct {
+	RunID            string
+	Status           string
+	ScheduledFor     time.Time
+	CompletedAt      *time.Time
+	Params           map[string]interface{}
+	RecordsProcessed int
+	// Watermark is the latest data timestamp the run wrote
+	Watermark *time.Time
+}
+
+// FieldError is a parameter whose template could not be expanded
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Errors lists every parameter that failed to expand
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fmt.Sprintf("%s: %v", fe.Field, fe.Err))
+	}
+	return "invalid parameter templates: " + strings.Join(parts, "; ")
+}
+
+// Render returns a copy of params with every string value expanded. If any
+// value fails to expand, the returned error is Errors.
+func Render(params map[string]interface{}, data Data) (map[string]interface{}, error) {
+	r := &renderer{data: data, funcs: funcs(data.ScheduledFor.Location())}
+	out := make(map[string]interface{}, len(params))
+	for _, key := range sortedKeys(params) {
+		out[key] = r.value(key, params[key])
+	}
+	if len(r.errs) > 0 {
+		return nil, r.errs
+	}
+	return out, nil
+}
+
+type renderer struct {
+	data  Data
+	funcs template.FuncMap
+	errs  Errors
+}
+
+// value expands v, found at the parameter path field
+func (r *renderer) value(field string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		s, err := r.expand(field, v)
+		if err != nil {
+			r.errs = append(r.errs, FieldError{Field: field, Err: err})
+			return v
+		}
+		return s
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for _, key := range sortedKeys(v) {
+			out[key] = r.value(field+"."+key, v[key])
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = r.value(field+"["+strconv.Itoa(i)+"]", item)
+		}
+		return out
+	}
+	return v
+}
+
+// expand replaces the legacy placeholders in s, then executes it as a
+// template if it has any actions
+func (r *renderer) expand(field, s string) (string, error) {
+	s = replaceLegacy(s, r.data.ScheduledFor)
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New(field).Funcs(r.funcs).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r.data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sortedKeys returns m's keys in order, so errors are reported in a stable
+// order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,198 @@
+package params
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testData(t *testing.T) Data {
+	t.Helper()
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Data{
+		// Thursday 2024-05-02 06:30 in Los Angeles, 13:30 UTC
+		ScheduledFor: time.Date(2024, 5, 2, 6, 30, 0, 0, la),
+		RunID:        "run-1",
+		Schedule:     Schedule{ID: "sched-1", Name: "nightly", Timezone: "America/Los_Angeles"},
+		Job:          Job{ID: "job-1", Name: "loader", Type: "historical_load"},
+	}
+}
+
+func TestRenderRecursesIntoMapsAndArrays(t *testing.T) {
+	params := map[string]interface{}{
+		"start_date": "{{ .ScheduledFor | addDays -1 | format `date` }}",
+		"source": map[string]interface{}{
+			"url":     "http://source/{{ .Job.Name }}",
+			"retries": float64(3),
+			"headers": map[string]interface{}{"X-Run": "{{ .RunID }}"},
+		},
+		"windows": []interface{}{
+			"{{ .ScheduledFor | startOf `day` | format `rfc3339` }}",
+			map[string]interface{}{"end": "{{ .ScheduledFor | endOf `day` | format `rfc3339` }}"},
+			true,
+		},
+		"plain": "unchanged",
+	}
+
+	got, err := Render(params, testData(t))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	want := map[string]interface{}{
+		"start_date": "2024-05-01",
+		"source": map[string]interface{}{
+			"url":     "http://source/loader",
+			"retries": float64(3),
+			"headers": map[string]interface{}{"X-Run": "run-1"},
+		},
+		"windows": []interface{}{
+			"2024-05-02T00:00:00-07:00",
+			map[string]interface{}{"end": "2024-05-03T00:00:00-07:00"},
+			true,
+		},
+		"plain": "unchanged",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Render = %#v, want %#v", got, want)
+	}
+	if params["start_date"] != "{{ .ScheduledFor | addDays -1 | format `date` }}" {
+		t.Error("Render modified its input")
+	}
+}
+
+func TestRenderReportsEveryFailedField(t *testing.T) {
+	params := map[string]interface{}{
+		"a": "{{ .Missing }}",
+		"nested": map[string]interface{}{
+			"list": []interface{}{"ok", "{{ .Job.Nope }}"},
+		},
+		"b": "{{ .ScheduledFor | format `date` ",
+		"c": "{{ .ScheduledFor | format `date` }}",
+	}
+
+	_, err := Render(params, testData(t))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Render error = %v, want Errors", err)
+	}
+	var fields []string
+	for _, fe := range errs {
+		fields = append(fields, fe.Field)
+	}
+	if want := []string{"a", "b", "nested.list[1]"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("failed fields = %v, want %v", fields, want)
+	}
+}
+
+func TestRenderMissingMapKey(t *testing.T) {
+	data := testData(t)
+	data.PrevRun = &Run{Params: map[string]interface{}{"end_date": "2024-05-01"}}
+
+	got, err := Render(map[string]interface{}{"start": "{{ .PrevRun.Params.end_date }}"}, data)
+	if err != nil || got["start"] != "2024-05-01" {
+		t.Fatalf("Render = %v, %v", got, err)
+	}
+
+	if _, err := Render(map[string]interface{}{"start": "{{ .PrevRun.Params.start_date }}"}, data); err == nil {
+		t.Error("missing map key rendered without error")
+	}
+
+	// index is the way to read optional keys
+	got, err = Render(map[string]interface{}{"start": "{{ index .PrevRun.Params `start_date` | default `none` }}"}, data)
+	if err != nil || got["start"] != "none" {
+		t.Errorf("Render with index = %v, %v", got, err)
+	}
+}
+
+func TestRenderTimezones(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		// Times are in the schedule's timezone
+		{"{{ .ScheduledFor | format `datetime` }}", "2024-05-02 06:30:00"},
+		{"{{ .ScheduledFor | utc | format `rfc3339` }}", "2024-05-02T13:30:00Z"},
+		{"{{ .ScheduledFor | in `Europe/Berlin` | format `datetime` }}", "2024-05-02 15:30:00"},
+		{"{{ .ScheduledFor | in `Asia/Tokyo` | startOf `day` | format `rfc3339` }}", "2024-05-02T00:00:00+09:00"},
+		// Strings without an offset are read in the schedule's timezone
+		{"{{ `2024-05-01` | toTime | utc | format `rfc3339` }}", "2024-05-01T07:00:00Z"},
+		{"{{ `2024-05-01T00:00:00Z` | toTime | format `rfc3339` }}", "2024-05-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(map[string]interface{}{"v": tt.template}, testData(t))
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if got["v"] != tt.want {
+			t.Errorf("%s = %v, want %s", tt.template, got["v"], tt.want)
+		}
+	}
+
+	if _, err := Render(map[string]interface{}{"v": "{{ .ScheduledFor | in `Mars/Olympus` }}"}, testData(t)); err == nil {
+		t.Error("unknown timezone rendered without error")
+	}
+}
+
+func TestRenderWatermarkDefault(t *testing.T) {
+	params := map[string]interface{}{
+		"since": "{{ .Watermark | default (.ScheduledFor | startOf `day`) | format `rfc3339` }}",
+	}
+
+	data := testData(t)
+	got, err := Render(params, data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "2024-05-02T00:00:00-07:00"; got["since"] != want {
+		t.Errorf("without a watermark since = %v, want %s", got["since"], want)
+	}
+
+	watermark := time.Date(2024, 5, 2, 4, 15, 0, 0, time.UTC)
+	data.Watermark = &watermark
+	if got, err = Render(params, data); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "2024-05-02T04:15:00Z"; got["since"] != want {
+		t.Errorf("with a watermark since = %v, want %s", got["since"], want)
+	}
+}
+
+func TestRenderLegacyPlaceholders(t *testing.T) {
+	params := map[string]interface{}{
+		"day":       "DYNAMIC_DAY_START..DYNAMIC_DAY_END",
+		"week":      "DYNAMIC_WEEK_START..DYNAMIC_WEEK_END",
+		"month":     "DYNAMIC_MONTH_START..DYNAMIC_MONTH_END",
+		"yesterday": "DYNAMIC_YESTERDAY",
+		"mixed":     "DYNAMIC_DAY_START/{{ .Job.Name }}",
+	}
+
+	got, err := Render(params, testData(t))
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	// Every end is the last day of its period, inclusive
+	want := map[string]interface{}{
+		"day":       "2024-05-02..2024-05-02",
+		"week":      "2024-04-28..2024-05-04",
+		"month":     "2024-05-01..2024-05-31",
+		"yesterday": "2024-05-01",
+		"mixed":     "2024-05-02/loader",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Render = %v, want %v", got, want)
+	}
+}
+
+func TestErrorsMessage(t *testing.T) {
+	err := Errors{{Field: "a", Err: errors.New("bad")}, {Field: "b.c", Err: errors.New("worse")}}
+	if msg := err.Error(); !strings.Contains(msg, "a: bad; b.c: worse") {
+		t.Errorf("Error() = %q", msg)
+	}
+}
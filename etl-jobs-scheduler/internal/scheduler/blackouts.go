@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+)
+
+// blackoutAt returns the blackout a fire lands in, or nil if no window of
+// the schedule's calendars is open at it. Of overlapping windows, the one
+// closing last decides the action. A deferred fire waits until every window
+// has closed, whatever its calendar's action.
+func (s *Scheduler) blackoutAt(windows []db.BlackoutWindow, fire time.Time) (*db.Blackout, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	spans := make([]cron.Window, len(windows))
+	for i, w := range windows {
+		spans[i] = w.Window
+	}
+
+	index, closes, err := s.cronParser.Blocked(spans, fire)
+	if err != nil || index < 0 {
+		return nil, err
+	}
+	blackout := &db.Blackout{BlackoutWindow: windows[index], Closes: closes, Until: closes}
+	if blackout.Action == db.BlackoutDefer {
+		if blackout.Until, err = s.cronParser.Clear(spans, closes); err != nil {
+			return nil, err
+		}
+	}
+	return blackout, nil
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+)
+
+func TestBlackoutAt(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return day.Add(time.Duration(hour) * time.Hour) }
+	window := func(name, action string, from, to int) db.BlackoutWindow {
+		start, end := at(from), at(to)
+		return db.BlackoutWindow{
+			CalendarName: name + " calendar",
+			Action:       action,
+			WindowName:   name,
+			Window:       cron.Window{Start: &start, End: &end},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		windows    []db.BlackoutWindow
+		fire       int
+		wantWindow string
+		wantCloses int
+		wantUntil  int
+	}{
+		{"no windows", nil, 3, "", 0, 0},
+		{"outside every window", []db.BlackoutWindow{window("a", db.BlackoutSkip, 2, 4)}, 5, "", 0, 0},
+		{"skip until the window closes", []db.BlackoutWindow{window("a", db.BlackoutSkip, 2, 4), window("b", db.BlackoutSkip, 4, 6)}, 3, "a", 4, 4},
+		{"defer until back-to-back windows close", []db.BlackoutWindow{window("a", db.BlackoutDefer, 2, 4), window("b", db.BlackoutSkip, 4, 6)}, 3, "a", 4, 6},
+		{"window closing last decides", []db.BlackoutWindow{window("a", db.BlackoutDefer, 2, 4), window("b", db.BlackoutSkip, 1, 5)}, 3, "b", 5, 5},
+	}
+
+	s := &Scheduler{cronParser: cron.NewParser()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blackout, err := s.blackoutAt(tt.windows, at(tt.fire))
+			if err != nil {
+				t.Fatalf("blackoutAt: %v", err)
+			}
+			if tt.wantWindow == "" {
+				if blackout != nil {
+					t.Errorf("blackoutAt = %s, want none", blackout.WindowName)
+				}
+				return
+			}
+			if blackout == nil {
+				t.Fatalf("blackoutAt = nil, want %s", tt.wantWindow)
+			}
+			if blackout.WindowName != tt.wantWindow || !blackout.Closes.Equal(at(tt.wantCloses)) || !blackout.Until.Equal(at(tt.wantUntil)) {
+				t.Errorf("blackoutAt = %s closes %s until %s, want %s closes %02d:00 until %02d:00",
+					blackout.WindowName, blackout.Closes.Format("15:04"), blackout.Until.Format("15:04"),
+					tt.wantWindow, tt.wantCloses, tt.wantUntil)
+			}
+		})
+	}
+}
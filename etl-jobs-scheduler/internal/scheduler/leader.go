@@ -0,0 +1,145 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/metrics"
+)
+
+// LeaseName is the lease scheduler replicas compete for
+const LeaseName = "etl-scheduler"
+
+// Elector keeps this replica's claim on the scheduler lease. Only the
+// leader runs scheduling cycles; the others stand by and take over once the
+// leader's lease expires or is released.
+type Elector struct {
+	db         *db.Client
+	instanceID string
+	ttl        time.Duration
+	logger     *slog.Logger
+
+	mu sync.Mutex
+	// leaderUntil is when this replica's lease runs out by the local clock;
+	// it is measured from before the renewal, so it never outlasts the
+	// database's expiry
+	leaderUntil time.Time
+	elected     chan struct{}
+}
+
+// LeaderStatus describes this replica and the current lease holder
+type LeaderStatus struct {
+	InstanceID string    `json:"instance_id"`
+	IsLeader   bool      `json:"is_leader"`
+	Lease      *db.Lease `json:"lease"`
+}
+
+// NewElector creates an elector for the replica identified by instanceID.
+// The lease is renewed every third of ttl.
+func NewElector(dbClient *db.Client, instanceID string, ttl time.Duration, logger *slog.Logger) *Elector {
+	return &Elector{
+		db:         dbClient,
+		instanceID: instanceID,
+		ttl:        ttl,
+		logger:     logger,
+		elected:    make(chan struct{}, 1),
+	}
+}
+
+// IsLeader reports whether this replica holds an unexpired lease
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Now().Before(e.leaderUntil)
+}
+
+// Elected receives a value each time this replica becomes the leader
+func (e *Elector) Elected() <-chan struct{} {
+	return e.elected
+}
+
+// Run competes for the lease until ctx is cancelled, then releases it
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.renew(ctx)
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns this replica's view of the election
+func (e *Elector) Status(ctx context.Context) (LeaderStatus, error) {
+	lease, err := e.db.GetLease(ctx, LeaseName)
+	if err != nil {
+		return LeaderStatus{}, err
+	}
+	return LeaderStatus{InstanceID: e.instanceID, IsLeader: e.IsLeader(), Lease: lease}, nil
+}
+
+// renew acquires or renews the lease. A failed renewal leaves the current
+// state to run out on its own, so a brief database error doesn't cost the
+// leadership.
+func (e *Elector) renew(ctx context.Context) {
+	start := time.Now()
+	renewCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	defer cancel()
+
+	acquired, err := e.db.AcquireLease(renewCtx, LeaseName, e.instanceID, e.ttl)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.Warn("Failed to renew scheduler lease", "error", err)
+		}
+		return
+	}
+
+	wasLeader := e.IsLeader()
+	e.mu.Lock()
+	if acquired {
+		e.leaderUntil = start.Add(e.ttl)
+	} else {
+		e.leaderUntil = time.Time{}
+	}
+	e.mu.Unlock()
+
+	switch {
+	case acquired && !wasLeader:
+		e.logger.Info("Acquired scheduler leadership", "instance_id", e.instanceID)
+		metrics.Leader.Set(1)
+		select {
+		case e.elected <- struct{}{}:
+		default:
+		}
+	case !acquired && wasLeader:
+		e.logger.Warn("Lost scheduler leadership", "instance_id", e.instanceID)
+		metrics.Leader.Set(0)
+	}
+}
+
+// release gives up the lease on shutdown so a standby takes over at once
+func (e *Elector) release() {
+	e.mu.Lock()
+	wasLeader := time.Now().Before(e.leaderUntil)
+	e.leaderUntil = time.Time{}
+	e.mu.Unlock()
+	metrics.Leader.Set(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.db.ReleaseLease(ctx, LeaseName, e.instanceID); err != nil {
+		e.logger.Warn("Failed to release scheduler lease", "error", err)
+		return
+	}
+	if wasLeader {
+		e.logger.Info("Released scheduler leadership", "instance_id", e.instanceID)
+	}
+}
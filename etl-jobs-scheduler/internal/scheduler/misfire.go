@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+)
+
+// Misfire policies, set per schedule
+const (
+	MisfireFireOnce = "fire_once"
+	MisfireFireAll  = "fire_all"
+	MisfireSkip     = "skip"
+)
+
+// dueFires returns the fire times of a due schedule to create runs for,
+// oldest first, and how many missed fires the misfire policy dropped. A fire
+// more than the schedule's grace period behind now is missed; fires within
+// it always run.
+func (s *Scheduler) dueFires(schedule db.Schedule, now time.Time, loc *time.Location) ([]time.Time, int, error) {
+	keep := 1
+	switch schedule.MisfirePolicy {
+	case MisfireFireAll:
+		keep = max(schedule.MaxCatchupRuns, 1)
+	case MisfireSkip:
+		keep = 0
+	}
+
+	spec := schedule.Spec()
+	first := *schedule.NextRun
+	cutoff := now.Add(-time.Duration(schedule.MisfireGraceSeconds) * time.Second)
+	missed, total, err := s.cronParser.FireTimes(spec, first, cutoff, loc, keep)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !first.After(cutoff) {
+		if first, err = s.cronParser.NextExecution(spec, cutoff, loc); err != nil {
+			return nil, 0, err
+		}
+		if first.IsZero() {
+			return missed, total - len(missed), nil
+		}
+	}
+	onTime, _, err := s.cronParser.FireTimes(spec, first, now, loc, math.MaxInt)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append(missed, onTime...), total - len(missed), nil
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+)
+
+func TestDueFires(t *testing.T) {
+	at := func(clock string) time.Time {
+		ts, err := time.Parse(time.RFC3339, "2024-05-01T"+clock+"Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		maxCatchup  int
+		graceSecs   int
+		nextRun     string
+		now         string
+		wantFires   []string
+		wantDropped int
+	}{
+		{"fire_once runs the latest missed fire", MisfireFireOnce, 0, 0, "10:00:00", "11:05:00", []string{"11:00:00"}, 6},
+		{"fire_all runs up to max_catchup_runs", MisfireFireAll, 3, 0, "10:00:00", "11:05:00", []string{"10:40:00", "10:50:00", "11:00:00"}, 4},
+		{"fire_all runs every missed fire within the limit", MisfireFireAll, 10, 0, "10:30:00", "11:05:00", []string{"10:30:00", "10:40:00", "10:50:00", "11:00:00"}, 0},
+		{"fire_all without a limit runs one", MisfireFireAll, 0, 0, "10:00:00", "11:05:00", []string{"11:00:00"}, 6},
+		{"skip drops every missed fire", MisfireSkip, 0, 0, "10:00:00", "11:05:00", nil, 7},
+		{"grace period keeps recent fires on time", MisfireSkip, 0, 600, "10:00:00", "11:05:00", []string{"11:00:00"}, 6},
+		{"fire_once with a grace period", MisfireFireOnce, 0, 600, "10:00:00", "11:05:00", []string{"10:50:00", "11:00:00"}, 5},
+		{"fire within the grace period is not missed", MisfireSkip, 0, 60, "11:00:00", "11:00:30", []string{"11:00:00"}, 0},
+		{"late fire without a grace period is missed", MisfireSkip, 0, 0, "11:00:00", "11:00:30", nil, 1},
+	}
+
+	s := &Scheduler{cronParser: cron.NewParser()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextRun := at(tt.nextRun)
+			schedule := db.Schedule{
+				Kind:                cron.KindCron,
+				CronExpression:      "*/10 * * * *",
+				NextRun:             &nextRun,
+				MisfirePolicy:       tt.policy,
+				MaxCatchupRuns:      tt.maxCatchup,
+				MisfireGraceSeconds: tt.graceSecs,
+			}
+
+			fires, dropped, err := s.dueFires(schedule, at(tt.now), time.UTC)
+			if err != nil {
+				t.Fatalf("dueFires: %v", err)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, tt.wantDropped)
+			}
+			if len(fires) != len(tt.wantFires) {
+				t.Fatalf("fires = %v, want %v", fires, tt.wantFires)
+			}
+			for i, want := range tt.wantFires {
+				if !fires[i].Equal(at(want)) {
+					t.Errorf("fire %d = %s, want %s", i, fires[i].Format(time.RFC3339), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"github.com/aquaflow/etl-jobs-scheduler/internal/logging"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/metrics"
+)
+
+// outcomeBatchSize caps the finished runs recorded per scheduling cycle
+const outcomeBatchSize = 500
+
+// recordOutcomes records the outcome of scheduled runs that finished since
+// the last cycle on their schedules, disabling schedules that keep failing
+func (s *Scheduler) recordOutcomes(stats *SchedulerStats) error {
+	outcomes, err := s.db.GetUnrecordedOutcomes(outcomeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, outcome := range outcomes {
+		logger := s.logger.With(
+			logging.KeyRunID, outcome.RunID.String(),
+			logging.KeyJobName, outcome.JobName,
+			"schedule_id", outcome.ScheduleID.String(),
+			"schedule_name", outcome.ScheduleName)
+
+		result, err := s.db.RecordRunOutcome(outcome)
+		if err != nil {
+			logger.Error("Failed to record run outcome", "error", err)
+			stats.Errors++
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		stats.OutcomesRecorded++
+		logger.Debug("Recorded run outcome",
+			"status", outcome.Status,
+			"consecutive_failures", result.ConsecutiveFailures)
+
+		if result.Disabled {
+			logger.Error("Schedule disabled after repeated failures",
+				"consecutive_failures", result.ConsecutiveFailures,
+				"auto_disable_after", *result.AutoDisableAfter,
+				"notification_id", result.NotificationID.String())
+			metrics.SchedulesDisabled.WithLabelValues(outcome.JobName).Inc()
+			stats.SchedulesDisabled++
+		}
+	}
+
+	return nil
+}
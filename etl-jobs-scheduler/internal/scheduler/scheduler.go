@@ -2,32 +2,54 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
-	"github.com/aquaflow/etl-jobs-scheduler/internal/cron"
+	"github.com/aquaflow/etl-common/cron"
+	"github.com/aquaflow/etl-common/jobschema"
 	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/logging"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/metrics"
+	"github.com/aquaflow/etl-jobs-scheduler/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Scheduler struct {
 	db         *db.Client
 	cronParser *cron.Parser
-	logger     *log.Logger
+	logger     *slog.Logger
+	elector    *Elector
+	// maxWait is how long a run may wait for its upstream jobs before it
+	// fails; zero waits forever
+	maxWait time.Duration
 }
 
 type SchedulerStats struct {
 	JobsCreated        int
 	TemplatesProcessed int
+	RunsWaiting        int
+	RunsReleased       int
+	RunsExpired        int
+	RunsSkipped        int
+	FiresBlocked       int
+	OutcomesRecorded   int
+	SchedulesDisabled  int
 	Errors             int
 	LastRunTime        time.Time
+	// MaxLag is how late the most overdue due schedule was
+	MaxLag time.Duration
 }
 
-func NewScheduler(dbClient *db.Client, logger *log.Logger) *Scheduler {
+func NewScheduler(dbClient *db.Client, logger *slog.Logger, elector *Elector, maxWait time.Duration) *Scheduler {
 	return &Scheduler{
 		db:         dbClient,
 		cronParser: cron.NewParser(),
 		logger:     logger,
+		elector:    elector,
+		maxWait:    maxWait,
 	}
 }
 
@@ -37,7 +59,34 @@ func (s *Scheduler) RunSchedulingCycle(ctx context.Context) (*SchedulerStats, er
 		LastRunTime: time.Now(),
 	}
 
-	s.logger.Printf("Starting scheduling cycle at %s", stats.LastRunTime.Format(time.RFC3339))
+	// Runs created in this cycle carry its trace context to the worker
+	ctx, span := tracing.Tracer.Start(ctx, "scheduling cycle")
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("scheduler.runs_created", stats.JobsCreated),
+			attribute.Int("scheduler.runs_released", stats.RunsReleased),
+			attribute.Int("scheduler.runs_expired", stats.RunsExpired),
+			attribute.Int("scheduler.runs_skipped", stats.RunsSkipped),
+			attribute.Int("scheduler.fires_blocked", stats.FiresBlocked),
+			attribute.Int("scheduler.schedules_disabled", stats.SchedulesDisabled),
+			attribute.Int("scheduler.errors", stats.Errors),
+		)
+		span.End()
+	}()
+
+	s.logger.Debug("Starting scheduling cycle", "cycle_time", stats.LastRunTime.Format(time.RFC3339))
+
+	// Queue waiting runs whose upstream jobs have run since the last cycle
+	if err := s.releaseWaitingRuns(stats); err != nil {
+		s.logger.Error("Failed to check waiting runs", "error", err)
+		stats.Errors++
+	}
+
+	// Record finished runs on their schedules, disabling failing ones
+	if err := s.recordOutcomes(stats); err != nil {
+		s.logger.Error("Failed to record run outcomes", "error", err)
+		stats.Errors++
+	}
 
 	// Get all due schedules
 	dueSchedules, err := s.db.GetDueSchedules(stats.LastRunTime)
@@ -47,71 +96,254 @@ func (s *Scheduler) RunSchedulingCycle(ctx context.Context) (*SchedulerStats, er
 	}
 
 	if len(dueSchedules) == 0 {
-		s.logger.Println("No due schedules found")
+		s.logger.Debug("No due schedules found")
 		return stats, nil
 	}
 
-	s.logger.Printf("Found %d due schedules", len(dueSchedules))
+	s.logger.Info("Found due schedules", "count", len(dueSchedules))
 
 	// Process each due schedule
 	for _, schedule := range dueSchedules {
 		if err := s.processSchedule(ctx, schedule, stats); err != nil {
-			s.logger.Printf("ERROR: Failed to process schedule %s: %v", schedule.ScheduleName, err)
+			s.logger.Error("Failed to process schedule", "schedule_id", schedule.ScheduleID.String(), "schedule_name", schedule.ScheduleName, "error", err)
 			stats.Errors++
 			continue
 		}
 		stats.TemplatesProcessed++
 	}
 
-	s.logger.Printf("Scheduling cycle completed: %d jobs created, %d templates processed, %d errors",
-		stats.JobsCreated, stats.TemplatesProcessed, stats.Errors)
+	s.logger.Info("Scheduling cycle completed",
+		"runs_created", stats.JobsCreated,
+		"schedules_processed", stats.TemplatesProcessed,
+		"runs_released", stats.RunsReleased,
+		"runs_expired", stats.RunsExpired,
+		"runs_waiting", stats.RunsWaiting,
+		"runs_skipped", stats.RunsSkipped,
+		"fires_blocked", stats.FiresBlocked,
+		"outcomes_recorded", stats.OutcomesRecorded,
+		"schedules_disabled", stats.SchedulesDisabled,
+		"errors", stats.Errors)
 
 	return stats, nil
 }
 
 // processSchedule handles a single due schedule
-func (s *Scheduler) processSchedule(ctx context.Context, schedule db.Schedule, stats *SchedulerStats) error {
-	s.logger.Printf("Processing schedule: %s (cron: %s)", schedule.ScheduleName, schedule.CronExpression)
+func (s *Scheduler) processSchedule(ctx context.Context, schedule db.Schedule, stats *SchedulerStats) (err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "schedule", trace.WithAttributes(
+		attribute.String("etl.schedule_id", schedule.ScheduleID.String()),
+		attribute.String("etl.schedule_name", schedule.ScheduleName),
+	))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	logger := s.logger.With("schedule_id", schedule.ScheduleID.String(), "schedule_name", schedule.ScheduleName)
+	logger.Debug("Processing schedule", "kind", schedule.Kind, "cron", schedule.CronExpression, "timezone", schedule.Timezone)
 
 	// Validate that the schedule is actually due
 	if schedule.NextRun == nil || schedule.NextRun.After(stats.LastRunTime) {
 		return fmt.Errorf("schedule %s is not due yet", schedule.ScheduleName)
 	}
 
+	if lag := stats.LastRunTime.Sub(*schedule.NextRun); lag > stats.MaxLag {
+		stats.MaxLag = lag
+	}
+
+	// Fire times are evaluated, runs named and their date placeholders
+	// resolved in the schedule's own timezone
+	loc, err := cron.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return err
+	}
+
 	// Get the job definition for this schedule
 	job, err := s.db.GetJobForSchedule(schedule.ScheduleID)
 	if err != nil {
 		return fmt.Errorf("failed to get job for schedule: %w", err)
 	}
+	logger = logger.With(logging.KeyJobName, job.JobName)
+	span.SetAttributes(attribute.String("etl.job_name", job.JobName), attribute.String("etl.job_type", job.JobType))
 
-	// Create job run from schedule
-	jobRun, err := s.db.CreateJobRun(schedule, *job, *schedule.NextRun)
+	// Fires missed while the scheduler was behind are handled by the
+	// schedule's misfire policy
+	fires, dropped, err := s.dueFires(schedule, stats.LastRunTime, loc)
 	if err != nil {
-		return fmt.Errorf("failed to create job run: %w", err)
+		return fmt.Errorf("failed to calculate due fire times: %w", err)
+	}
+	span.SetAttributes(attribute.Int("scheduler.fires", len(fires)), attribute.Int("scheduler.misfires_dropped", dropped))
+	if dropped > 0 {
+		logger.Warn("Dropped missed schedule fires", "dropped", dropped, "misfire_policy", schedule.MisfirePolicy)
+		metrics.MisfiresDropped.WithLabelValues(job.JobName).Add(float64(dropped))
 	}
 
-	s.logger.Printf("Created job run: %s (ID: %s)", jobRun.RunName, jobRun.RunID.String())
-	stats.JobsCreated++
+	// Fires landing in a blackout window of the schedule's or the job's
+	// calendars are skipped or deferred
+	var windows []db.BlackoutWindow
+	if len(fires) > 0 {
+		if windows, err = s.db.GetBlackoutWindows(schedule.ScheduleID, job.JobID); err != nil {
+			return err
+		}
+	}
+
+	// Create a job run for each fire, carrying its own scheduled time
+	for _, fire := range fires {
+		blackout, err := s.blackoutAt(windows, fire)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate blackout windows: %w", err)
+		}
+		if blackout != nil {
+			logger.Info("Fire is in a blackout window", "scheduled_for", fire.In(loc).Format(time.RFC3339),
+				"calendar", blackout.CalendarName, "window", blackout.WindowName, "action", blackout.Action,
+				"until", blackout.Until.Format(time.RFC3339))
+			stats.FiresBlocked++
+			metrics.FiresBlocked.WithLabelValues(job.JobName, blackout.Action).Inc()
+		}
+		if err := s.createRun(ctx, logger, schedule, *job, fire.In(loc), blackout, stats); err != nil {
+			// Resume from this fire next cycle rather than repeat the runs
+			// already created
+			if err := s.db.UpdateScheduleNextRun(schedule.ScheduleID, fire); err != nil {
+				logger.Error("Failed to update schedule next run", "error", err)
+			}
+			return err
+		}
+	}
 
 	// Calculate next run time
-	nextRun, err := s.cronParser.NextExecution(schedule.CronExpression, stats.LastRunTime)
+	nextRun, err := s.cronParser.NextExecution(schedule.Spec(), stats.LastRunTime, loc)
 	if err != nil {
 		return fmt.Errorf("failed to calculate next run time: %w", err)
 	}
 
-	// Update schedule with next run time
+	// Update schedule with next run time; a schedule that never fires again,
+	// such as a one-time schedule that has fired, is left without one
 	if err := s.db.UpdateScheduleNextRun(schedule.ScheduleID, nextRun); err != nil {
 		return fmt.Errorf("failed to update schedule next run: %w", err)
 	}
 
-	s.logger.Printf("Updated schedule %s next run to: %s", schedule.ScheduleName, nextRun.Format(time.RFC3339))
+	if nextRun.IsZero() {
+		logger.Info("Schedule has no further fires", "kind", schedule.Kind)
+	} else {
+		logger.Debug("Updated schedule next run", "next_run", nextRun.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// createRun creates the run of a schedule for one fire time
+func (s *Scheduler) createRun(ctx context.Context, logger *slog.Logger, schedule db.Schedule, job db.Job, scheduledFor time.Time, blackout *db.Blackout, stats *SchedulerStats) error {
+	jobRun, err := s.db.CreateJobRun(schedule, job, scheduledFor, blackout, tracing.Inject(ctx))
+	var validationErr *jobschema.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		// The run was recorded as failed; keep the schedule moving so a broken
+		// definition doesn't produce a rejected run every cycle
+		for _, fe := range validationErr.Errors {
+			logger.Error("Schedule has invalid parameter", "field", fe.Field, "reason", fe.Message)
+		}
+		stats.Errors++
+		metrics.RunsCreated.WithLabelValues(job.JobName, job.JobType, "failed").Inc()
+	case errors.Is(err, db.ErrRunExists):
+		logger.Info("Run already created by another replica", "scheduled_for", scheduledFor.Format(time.RFC3339))
+	case err != nil:
+		return fmt.Errorf("failed to create job run: %w", err)
+	case jobRun.SkipReason != nil:
+		logger.Info("Skipped job run", logging.KeyRunID, jobRun.RunID.String(), "run_name", jobRun.RunName, "reason", *jobRun.SkipReason)
+		stats.RunsSkipped++
+		metrics.RunsCreated.WithLabelValues(job.JobName, job.JobType, "skipped").Inc()
+	default:
+		logger.Info("Created job run", logging.KeyRunID, jobRun.RunID.String(), "run_name", jobRun.RunName,
+			"scheduled_for", scheduledFor.Format(time.RFC3339), "correlation_id", jobRun.CorrelationID.String())
+		stats.JobsCreated++
+		metrics.RunsCreated.WithLabelValues(job.JobName, job.JobType, jobRun.Status).Inc()
+		for _, replacedID := range jobRun.ReplacedRuns {
+			logger.Info("Cancelled replaced run", logging.KeyRunID, replacedID.String(), "replaced_by", jobRun.RunID.String())
+		}
+		if jobRun.BlockedReason != nil {
+			logger.Info("Run is waiting", logging.KeyRunID, jobRun.RunID.String(), "reason", *jobRun.BlockedReason)
+			stats.RunsWaiting++
+		}
+	}
+	return nil
+}
+
+// releaseWaitingRuns re-checks the dependencies of every waiting run, queues
+// the runs that are satisfied and refreshes the reason of the others
+func (s *Scheduler) releaseWaitingRuns(stats *SchedulerStats) error {
+	waiting, err := s.db.GetWaitingRuns()
+	if err != nil {
+		return err
+	}
+
+	for _, run := range waiting {
+		// A run deferred by a blackout waits out the window first
+		if run.DeferredUntil != nil && stats.LastRunTime.Before(*run.DeferredUntil) {
+			stats.RunsWaiting++
+			continue
+		}
+
+		reason, err := s.db.CheckDependencies(run.JobID, run.StartedAt)
+		if err != nil {
+			s.logger.Error("Failed to check dependencies", logging.KeyRunID, run.RunID.String(), "error", err)
+			stats.Errors++
+			continue
+		}
+
+		if reason != "" && s.waitedTooLong(run, stats.LastRunTime) {
+			run.BlockedReason = &reason
+			expired, err := s.db.ExpireWaitingRun(run, s.maxWait)
+			if err != nil {
+				s.logger.Error("Failed to expire waiting run", logging.KeyRunID, run.RunID.String(), "error", err)
+				stats.Errors++
+				continue
+			}
+			if expired {
+				s.logger.Warn("Run waited too long for its dependencies, failed", logging.KeyRunID, run.RunID.String(),
+					"run_name", run.RunName, "max_wait", s.maxWait.String(), "blocked_reason", reason)
+				stats.RunsExpired++
+			}
+			continue
+		}
+
+		if reason != "" {
+			stats.RunsWaiting++
+			if run.BlockedReason == nil || *run.BlockedReason != reason {
+				if err := s.db.UpdateBlockedReason(run.RunID, reason); err != nil {
+					s.logger.Error("Failed to update blocked reason", logging.KeyRunID, run.RunID.String(), "error", err)
+					stats.Errors++
+				}
+			}
+			continue
+		}
+
+		released, err := s.db.ReleaseWaitingRun(run)
+		if err != nil {
+			s.logger.Error("Failed to release run", logging.KeyRunID, run.RunID.String(), "error", err)
+			stats.Errors++
+			continue
+		}
+		if released {
+			s.logger.Info("Dependencies satisfied, queued run", logging.KeyRunID, run.RunID.String(), "run_name", run.RunName)
+			stats.RunsReleased++
+		}
+	}
 
 	return nil
 }
 
+// waitedTooLong reports whether a run has waited for its dependencies longer
+// than the maximum wait. A deferred run starts waiting when its blackout ends.
+func (s *Scheduler) waitedTooLong(run db.JobRun, now time.Time) bool {
+	if s.maxWait <= 0 {
+		return false
+	}
+	since := run.StartedAt
+	if run.DeferredUntil != nil && run.DeferredUntil.After(since) {
+		since = *run.DeferredUntil
+	}
+	return now.Sub(since) > s.maxWait
+}
+
 // Start begins the scheduler with the specified interval
 func (s *Scheduler) Start(ctx context.Context, checkInterval time.Duration) error {
-	s.logger.Printf("Starting ETL Jobs Scheduler with %v check interval", checkInterval)
+	s.logger.Info("Starting ETL Jobs Scheduler", "check_interval", checkInterval.String())
 
 	// Initial health check
 	if err := s.db.HealthCheck(ctx); err != nil {
@@ -121,51 +353,104 @@ func (s *Scheduler) Start(ctx context.Context, checkInterval time.Duration) erro
 	// Log active schedules count
 	count, err := s.db.GetActiveSchedulesCount()
 	if err != nil {
-		s.logger.Printf("WARNING: Failed to get active schedules count: %v", err)
+		s.logger.Warn("Failed to get active schedules count", "error", err)
 	} else {
-		s.logger.Printf("Monitoring %d active schedules", count)
+		s.logger.Info("Monitoring active schedules", "count", count)
 	}
 
-	ticker := time.NewTicker(checkInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(checkInterval)
+	defer timer.Stop()
 
-	// Run initial cycle
-	if _, err := s.RunSchedulingCycle(ctx); err != nil {
-		s.logger.Printf("ERROR: Initial scheduling cycle failed: %v", err)
-	}
-
-	// Main scheduling loop
+	// Main scheduling loop; only the leader runs cycles, starting with one
+	// as soon as it is elected. Cycles run every checkInterval, or sooner
+	// when a schedule fires before then, so schedules firing more often
+	// than checkInterval keep their cadence.
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Println("Scheduler shutdown requested")
+			s.logger.Info("Scheduler shutdown requested")
 			return ctx.Err()
-		case <-ticker.C:
+		case <-s.elector.Elected():
+			s.runCycle(ctx)
+		case <-timer.C:
+			if !s.elector.IsLeader() {
+				s.logger.Debug("Standing by, another replica is the leader")
+				timer.Reset(checkInterval)
+				continue
+			}
+
 			// Run health check periodically
 			if err := s.db.HealthCheck(ctx); err != nil {
-				s.logger.Printf("WARNING: Database health check failed: %v", err)
+				s.logger.Warn("Database health check failed", "error", err)
+				timer.Reset(checkInterval)
 				continue
 			}
 
-			// Run scheduling cycle
-			if _, err := s.RunSchedulingCycle(ctx); err != nil {
-				s.logger.Printf("ERROR: Scheduling cycle failed: %v", err)
+			s.runCycle(ctx)
+		}
+
+		wait := s.untilNextCycle(ctx, checkInterval)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
 			}
 		}
+		timer.Reset(wait)
 	}
 }
 
-// ValidateTemplateSchedule validates a cron expression for a template
-func (s *Scheduler) ValidateTemplateSchedule(cronExpr string) error {
-	return s.cronParser.ValidateCronExpression(cronExpr)
+// untilNextCycle returns how long to wait for the next cycle: checkInterval,
+// or less if a schedule fires sooner
+func (s *Scheduler) untilNextCycle(ctx context.Context, checkInterval time.Duration) time.Duration {
+	next, err := s.db.GetNextFireTime(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("Failed to get next fire time", "error", err)
+		}
+		return checkInterval
+	}
+	if next == nil {
+		return checkInterval
+	}
+	return max(min(time.Until(*next), checkInterval), 0)
 }
 
-// GetHumanReadableSchedule returns a human-readable format of a cron expression
-func (s *Scheduler) GetHumanReadableSchedule(cronExpr string) string {
-	return s.cronParser.GetHumanReadableSchedule(cronExpr)
+// runCycle runs one scheduling cycle and exports its stats
+func (s *Scheduler) runCycle(ctx context.Context) {
+	stats, err := s.RunSchedulingCycle(ctx)
+	observeCycle(stats)
+	if err != nil {
+		s.logger.Error("Scheduling cycle failed", "error", err)
+	}
+}
+
+// observeCycle exports the stats of a finished scheduling cycle
+func observeCycle(stats *SchedulerStats) {
+	metrics.CycleDuration.Observe(time.Since(stats.LastRunTime).Seconds())
+	metrics.LastCycle.Set(float64(stats.LastRunTime.Unix()))
+	metrics.Lag.Set(stats.MaxLag.Seconds())
+	metrics.RunsReleased.Add(float64(stats.RunsReleased))
+	metrics.RunsExpired.Add(float64(stats.RunsExpired))
+	metrics.RunsWaiting.Set(float64(stats.RunsWaiting))
+	metrics.Errors.Add(float64(stats.Errors))
+}
+
+// ValidateTemplateSchedule validates a schedule spec for a template
+func (s *Scheduler) ValidateTemplateSchedule(spec cron.Spec) error {
+	return s.cronParser.ValidateSpec(spec)
+}
+
+// GetHumanReadableSchedule returns a human-readable format of a schedule spec
+func (s *Scheduler) GetHumanReadableSchedule(spec cron.Spec) string {
+	return s.cronParser.GetHumanReadableSchedule(spec)
 }
 
-// GetNextRunTime calculates when a template will next run
-func (s *Scheduler) GetNextRunTime(cronExpr string) (time.Time, error) {
-	return s.cronParser.GetNextRun(cronExpr)
+// GetNextRunTime calculates when a template will next run in the given timezone
+func (s *Scheduler) GetNextRunTime(spec cron.Spec, timezone string) (time.Time, error) {
+	loc, err := cron.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.cronParser.GetNextRun(spec, loc)
 }
\ No newline at end of file
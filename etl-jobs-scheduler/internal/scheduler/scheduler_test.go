@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquaflow/etl-jobs-scheduler/internal/db"
+)
+
+func TestWaitedTooLong(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(h int) *time.Time {
+		ts := now.Add(-time.Duration(h) * time.Hour)
+		return &ts
+	}
+
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		run     db.JobRun
+		want    bool
+	}{
+		{"within the maximum wait", 24 * time.Hour, db.JobRun{StartedAt: *hoursAgo(23)}, false},
+		{"past the maximum wait", 24 * time.Hour, db.JobRun{StartedAt: *hoursAgo(25)}, true},
+		{"no maximum wait", 0, db.JobRun{StartedAt: *hoursAgo(1000)}, false},
+		{"deferred run counts from the end of its blackout", 24 * time.Hour, db.JobRun{StartedAt: *hoursAgo(48), DeferredUntil: hoursAgo(2)}, false},
+		{"deferred run past the maximum wait", 24 * time.Hour, db.JobRun{StartedAt: *hoursAgo(48), DeferredUntil: hoursAgo(30)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scheduler{maxWait: tt.maxWait}
+			if got := s.waitedTooLong(tt.run, now); got != tt.want {
+				t.Errorf("waitedTooLong = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+"""Example exec job script.
+
+Reads its parameters from $ETL_PARAMS_FILE and writes one data point per
+line to stdout as NDJSON. Anything else printed is logged with the run.
+
+Run it with parameters such as:
+  {"script": "example_ndjson.py", "interpreter": "python3", "output": "ndjson",
+   "params": {"series_id": 1, "points": 24}}
+"""
+import json
+import os
+from datetime import datetime, timedelta, timezone
+
+with open(os.environ["ETL_PARAMS_FILE"]) as f:
+    params = json.load(f)["parameters"]
+
+series_id = int(params.get("series_id", 1))
+points = int(params.get("points", 24))
+start = datetime.now(timezone.utc).replace(minute=0, second=0, microsecond=0) - timedelta(hours=points)
+
+print(f"Generating {points} points for series {series_id}")
+for i in range(points):
+    print(json.dumps({
+        "timestamp": (start + timedelta(hours=i)).isoformat(),
+        "series_id": series_id,
+        "value": round(100 + i * 0.5, 2),
+        "unit": "cfs",
+    }))
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/jobs"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// readinessTimeout bounds the database ping of a readiness probe
+const readinessTimeout = 2 * time.Second
+
+// defaultAdminAddr listens on all interfaces so orchestrators can probe the
+// worker and Prometheus can scrape it; admin actions need the token
+const defaultAdminAddr = ":8081"
+
+// adminServer serves the worker's health probes and admin endpoints
+type adminServer struct {
+	database  *sql.DB
+	processor *jobs.Processor
+	slots     []workerSlot
+	started   time.Time
+	// token is the bearer token required to change the worker's state and
+	// to read profiles; without one, those requests are refused
+	token string
+	// pprofEnabled exposes /debug/pprof/; it can be switched on at runtime
+	// so a slow load can be profiled without restarting the worker
+	pprofEnabled atomic.Bool
+}
+
+func newAdminServer(database *sql.DB, processor *jobs.Processor, slots []workerSlot, pprofEnabled bool, token string) *adminServer {
+	s := &adminServer{
+		database:  database,
+		processor: processor,
+		slots:     slots,
+		started:   time.Now(),
+		token:     token,
+	}
+	s.pprofEnabled.Store(pprofEnabled)
+	return s
+}
+
+func (s *adminServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleLiveness)
+	mux.HandleFunc("/readyz", s.handleReadiness)
+	mux.HandleFunc("/runs", s.handleRuns)
+	mux.HandleFunc("/job-types", s.handleJobTypes)
+	mux.HandleFunc("/drain", s.handleDrain)
+	mux.HandleFunc("/pprof", s.handlePprofToggle)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.Handle("/debug/pprof/", s.requirePprof(http.HandlerFunc(pprof.Index)))
+	mux.Handle("/debug/pprof/cmdline", s.requirePprof(http.HandlerFunc(pprof.Cmdline)))
+	mux.Handle("/debug/pprof/profile", s.requirePprof(http.HandlerFunc(pprof.Profile)))
+	mux.Handle("/debug/pprof/symbol", s.requirePprof(http.HandlerFunc(pprof.Symbol)))
+	mux.Handle("/debug/pprof/trace", s.requirePprof(http.HandlerFunc(pprof.Trace)))
+	return s.requireToken(mux)
+}
+
+// requireToken checks the bearer token of requests that change the worker's
+// state, such as draining it or enabling profiling, and of profile reads.
+// Probes, run listings and metrics stay open.
+func (s *adminServer) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protected := (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			strings.HasPrefix(r.URL.Path, "/debug/pprof/")
+		if !protected {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.token == "" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin token is not configured"})
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid admin token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve runs the admin server until ctx is cancelled
+func (s *adminServer) serve(ctx context.Context, addr string) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Admin server listening", "addr", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Error("Admin server failed", "error", err)
+	}
+}
+
+// handleLiveness reports that the process is up
+func (s *adminServer) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"uptime_seconds": time.Since(s.started).Seconds(),
+	})
+}
+
+// handleReadiness reports whether the worker can take runs: the database
+// is reachable and the worker is not draining
+func (s *adminServer) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "claims": "ok"}
+	status := http.StatusOK
+	if err := s.database.PingContext(ctx); err != nil {
+		checks["database"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+	if s.processor.Draining() {
+		checks["claims"] = "draining"
+		status = http.StatusServiceUnavailable
+	}
+
+	result := "ready"
+	if status != http.StatusOK {
+		result = "not ready"
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"status": result,
+		"checks": checks,
+	})
+}
+
+// handleRuns lists the runs the worker is executing
+func (s *adminServer) handleRuns(w http.ResponseWriter, r *http.Request) {
+	runs := s.processor.ActiveRuns()
+	slots := make([]string, len(s.slots))
+	for i, slot := range s.slots {
+		slots[i] = slot.String()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"runs":     runs,
+		"count":    len(runs),
+		"slots":    slots,
+		"draining": s.processor.Draining(),
+	})
+}
+
+// handleJobTypes lists the job types this worker can execute
+func (s *adminServer) handleJobTypes(w http.ResponseWriter, r *http.Request) {
+	types := jobs.RegisteredJobTypes()
+	result := make([]map[string]interface{}, 0, len(types))
+	for _, jobType := range types {
+		result = append(result, map[string]interface{}{
+			"name":                    jobType.Name,
+			"description":             jobType.Description,
+			"default_timeout_seconds": jobType.DefaultTimeout.Seconds(),
+			"shard_modes":             jobType.ShardModes,
+			"parameter_schema":        jobType.ParameterSchema,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job_types": result})
+}
+
+// handleDrain stops new claims on POST and resumes them on DELETE. Runs in
+// progress are not interrupted. Both need the admin token.
+func (s *adminServer) handleDrain(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !s.processor.Draining() {
+			slog.Info("Draining: no new runs will be claimed")
+		}
+		s.processor.Drain()
+	case http.MethodDelete:
+		if s.processor.Draining() {
+			slog.Info("Drain cancelled: claiming runs again")
+		}
+		s.processor.Resume()
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"draining":    s.processor.Draining(),
+		"active_runs": len(s.processor.ActiveRuns()),
+	})
+}
+
+// handlePprofToggle enables the profiling endpoints on POST and disables
+// them on DELETE
+func (s *adminServer) handlePprofToggle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.pprofEnabled.Store(true)
+		slog.Info("Profiling endpoints enabled")
+	case http.MethodDelete:
+		s.pprofEnabled.Store(false)
+		slog.Info("Profiling endpoints disabled")
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pprof_enabled": s.pprofEnabled.Load()})
+}
+
+func (s *adminServer) requirePprof(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.pprofEnabled.Load() {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "profiling is disabled"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		auth   string
+		want   int
+	}{
+		{"probe is open", "secret", http.MethodGet, "/readyz", "", http.StatusNoContent},
+		{"drain status is open", "secret", http.MethodGet, "/drain", "", http.StatusNoContent},
+		{"drain needs token", "secret", http.MethodPost, "/drain", "", http.StatusUnauthorized},
+		{"drain with wrong token", "secret", http.MethodPost, "/drain", "Bearer nope", http.StatusUnauthorized},
+		{"drain with token", "secret", http.MethodPost, "/drain", "Bearer secret", http.StatusNoContent},
+		{"resume needs token", "secret", http.MethodDelete, "/drain", "", http.StatusUnauthorized},
+		{"pprof toggle needs token", "secret", http.MethodPost, "/pprof", "", http.StatusUnauthorized},
+		{"profiles need token", "secret", http.MethodGet, "/debug/pprof/cmdline", "", http.StatusUnauthorized},
+		{"profiles with token", "secret", http.MethodGet, "/debug/pprof/cmdline", "Bearer secret", http.StatusNoContent},
+		{"no token configured", "", http.MethodPost, "/drain", "Bearer ", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &adminServer{token: tt.token}
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			s.requireToken(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aquaflow/etl-workers/internal/jobs"
+)
+
+// loadExecConfig reads the exec job type configuration from the environment
+func loadExecConfig() (jobs.ExecConfig, error) {
+	config := jobs.ExecConfig{
+		ScriptsDir:      "/opt/etl-scripts",
+		Interpreters:    []string{"python3", "Rscript"},
+		DefaultMemoryMB: 512,
+	}
+
+	if v := os.Getenv("ETL_EXEC_SCRIPTS_DIR"); v != "" {
+		config.ScriptsDir = v
+	}
+	if v, ok := os.LookupEnv("ETL_EXEC_INTERPRETERS"); ok {
+		config.Interpreters = nil
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Interpreters = append(config.Interpreters, name)
+			}
+		}
+	}
+	if v := os.Getenv("ETL_EXEC_DEFAULT_MEMORY_MB"); v != "" {
+		memoryMB, err := strconv.Atoi(v)
+		if err != nil || memoryMB < 0 {
+			return config, fmt.Errorf("invalid ETL_EXEC_DEFAULT_MEMORY_MB %q", v)
+		}
+		config.DefaultMemoryMB = memoryMB
+	}
+
+	var err error
+	if config.UID, err = parseID("ETL_EXEC_UID"); err != nil {
+		return config, err
+	}
+	if config.GID, err = parseID("ETL_EXEC_GID"); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+// parseID reads an optional user or group ID
+func parseID(name string) (*uint32, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q", name, v)
+	}
+	id32 := uint32(id)
+	return &id32, nil
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/aquaflow/etl-workers/internal/db"
 	"github.com/aquaflow/etl-workers/internal/jobs"
 	"github.com/aquaflow/etl-workers/internal/logger"
+	"github.com/aquaflow/etl-workers/internal/logging"
+	"github.com/aquaflow/etl-workers/internal/metrics"
+	"github.com/aquaflow/etl-workers/internal/tracing"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
@@ -24,13 +30,13 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 	maxBackoff := 30 * time.Second
 	
 	for i := 0; i <= maxRetries; i++ {
-		log.Printf("Attempting database connection (attempt %d/%d)...", i+1, maxRetries+1)
+		slog.Info("Attempting database connection", "attempt", i+1, "max_attempts", maxRetries+1)
 		
 		database, err = sql.Open("postgres", dbURL)
 		if err != nil {
-			log.Printf("Failed to open database connection: %v", err)
+			slog.Error("Failed to open database connection", "error", err)
 			if i < maxRetries {
-				log.Printf("Retrying in %v...", backoff)
+				slog.Info("Retrying database connection", "backoff", backoff.String())
 				time.Sleep(backoff)
 				backoff = backoff * 2
 				if backoff > maxBackoff {
@@ -47,10 +53,10 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 		cancel()
 		
 		if err != nil {
-			log.Printf("Failed to ping database: %v", err)
+			slog.Error("Failed to ping database", "error", err)
 			database.Close()
 			if i < maxRetries {
-				log.Printf("Retrying in %v...", backoff)
+				slog.Info("Retrying database connection", "backoff", backoff.String())
 				time.Sleep(backoff)
 				backoff = backoff * 2
 				if backoff > maxBackoff {
@@ -66,7 +72,7 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 		database.SetMaxIdleConns(5)
 		database.SetConnMaxLifetime(time.Hour)
 		
-		log.Println("Successfully connected to database")
+		slog.Info("Successfully connected to database")
 		return database, nil
 	}
 	
@@ -74,29 +80,97 @@ func connectWithRetry(dbURL string, maxRetries int) (*sql.DB, error) {
 }
 
 func main() {
-	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile)
+	if _, err := logging.Init("etl-worker"); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
 	
 	// Database connection
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
+		logging.Fatal("DATABASE_URL environment variable is required")
 	}
 
 	// Connect with retry logic
 	database, err := connectWithRetry(dbURL, 10)
 	if err != nil {
-		log.Fatal("Failed to establish database connection:", err)
+		logging.Fatal("Failed to establish database connection", "error", err)
 	}
 	defer database.Close()
 
+	// Tracing; OTEL_TRACES_EXPORTER selects the exporter
+	shutdownTracing, err := tracing.Init(context.Background(), "etl-worker")
+	if err != nil {
+		logging.Fatal("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
 	// Initialize components
 	dbClient := db.NewClient(database)
 	etlLogger := logger.NewETLLogger(database)
+	defer etlLogger.Close()
+	if levelName := os.Getenv("ETL_LOG_LEVEL"); levelName != "" {
+		level, ok := logger.ParseLevel(levelName)
+		if !ok {
+			logging.Fatal("Invalid ETL_LOG_LEVEL", "value", levelName)
+		}
+		etlLogger.SetMinLevel(level)
+	}
 	
+	// Publish parameter schemas for the job types this worker handles
+	if err := jobs.PublishJobTypes(dbClient); err != nil {
+		slog.Warn("Failed to publish job types", "error", err)
+	}
+
+	// Scripts run by the exec job type
+	execConfig, err := loadExecConfig()
+	if err != nil {
+		logging.Fatal("Invalid exec configuration", "error", err)
+	}
+	jobs.ConfigureExec(execConfig)
+
 	// Create job processor
 	processor := jobs.NewProcessor(dbClient, etlLogger)
 
-	// Setup graceful shutdown
+	// Queue depth and active runs are read on every scrape of /metrics
+	prometheus.MustRegister(
+		metrics.NewQueueCollector(dbClient),
+		metrics.NewActiveRunsGauge(func() int { return len(processor.ActiveRuns()) }),
+	)
+
+	// Slots run claims concurrently; some may be reserved for high-priority runs
+	slotCount := 1
+	if v := os.Getenv("ETL_WORKER_SLOTS"); v != "" {
+		if slotCount, err = strconv.Atoi(v); err != nil {
+			logging.Fatal("Invalid ETL_WORKER_SLOTS", "value", v)
+		}
+	}
+	aging := 10 * time.Minute
+	if v := os.Getenv("ETL_PRIORITY_AGING"); v != "" {
+		if aging, err = time.ParseDuration(v); err != nil || aging < 0 {
+			logging.Fatal("Invalid ETL_PRIORITY_AGING", "value", v)
+		}
+	}
+	slots, err := parseSlots(slotCount, os.Getenv("ETL_RESERVED_SLOTS"), aging)
+	if err != nil {
+		logging.Fatal("Invalid worker slots", "error", err)
+	}
+
+	// Setup graceful shutdown: a signal stops the slots from claiming runs.
+	// Runs already claimed have ETL_WORKER_SHUTDOWN_GRACE to finish before
+	// they are stopped and requeued; streaming runs requeue at once.
+	shutdownGrace := 30 * time.Second
+	if v := os.Getenv("ETL_WORKER_SHUTDOWN_GRACE"); v != "" {
+		if shutdownGrace, err = time.ParseDuration(v); err != nil || shutdownGrace < 0 {
+			logging.Fatal("Invalid ETL_WORKER_SHUTDOWN_GRACE", "value", v)
+		}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -105,12 +179,37 @@ func main() {
 
 	go func() {
 		<-sigChan
-		log.Println("Shutdown signal received, stopping worker...")
+		slog.Info("Shutdown signal received, stopping worker")
+		processor.Drain()
 		cancel()
 	}()
 
-	log.Println("ETL Worker started successfully")
-	log.Printf("Worker configuration: PollInterval=5s, MaxConnections=%d", 10)
+	// Admin server for probes, stays up on shutdown until in-flight runs
+	// have finished or been requeued.
+	// It listens on :8081 unless ETL_WORKER_ADMIN_ADDR says otherwise;
+	// draining and profiling need ETL_WORKER_ADMIN_TOKEN as a bearer token.
+	adminCtx, stopAdmin := context.WithCancel(context.Background())
+	defer stopAdmin()
+	if addr, ok := os.LookupEnv("ETL_WORKER_ADMIN_ADDR"); !ok || addr != "" {
+		if !ok {
+			addr = defaultAdminAddr
+		}
+		pprofEnabled := false
+		if v := os.Getenv("ETL_WORKER_PPROF"); v != "" {
+			if pprofEnabled, err = strconv.ParseBool(v); err != nil {
+				logging.Fatal("Invalid ETL_WORKER_PPROF", "value", v)
+			}
+		}
+		admin := newAdminServer(database, processor, slots, pprofEnabled, os.Getenv("ETL_WORKER_ADMIN_TOKEN"))
+		go admin.serve(adminCtx, addr)
+	}
+
+	slog.Info("ETL Worker started successfully",
+		"poll_interval", pollInterval.String(), "max_connections", 10,
+		"slots", len(slots), "priority_aging", aging.String())
+	for _, slot := range slots {
+		slog.Info("Starting slot", "slot", slot.String())
+	}
 
 	// Health check goroutine
 	go func() {
@@ -124,43 +223,39 @@ func main() {
 			case <-healthTicker.C:
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 				if err := database.PingContext(ctx); err != nil {
-					log.Printf("WARNING: Database health check failed: %v", err)
+					slog.Warn("Database health check failed", "error", err)
 				}
 				cancel()
 			}
 		}
 	}()
 
-	// Main processing loop
-	ticker := time.NewTicker(5 * time.Second) // Poll every 5 seconds
-	defer ticker.Stop()
-
-	consecutiveErrors := 0
-	maxConsecutiveErrors := 5
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Shutdown complete")
-			return
-		case <-ticker.C:
-			if err := processor.ProcessNextJob(ctx); err != nil {
-				if err != jobs.ErrNoJobsAvailable {
-					consecutiveErrors++
-					log.Printf("Error processing job (consecutive errors: %d): %v", consecutiveErrors, err)
-					
-					if consecutiveErrors >= maxConsecutiveErrors {
-						log.Printf("CRITICAL: Too many consecutive errors (%d), backing off for 30 seconds", consecutiveErrors)
-						time.Sleep(30 * time.Second)
-						consecutiveErrors = 0
-					}
-				}
-			} else {
-				// Reset error counter on successful processing
-				if consecutiveErrors > 0 {
-					consecutiveErrors = 0
-				}
-			}
-		}
+	// Main processing loop: one goroutine per slot. On shutdown the worker
+	// waits for in-flight runs up to the grace period, then stops the rest,
+	// which requeue, and waits for them to do so.
+	var wg sync.WaitGroup
+	for _, slot := range slots {
+		wg.Add(1)
+		go func(slot workerSlot) {
+			defer wg.Done()
+			runSlot(ctx, processor, slot)
+		}(slot)
 	}
-}
\ No newline at end of file
+
+	<-ctx.Done()
+	slotsDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(slotsDone)
+	}()
+	select {
+	case <-slotsDone:
+	case <-time.After(shutdownGrace):
+		slog.Warn("Shutdown grace period over, requeueing in-flight runs",
+			"grace", shutdownGrace.String(), "runs", len(processor.ActiveRuns()))
+		processor.StopRuns()
+		<-slotsDone
+	}
+	stopAdmin()
+	slog.Info("Shutdown complete")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/jobs"
+)
+
+const (
+	pollInterval         = 5 * time.Second
+	maxConsecutiveErrors = 5
+)
+
+// workerSlot processes one run at a time. Reserved slots only claim runs
+// of at least their minimum priority.
+type workerSlot struct {
+	id   int
+	opts db.ClaimOptions
+}
+
+func (s workerSlot) String() string {
+	if s.opts.MinPriority > 0 {
+		return fmt.Sprintf("slot %d (priority >= %d)", s.id, s.opts.MinPriority)
+	}
+	return fmt.Sprintf("slot %d", s.id)
+}
+
+// parseSlots builds the worker's slots. reserved is a comma-separated list of
+// minPriority:count pairs, e.g. "8:1" reserves one slot for runs of priority
+// 8 and above. At least one slot must stay unreserved.
+func parseSlots(total int, reserved string, aging time.Duration) ([]workerSlot, error) {
+	if total < 1 {
+		return nil, fmt.Errorf("worker needs at least one slot, got %d", total)
+	}
+
+	var slots []workerSlot
+	for _, entry := range strings.Split(reserved, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		priorityStr, countStr, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid reserved slot %q, expected minPriority:count", entry)
+		}
+		priority, err := strconv.Atoi(strings.TrimSpace(priorityStr))
+		if err != nil || priority < 1 || priority > db.MaxPriority {
+			return nil, fmt.Errorf("invalid reserved slot priority %q", priorityStr)
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countStr))
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("invalid reserved slot count %q", countStr)
+		}
+		for i := 0; i < count; i++ {
+			slots = append(slots, workerSlot{opts: db.ClaimOptions{MinPriority: priority, AgingInterval: aging}})
+		}
+	}
+
+	if len(slots) >= total {
+		return nil, fmt.Errorf("%d reserved slots leave none of the %d slots for other runs", len(slots), total)
+	}
+	for len(slots) < total {
+		slots = append(slots, workerSlot{opts: db.ClaimOptions{AgingInterval: aging}})
+	}
+	for i := range slots {
+		slots[i].id = i + 1
+	}
+	return slots, nil
+}
+
+// runSlot polls for runs the slot may claim until ctx is cancelled
+func runSlot(ctx context.Context, processor *jobs.Processor, slot workerSlot) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	consecutiveErrors := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := processor.ProcessNextJob(ctx, slot.opts); err != nil {
+				if err != jobs.ErrNoJobsAvailable {
+					consecutiveErrors++
+					slog.Error("Error processing job", "slot", slot.String(), "consecutive_errors", consecutiveErrors, "error", err)
+
+					if consecutiveErrors >= maxConsecutiveErrors {
+						slog.Error("Too many consecutive errors, backing off for 30 seconds", "slot", slot.String(), "consecutive_errors", consecutiveErrors)
+						time.Sleep(30 * time.Second)
+						consecutiveErrors = 0
+					}
+				}
+			} else {
+				// Reset error counter on successful processing
+				consecutiveErrors = 0
+			}
+		}
+	}
+}
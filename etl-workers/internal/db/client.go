@@ -5,10 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Client struct {
@@ -27,13 +28,46 @@ type ETLJob struct {
 	StartedAt        time.Time              `json:"started_at"`
 	CompletedAt      *time.Time             `json:"completed_at"`
 	ErrorMessage     *string                `json:"error_message"`
+	DryRun           bool                   `json:"dry_run"`
+	LogLevel         string                 `json:"log_level,omitempty"`
+	CorrelationID    uuid.UUID              `json:"correlation_id"`
+	Priority         int                    `json:"priority"`
+	// ShardBy is set on a backfill parent, which is split into child runs
+	// instead of being executed
+	ShardBy     string     `json:"shard_by,omitempty"`
+	ParentRunID *uuid.UUID `json:"parent_run_id,omitempty"`
+	// TraceContext is the trace of the request or cycle that created the run
+	TraceContext []byte `json:"-"`
 }
 
+// ClaimOptions control which run GetNextPendingJob claims
+type ClaimOptions struct {
+	// MinPriority restricts the claim to runs whose own priority is at least
+	// this; zero takes any run
+	MinPriority int
+	// AgingInterval raises a queued run's effective priority by one for every
+	// interval it has waited; zero disables aging
+	AgingInterval time.Duration
+}
+
+// MaxPriority is the highest run priority
+const MaxPriority = 10
+
+// concurrencyLimit is the number of runs of job j that may run at once, or
+// NULL for no limit. The queue overlap policy runs one at a time.
+const concurrencyLimit = `CASE WHEN j.overlap_policy = 'queue' THEN 1 ELSE j.max_concurrent_runs END`
+
+// StaleHeartbeatAfter is how long a streaming run may go without a heartbeat
+// before another worker reclaims it
+const StaleHeartbeatAfter = 5 * time.Minute
+
 func NewClient(db *sql.DB) *Client {
 	return &Client{db: db}
 }
 
-func (c *Client) GetNextPendingJob() (*ETLJob, error) {
+// GetNextPendingJob claims the queued run with the highest effective
+// priority, oldest first among equals
+func (c *Client) GetNextPendingJob(opts ClaimOptions) (*ETLJob, error) {
 	tx, err := c.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
@@ -43,24 +77,44 @@ func (c *Client) GetNextPendingJob() (*ETLJob, error) {
 	var job ETLJob
 	var paramsJSON []byte
 
-	// Lock the job run for processing (check both old and new tables for backward compatibility)
+	// Lock the job run for processing (check both old and new tables for backward compatibility).
+	// Streaming runs whose heartbeat has gone stale are reclaimed and resume from their checkpoint.
+	// Runs of a job already at its concurrency limit are passed over; the job row is locked too
+	// so two workers can't both take the last free slot of a job.
 	query := `
 		SELECT r.run_id as batch_id, r.run_name as job_name, j.job_type, 'scheduled' as load_type, 
 			   r.status, COALESCE(r.runtime_parameters, j.parameters) as parameters,
-			   r.records_processed, r.records_failed, r.started_at
+			   r.records_processed, r.records_failed, r.started_at, r.dry_run,
+			   COALESCE(r.log_level, j.log_level, '') as log_level, r.correlation_id,
+			   r.priority, COALESCE(r.shard_by, '') as shard_by, r.parent_run_id, r.trace_context,
+			   j.job_id, ` + concurrencyLimit + ` as concurrency_limit
 		FROM aquaflow.etl_job_runs r
 		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
-		WHERE r.status = 'queued'
+		WHERE (r.status = 'queued'
+		       OR (r.status = 'running' AND r.heartbeat_at < NOW() - $1 * INTERVAL '1 second'))
 		  AND j.is_active = true
-		ORDER BY r.started_at ASC
+		  AND r.priority >= $2
+		  AND (` + concurrencyLimit + ` IS NULL
+		       OR (SELECT COUNT(*) FROM aquaflow.etl_job_runs o
+		           WHERE o.job_id = r.job_id AND o.status = 'running' AND o.run_id <> r.run_id
+		             AND o.shard_by IS NULL) < ` + concurrencyLimit + `)
+		ORDER BY CASE WHEN $3::float8 > 0
+		              THEN LEAST($4::int, r.priority + FLOOR(GREATEST(EXTRACT(EPOCH FROM NOW() - r.started_at), 0) / $3::float8))
+		              ELSE r.priority END DESC,
+		         r.started_at ASC
 		LIMIT 1
-		FOR UPDATE SKIP LOCKED
+		FOR UPDATE OF r, j SKIP LOCKED
 	`
 
-	err = tx.QueryRow(query).Scan(
+	var jobID uuid.UUID
+	var limit sql.NullInt64
+	err = tx.QueryRow(query, int(StaleHeartbeatAfter.Seconds()), opts.MinPriority,
+		opts.AgingInterval.Seconds(), MaxPriority).Scan(
 		&job.BatchID, &job.JobName, &job.JobType, &job.LoadType,
 		&job.Status, &paramsJSON, &job.RecordsProcessed,
-		&job.RecordsFailed, &job.StartedAt,
+		&job.RecordsFailed, &job.StartedAt, &job.DryRun,
+		&job.LogLevel, &job.CorrelationID,
+		&job.Priority, &job.ShardBy, &job.ParentRunID, &job.TraceContext, &jobID, &limit,
 	)
 
 	if err == sql.ErrNoRows {
@@ -70,6 +124,22 @@ func (c *Client) GetNextPendingJob() (*ETLJob, error) {
 		return nil, err
 	}
 
+	// The count above used the statement's snapshot; recount now that the job
+	// row is locked, in case a run of the job started just before
+	if limit.Valid {
+		var running int64
+		countQuery := `
+			SELECT COUNT(*) FROM aquaflow.etl_job_runs
+			WHERE job_id = $1 AND status = 'running' AND run_id <> $2 AND shard_by IS NULL
+		`
+		if err := tx.QueryRow(countQuery, jobID, job.BatchID).Scan(&running); err != nil {
+			return nil, err
+		}
+		if running >= limit.Int64 {
+			return nil, nil
+		}
+	}
+
 	// Parse parameters
 	if len(paramsJSON) > 0 {
 		if err := json.Unmarshal(paramsJSON, &job.Parameters); err != nil {
@@ -80,7 +150,8 @@ func (c *Client) GetNextPendingJob() (*ETLJob, error) {
 	// Update status to running
 	updateQuery := `
 		UPDATE aquaflow.etl_job_runs 
-		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		SET status = 'running', started_at = NOW(), updated_at = NOW(),
+		    heartbeat_at = CASE WHEN heartbeat_at IS NOT NULL THEN NOW() END
 		WHERE run_id = $1
 	`
 	if _, err := tx.Exec(updateQuery, job.BatchID); err != nil {
@@ -94,6 +165,9 @@ func (c *Client) GetNextPendingJob() (*ETLJob, error) {
 	return &job, nil
 }
 
+// UpdateJobStatus sets a run's status and counts. A final status sets
+// completed_at in the same statement, so a finished run is never seen
+// without it.
 func (c *Client) UpdateJobStatus(batchID uuid.UUID, status string, recordsProcessed, recordsFailed int, errorMsg *string) error {
 	// Try to update job run first (new architecture)
 	query := `
@@ -102,6 +176,7 @@ func (c *Client) UpdateJobStatus(batchID uuid.UUID, status string, recordsProces
 			records_processed = $3,
 			records_failed = $4,
 			error_message = $5,
+			completed_at = CASE WHEN $6 THEN NOW() ELSE completed_at END,
 			updated_at = NOW()
 		WHERE run_id = $1
 	`
@@ -114,7 +189,9 @@ func (c *Client) UpdateJobStatus(batchID uuid.UUID, status string, recordsProces
 		errorParam = nil
 	}
 	
-	result, err := c.db.Exec(query, batchID, status, recordsProcessed, recordsFailed, errorParam)
+	final := status == "completed" || status == "failed" || status == "completed_with_errors"
+	
+	result, err := c.db.Exec(query, batchID, status, recordsProcessed, recordsFailed, errorParam, final)
 	if err != nil {
 		return err
 	}
@@ -132,53 +209,42 @@ func (c *Client) UpdateJobStatus(batchID uuid.UUID, status string, recordsProces
 			SET status = $2, 
 				records_processed = $3,
 				records_failed = $4,
-				error_message = $5
+				error_message = $5,
+				completed_at = CASE WHEN $6 THEN NOW() ELSE completed_at END
 			WHERE batch_id = $1
 		`
-		_, err = c.db.Exec(oldQuery, batchID, status, recordsProcessed, recordsFailed, errorParam)
-		if err != nil {
-			return err
-		}
-	}
-	
-	// Update completed_at separately for terminal statuses
-	if status == "completed" || status == "failed" || status == "completed_with_errors" {
-		// Try new table first
-		completedQuery := `UPDATE aquaflow.etl_job_runs SET completed_at = NOW() WHERE run_id = $1`
-		result, err = c.db.Exec(completedQuery, batchID)
-		if err != nil {
-			return err
-		}
-		
-		// If no rows affected, try old table
-		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
-			oldCompletedQuery := `UPDATE aquaflow.etl_jobs SET completed_at = NOW() WHERE batch_id = $1`
-			_, err = c.db.Exec(oldCompletedQuery, batchID)
-		}
+		_, err = c.db.Exec(oldQuery, batchID, status, recordsProcessed, recordsFailed, errorParam, final)
 	}
 	
 	return err
 }
 
-func (c *Client) InsertNumericValues(values []NumericValue) error {
+// InsertNumericValues inserts a batch of values and returns the ones the
+// database refused. The whole batch is tried in one transaction first; if any
+// row fails, the batch is retried row by row behind savepoints so a single
+// bad record doesn't discard the rest of the page. The error is only set when
+// the batch could not be written at all.
+func (c *Client) InsertNumericValues(values []NumericValue) ([]Rejection, error) {
 	if len(values) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	// Prepare bulk insert with ON CONFLICT to handle duplicates
-	query := `
-		INSERT INTO aquaflow.numeric_values (series_id, time_point, value)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (series_id, time_point, version) DO NOTHING
-	`
+	if err := c.insertNumericBatch(values); err == nil {
+		return nil, nil
+	}
+
+	return c.insertNumericIsolated(values)
+}
 
+// insertNumericBatch inserts all values in a single transaction
+func (c *Client) insertNumericBatch(values []NumericValue) error {
 	tx, err := c.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(query)
+	stmt, err := tx.Prepare(insertNumericValueQuery)
 	if err != nil {
 		return err
 	}
@@ -193,10 +259,317 @@ func (c *Client) InsertNumericValues(values []NumericValue) error {
 	return tx.Commit()
 }
 
+// insertNumericIsolated inserts values one at a time, rolling back to a
+// savepoint for each row that fails
+func (c *Client) insertNumericIsolated(values []NumericValue) ([]Rejection, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(insertNumericValueQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var rejections []Rejection
+	for _, v := range values {
+		if _, err := tx.Exec("SAVEPOINT numeric_value"); err != nil {
+			return nil, err
+		}
+		if _, err := stmt.Exec(v.SeriesID, v.Timestamp, v.Value); err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT numeric_value"); rbErr != nil {
+				return nil, rbErr
+			}
+			rejections = append(rejections, Rejection{
+				Value:    v,
+				Reason:   err.Error(),
+				Category: CategorizeInsertError(err),
+			})
+			continue
+		}
+		if _, err := tx.Exec("RELEASE SAVEPOINT numeric_value"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rejections, nil
+}
+
+// insertNumericValueQuery uses ON CONFLICT to handle duplicates
+const insertNumericValueQuery = `
+	INSERT INTO aquaflow.numeric_values (series_id, time_point, value)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (series_id, time_point, version) DO NOTHING
+`
+
 type NumericValue struct {
 	Timestamp time.Time
 	SeriesID  int
 	Value     float64
+	// Payload is the source record as received, kept for the dead-letter store
+	Payload json.RawMessage
+}
+
+// SeriesSelector selects series by their dataset, parameter, dimensions and tags.
+// All set fields must match; dimension names and values are compared after
+// aquaflow.normalize_text.
+type SeriesSelector struct {
+	Dataset    string            `json:"dataset,omitempty"`
+	Parameter  string            `json:"parameter,omitempty"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+}
+
+// ResolveSeriesSelector returns the IDs of all series matching a selector
+func (c *Client) ResolveSeriesSelector(ctx context.Context, selector SeriesSelector) ([]int, error) {
+	query := `
+		SELECT s.series_id
+		FROM aquaflow.series s
+		JOIN aquaflow.datasets ds ON s.dataset_id = ds.dataset_id
+		JOIN aquaflow.parameters p ON s.parameter_id = p.parameter_id
+		WHERE 1=1
+	`
+
+	args := []interface{}{}
+	argCount := 0
+
+	if selector.Dataset != "" {
+		argCount++
+		query += fmt.Sprintf(" AND aquaflow.normalize_text(ds.dataset_name) = aquaflow.normalize_text($%d)", argCount)
+		args = append(args, selector.Dataset)
+	}
+
+	if selector.Parameter != "" {
+		argCount++
+		query += fmt.Sprintf(" AND aquaflow.normalize_text(p.parameter_name) = aquaflow.normalize_text($%d)", argCount)
+		args = append(args, selector.Parameter)
+	}
+
+	// Sort dimension names so the generated query is stable
+	names := make([]string, 0, len(selector.Dimensions))
+	for name := range selector.Dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		query += fmt.Sprintf(`
+		  AND EXISTS (
+			SELECT 1 FROM aquaflow.series_dimensions sd
+			JOIN aquaflow.dimensions d ON sd.dimension_id = d.dimension_id
+			WHERE sd.series_id = s.series_id
+			  AND aquaflow.normalize_text(d.dimension_name) = aquaflow.normalize_text($%d)
+			  AND aquaflow.normalize_text(d.dimension_value) = aquaflow.normalize_text($%d)
+		  )`, argCount+1, argCount+2)
+		argCount += 2
+		args = append(args, name, selector.Dimensions[name])
+	}
+
+	if len(selector.Tags) > 0 {
+		argCount++
+		query += fmt.Sprintf(" AND s.tags @> $%d", argCount)
+		args = append(args, pq.Array(selector.Tags))
+	}
+
+	query += " ORDER BY s.series_id"
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve series selector: %w", err)
+	}
+	defer rows.Close()
+
+	var seriesIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan series id: %w", err)
+		}
+		seriesIDs = append(seriesIDs, id)
+	}
+
+	return seriesIDs, rows.Err()
+}
+
+// SetResolvedSeries records the series a run is going to process
+func (c *Client) SetResolvedSeries(runID uuid.UUID, seriesIDs []int) error {
+	query := `UPDATE aquaflow.etl_job_runs SET resolved_series_ids = $2 WHERE run_id = $1`
+	_, err := c.db.Exec(query, runID, pq.Array(seriesIDs))
+	return err
+}
+
+// SeriesExists reports whether a series with the given ID exists
+func (c *Client) SeriesExists(seriesID int) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM aquaflow.series WHERE series_id = $1)`
+	err := c.db.QueryRow(query, seriesID).Scan(&exists)
+	return exists, err
+}
+
+// CountExistingValues counts how many of the given time points already have
+// a value for the series
+func (c *Client) CountExistingValues(seriesID int, timestamps []time.Time) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(DISTINCT time_point) FROM aquaflow.numeric_values
+		WHERE series_id = $1 AND time_point = ANY($2)
+	`
+	err := c.db.QueryRow(query, seriesID, pq.Array(timestamps)).Scan(&count)
+	return count, err
+}
+
+// RecordHeartbeat marks a streaming run as alive, updates its running totals
+// and, when given, its checkpoint. It returns the run's current status so the
+// caller can notice a cancellation.
+func (c *Client) RecordHeartbeat(runID uuid.UUID, recordsProcessed, recordsFailed int, checkpoint []byte) (string, error) {
+	var status string
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET heartbeat_at = NOW(),
+			records_processed = $2,
+			records_failed = $3,
+			checkpoint = COALESCE($4, checkpoint),
+			updated_at = NOW()
+		WHERE run_id = $1
+		RETURNING status
+	`
+	err := c.db.QueryRow(query, runID, recordsProcessed, recordsFailed, checkpoint).Scan(&status)
+	return status, err
+}
+
+// GetRunCheckpoint returns the stored checkpoint of a run, or nil if it has none
+func (c *Client) GetRunCheckpoint(runID uuid.UUID) ([]byte, error) {
+	var checkpoint []byte
+	query := `SELECT checkpoint FROM aquaflow.etl_job_runs WHERE run_id = $1`
+	err := c.db.QueryRow(query, runID).Scan(&checkpoint)
+	return checkpoint, err
+}
+
+// Shard is one child run of a backfill
+type Shard struct {
+	Key        string
+	Parameters map[string]interface{}
+}
+
+// CreateShardRuns queues a child run per shard under a backfill parent. The
+// children inherit the parent's job, trigger, priority, dry-run flag, log
+// level and correlation ID, and are queued in shard order. traceContext
+// places their runs in the trace of the parent's run.
+func (c *Client) CreateShardRuns(parentRunID uuid.UUID, shards []Shard, traceContext *string) ([]uuid.UUID, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO aquaflow.etl_job_runs (
+			run_id, job_id, schedule_id, run_name, status, trigger_type, runtime_parameters,
+			parent_run_id, shard_key, priority, dry_run, log_level, correlation_id, trace_context, started_at
+		)
+		SELECT $2, job_id, schedule_id, run_name || ' [' || $3 || ']', 'queued', trigger_type, $4,
+			   run_id, $3, priority, dry_run, log_level, correlation_id, $6,
+			   NOW() + $5 * INTERVAL '1 microsecond'
+		FROM aquaflow.etl_job_runs
+		WHERE run_id = $1
+	`
+
+	runIDs := make([]uuid.UUID, 0, len(shards))
+	for i, shard := range shards {
+		paramsJSON, err := json.Marshal(shard.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal shard parameters: %w", err)
+		}
+		runID := uuid.New()
+		if _, err := tx.Exec(query, parentRunID, runID, shard.Key, paramsJSON, i, traceContext); err != nil {
+			return nil, fmt.Errorf("failed to create shard run: %w", err)
+		}
+		runIDs = append(runIDs, runID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return runIDs, nil
+}
+
+// GetRunStatus returns the current status of a run
+func (c *Client) GetRunStatus(runID uuid.UUID) (string, error) {
+	var status string
+	err := c.db.QueryRow(`SELECT status FROM aquaflow.etl_job_runs WHERE run_id = $1`, runID).Scan(&status)
+	return status, err
+}
+
+// RequeueRun hands a running run back to the queue so another worker can
+// resume it
+func (c *Client) RequeueRun(runID uuid.UUID) error {
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'queued', heartbeat_at = NULL, updated_at = NOW()
+		WHERE run_id = $1 AND status = 'running'
+	`
+	_, err := c.db.Exec(query, runID)
+	return err
+}
+
+// FailJobRun marks a run as failed with an error category, keeping the
+// record counts already stored on it
+func (c *Client) FailJobRun(runID uuid.UUID, errorMsg, errorCategory string) error {
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET status = 'failed', error_message = $2, error_category = $3,
+			completed_at = NOW(), updated_at = NOW()
+		WHERE run_id = $1
+	`
+	_, err := c.db.Exec(query, runID, errorMsg, errorCategory)
+	return err
+}
+
+// UpdateRunProgress stores a run's running totals and structured progress
+func (c *Client) UpdateRunProgress(runID uuid.UUID, recordsProcessed, recordsFailed int, progress []byte) error {
+	query := `
+		UPDATE aquaflow.etl_job_runs
+		SET records_processed = $2, records_failed = $3, progress = $4, updated_at = NOW()
+		WHERE run_id = $1
+	`
+	_, err := c.db.Exec(query, runID, recordsProcessed, recordsFailed, progress)
+	return err
+}
+
+// SaveWatermark records the latest data timestamp a run wrote. A resumed
+// run never moves its watermark back.
+func (c *Client) SaveWatermark(runID uuid.UUID, watermark time.Time) error {
+	query := `UPDATE aquaflow.etl_job_runs SET watermark = GREATEST(watermark, $2) WHERE run_id = $1`
+	_, err := c.db.Exec(query, runID, watermark)
+	return err
+}
+
+// SaveDryRunReport stores the report of a dry run on the run
+func (c *Client) SaveDryRunReport(runID uuid.UUID, report []byte) error {
+	query := `UPDATE aquaflow.etl_job_runs SET dry_run_report = $2 WHERE run_id = $1`
+	_, err := c.db.Exec(query, runID, report)
+	return err
+}
+
+// UpsertJobType publishes a job type and its parameter schema
+func (c *Client) UpsertJobType(jobType, description string, parameterSchema []byte) error {
+	query := `
+		INSERT INTO aquaflow.etl_job_types (job_type, description, parameter_schema, registered_by)
+		VALUES ($1, $2, $3, 'worker')
+		ON CONFLICT (job_type) DO UPDATE SET
+			description = EXCLUDED.description,
+			parameter_schema = EXCLUDED.parameter_schema,
+			registered_by = EXCLUDED.registered_by
+	`
+	if _, err := c.db.Exec(query, jobType, description, parameterSchema); err != nil {
+		return fmt.Errorf("failed to publish job type %s: %w", jobType, err)
+	}
+	return nil
 }
 
 // HealthCheck verifies database connectivity
@@ -228,4 +601,41 @@ func (c *Client) GetJobRetryCount(batchID uuid.UUID) (int, error) {
 		return int(count.Int64), nil
 	}
 	return 0, nil
-}
\ No newline at end of file
+}
+// QueueStat is the number of runs of a job waiting to be executed
+type QueueStat struct {
+	JobName string
+	Status  string
+	Count   int
+	// OldestAge is how long the oldest of the runs has waited
+	OldestAge time.Duration
+}
+
+// GetQueueStats returns the waiting and queued runs of every job
+func (c *Client) GetQueueStats() ([]QueueStat, error) {
+	query := `
+		SELECT j.job_name, r.status, COUNT(*),
+			   EXTRACT(EPOCH FROM NOW() - MIN(r.started_at))
+		FROM aquaflow.etl_job_runs r
+		JOIN aquaflow.etl_jobs_v2 j ON r.job_id = j.job_id
+		WHERE r.status IN ('waiting', 'queued')
+		GROUP BY j.job_name, r.status
+	`
+	rows, err := c.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queue stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []QueueStat
+	for rows.Next() {
+		var stat QueueStat
+		var ageSeconds float64
+		if err := rows.Scan(&stat.JobName, &stat.Status, &stat.Count, &ageSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan queue stats: %w", err)
+		}
+		stat.OldestAge = time.Duration(ageSeconds * float64(time.Second))
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
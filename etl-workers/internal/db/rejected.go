@@ -0,0 +1,164 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Rejection categories stored in etl_rejected_records.error_category
+const (
+	RejectParse         = "parse"
+	RejectValidation    = "validation"
+	RejectUnknownSeries = "unknown_series"
+	RejectInvalidValue  = "invalid_value"
+	RejectConstraint    = "constraint"
+	RejectDatabase      = "database"
+)
+
+// Rejection is a source record that could not be loaded
+type Rejection struct {
+	Value    NumericValue
+	Reason   string
+	Category string
+}
+
+// RejectedRecord is a row of the dead-letter store
+type RejectedRecord struct {
+	RecordID       int64
+	RunID          uuid.UUID
+	SeriesID       *int
+	TimePoint      *time.Time
+	RawPayload     json.RawMessage
+	Reason         string
+	ErrorCategory  string
+	Status         string
+	ReplayAttempts int
+}
+
+// CategorizeInsertError maps a database error to a rejection category
+func CategorizeInsertError(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return RejectDatabase
+	}
+	switch {
+	case pqErr.Code == "23503": // foreign_key_violation
+		return RejectUnknownSeries
+	case strings.HasPrefix(string(pqErr.Code), "22"): // data_exception
+		return RejectInvalidValue
+	case strings.HasPrefix(string(pqErr.Code), "23"): // integrity_constraint_violation
+		return RejectConstraint
+	default:
+		return RejectDatabase
+	}
+}
+
+// InsertRejectedRecords stores rejected records for a run in the dead-letter store
+func (c *Client) InsertRejectedRecords(runID uuid.UUID, rejections []Rejection) error {
+	if len(rejections) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+		INSERT INTO aquaflow.etl_rejected_records
+			(run_id, series_id, time_point, raw_payload, reason, error_category)
+		VALUES `)
+
+	args := make([]interface{}, 0, len(rejections)*6)
+	for i, r := range rejections {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		n := i * 6
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+
+		var seriesID interface{}
+		if r.Value.SeriesID != 0 {
+			seriesID = r.Value.SeriesID
+		}
+		var timePoint interface{}
+		if !r.Value.Timestamp.IsZero() {
+			timePoint = r.Value.Timestamp
+		}
+		payload := r.Value.Payload
+		if !json.Valid(payload) {
+			// Keep unparseable payloads as a JSON string so nothing is lost
+			payload, _ = json.Marshal(string(payload))
+		}
+
+		args = append(args, runID, seriesID, timePoint, []byte(payload), r.Reason, r.Category)
+	}
+
+	if _, err := c.db.Exec(sb.String(), args...); err != nil {
+		return fmt.Errorf("failed to store rejected records: %w", err)
+	}
+	return nil
+}
+
+// GetPendingRejectedRecords returns the pending records among the given IDs
+func (c *Client) GetPendingRejectedRecords(recordIDs []int64) ([]RejectedRecord, error) {
+	query := `
+		SELECT record_id, run_id, series_id, time_point, raw_payload, reason,
+			   error_category, status, replay_attempts
+		FROM aquaflow.etl_rejected_records
+		WHERE record_id = ANY($1) AND status = 'pending'
+		ORDER BY record_id
+	`
+
+	rows, err := c.db.Query(query, pq.Array(recordIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejected records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []RejectedRecord
+	for rows.Next() {
+		var r RejectedRecord
+		var payload []byte
+		if err := rows.Scan(&r.RecordID, &r.RunID, &r.SeriesID, &r.TimePoint, &payload,
+			&r.Reason, &r.ErrorCategory, &r.Status, &r.ReplayAttempts); err != nil {
+			return nil, fmt.Errorf("failed to scan rejected record: %w", err)
+		}
+		r.RawPayload = json.RawMessage(payload)
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// MarkRejectedRecordReplayed marks a record as successfully replayed by a run
+func (c *Client) MarkRejectedRecordReplayed(recordID int64, replayRunID uuid.UUID) error {
+	query := `
+		UPDATE aquaflow.etl_rejected_records
+		SET status = 'replayed',
+			replay_attempts = replay_attempts + 1,
+			last_replay_at = NOW(),
+			last_replay_error = NULL,
+			replay_run_id = $2
+		WHERE record_id = $1
+	`
+	_, err := c.db.Exec(query, recordID, replayRunID)
+	return err
+}
+
+// MarkRejectedRecordReplayFailed records a failed replay attempt
+func (c *Client) MarkRejectedRecordReplayFailed(recordID int64, replayRunID uuid.UUID, reason, category string) error {
+	query := `
+		UPDATE aquaflow.etl_rejected_records
+		SET replay_attempts = replay_attempts + 1,
+			last_replay_at = NOW(),
+			last_replay_error = $3,
+			error_category = $4,
+			replay_run_id = $2
+		WHERE record_id = $1
+	`
+	_, err := c.db.Exec(query, recordID, replayRunID, reason, category)
+	return err
+}
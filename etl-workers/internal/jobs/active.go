@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/google/uuid"
+)
+
+// ActiveRun is a run this worker is currently executing
+type ActiveRun struct {
+	RunID         uuid.UUID  `json:"run_id"`
+	JobName       string     `json:"job_name"`
+	JobType       string     `json:"job_type"`
+	CorrelationID uuid.UUID  `json:"correlation_id"`
+	Priority      int        `json:"priority"`
+	DryRun        bool       `json:"dry_run"`
+	ParentRunID   *uuid.UUID `json:"parent_run_id,omitempty"`
+	ClaimedAt     time.Time  `json:"claimed_at"`
+}
+
+// ActiveRuns returns the runs being executed, oldest claim first
+func (p *Processor) ActiveRuns() []ActiveRun {
+	p.activeMu.Lock()
+	runs := make([]ActiveRun, 0, len(p.active))
+	for _, run := range p.active {
+		runs = append(runs, run)
+	}
+	p.activeMu.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ClaimedAt.Before(runs[j].ClaimedAt) })
+	return runs
+}
+
+// Drain stops the processor from claiming new runs. Runs already claimed
+// carry on to completion.
+func (p *Processor) Drain() {
+	p.draining.Store(true)
+}
+
+// Resume lets a drained processor claim runs again
+func (p *Processor) Resume() {
+	p.draining.Store(false)
+}
+
+// Draining reports whether the processor has stopped claiming runs
+func (p *Processor) Draining() bool {
+	return p.draining.Load()
+}
+
+// StopRuns cancels the runs being executed; each is handed back to the
+// queue. The worker calls it once its shutdown grace period is over.
+func (p *Processor) StopRuns() {
+	p.stopRuns()
+}
+
+func (p *Processor) runsStopped() bool {
+	return p.runsCtx.Err() != nil
+}
+
+// runContext returns the context a run executes under. It carries the
+// values of ctx but is cancelled by StopRuns rather than by ctx.
+func (p *Processor) runContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), workerContextKey{}, ctx))
+	stop := context.AfterFunc(p.runsCtx, cancel)
+	return runCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (p *Processor) trackRun(job *db.ETLJob) {
+	p.activeMu.Lock()
+	defer p.activeMu.Unlock()
+	p.active[job.BatchID] = ActiveRun{
+		RunID:         job.BatchID,
+		JobName:       job.JobName,
+		JobType:       job.JobType,
+		CorrelationID: job.CorrelationID,
+		Priority:      job.Priority,
+		DryRun:        job.DryRun,
+		ParentRunID:   job.ParentRunID,
+		ClaimedAt:     time.Now(),
+	}
+}
+
+func (p *Processor) untrackRun(runID uuid.UUID) {
+	p.activeMu.Lock()
+	defer p.activeMu.Unlock()
+	delete(p.active, runID)
+}
+
+// workerContextKey holds the worker's context in the context of a run, which
+// outlives it
+type workerContextKey struct{}
+
+// untilShutdown returns a context of a run that is also cancelled when the
+// worker shuts down, for runs that requeue as soon as it does
+func untilShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	workerCtx, ok := ctx.Value(workerContextKey{}).(context.Context)
+	if !ok {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(workerCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestProcessor() *Processor {
+	runsCtx, stopRuns := context.WithCancel(context.Background())
+	return &Processor{runsCtx: runsCtx, stopRuns: stopRuns}
+}
+
+func TestRunContextOutlivesShutdown(t *testing.T) {
+	p := newTestProcessor()
+	workerCtx, shutdown := context.WithCancel(context.Background())
+	runCtx, stop := p.runContext(workerCtx)
+	defer stop()
+
+	shutdown()
+	if runCtx.Err() != nil {
+		t.Fatalf("run context cancelled by worker shutdown: %v", runCtx.Err())
+	}
+	if p.runsStopped() {
+		t.Fatal("runs stopped by worker shutdown")
+	}
+}
+
+func TestStopRunsCancelsRunContext(t *testing.T) {
+	p := newTestProcessor()
+	runCtx, stop := p.runContext(context.Background())
+	defer stop()
+
+	p.StopRuns()
+	select {
+	case <-runCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("run context not cancelled by StopRuns")
+	}
+	if !p.runsStopped() {
+		t.Fatal("runsStopped false after StopRuns")
+	}
+}
+
+func TestUntilShutdownCancelsOnShutdown(t *testing.T) {
+	p := newTestProcessor()
+	workerCtx, shutdown := context.WithCancel(context.Background())
+	runCtx, stopRun := p.runContext(workerCtx)
+	defer stopRun()
+	ctx, stop := untilShutdown(runCtx)
+	defer stop()
+
+	shutdown()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("streaming context not cancelled by worker shutdown")
+	}
+}
+
+func TestUntilShutdownStop(t *testing.T) {
+	p := newTestProcessor()
+	workerCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	runCtx, stopRun := p.runContext(workerCtx)
+	defer stopRun()
+
+	ctx, stop := untilShutdown(runCtx)
+	stop()
+	if ctx.Err() == nil {
+		t.Fatal("context not cancelled by stop")
+	}
+	if runCtx.Err() != nil {
+		t.Fatal("stop cancelled the run context")
+	}
+}
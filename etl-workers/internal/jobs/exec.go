@@ -0,0 +1,530 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/logger"
+)
+
+// execSchema is the JSON Schema published for exec parameters
+const execSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["script"],
+	"properties": {
+		"script": {"type": "string", "minLength": 1},
+		"interpreter": {"type": "string"},
+		"args": {"type": "array", "items": {"type": "string"}},
+		"params": {"type": "object"},
+		"env": {
+			"type": "object",
+			"additionalProperties": {"type": "string"},
+			"propertyNames": {
+				"pattern": "^[A-Za-z_][A-Za-z0-9_]*$",
+				"not": {"pattern": "^(LD_|PATH$|PYTHON|R_|HOME$|TMPDIR$|ETL_)"}
+			}
+		},
+		"output": {"type": "string", "enum": ["text", "ndjson"]},
+		"batch_size": {"type": "integer", "minimum": 1, "maximum": 10000},
+		"memory_limit_mb": {"type": "integer", "minimum": 16},
+		"max_log_lines": {"type": "integer", "minimum": 0},
+		"timeout_seconds": {"type": "integer", "minimum": 1}
+	}
+}`
+
+// reservedEnvPattern matches the environment variables a run may not set:
+// those that change which code the interpreter loads, and the ones the
+// worker sets itself. execSchema rejects the same names.
+var reservedEnvPattern = regexp.MustCompile(`^(LD_|PATH$|PYTHON|R_|HOME$|TMPDIR$|ETL_)`)
+
+// envNamePattern matches a valid environment variable name
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+const (
+	defaultExecBatchSize   = 1000
+	defaultExecMaxLogLines = 1000
+	// execMaxLineSize is the longest stdout or stderr line a script may write
+	execMaxLineSize = 1024 * 1024
+)
+
+// ExecConfig is the worker-wide configuration of the exec job type
+type ExecConfig struct {
+	// ScriptsDir is the only directory scripts may be run from
+	ScriptsDir string
+	// Interpreters are the interpreter commands a run may ask for
+	Interpreters []string
+	// DefaultMemoryMB limits a script's address space when the run does
+	// not set memory_limit_mb; 0 means no limit
+	DefaultMemoryMB int
+	// UID and GID, when set, run scripts as an unprivileged user
+	UID *uint32
+	GID *uint32
+}
+
+var execConfig = ExecConfig{
+	ScriptsDir:      "/opt/etl-scripts",
+	Interpreters:    []string{"python3", "Rscript"},
+	DefaultMemoryMB: 512,
+}
+
+// ConfigureExec sets the configuration of the exec job type. It must be
+// called before the worker starts claiming runs.
+func ConfigureExec(config ExecConfig) {
+	execConfig = config
+}
+
+func init() {
+	Register(JobType{
+		Name:            "exec",
+		Description:     "Run a script from the scripts directory, optionally loading NDJSON data points from its output",
+		ParameterSchema: json.RawMessage(execSchema),
+		DefaultTimeout:  time.Hour,
+		New: func(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) JobHandler {
+			return NewExecJob(dbClient, logger, sink)
+		},
+	})
+}
+
+// ExecJob runs an external script in a scratch directory with a clean
+// environment and a memory limit. Its stdout and stderr go to the run's
+// logs; with output "ndjson", stdout lines that are JSON objects are loaded
+// as data points instead.
+type ExecJob struct {
+	db     *db.Client
+	logger *logger.ETLLogger
+	sink   ValueSink
+	config ExecConfig
+}
+
+func NewExecJob(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) *ExecJob {
+	return &ExecJob{
+		db:     dbClient,
+		logger: logger,
+		sink:   sink,
+		config: execConfig,
+	}
+}
+
+// execParams are the decoded parameters of an exec run
+type execParams struct {
+	Script      string            `json:"script"`
+	Interpreter string            `json:"interpreter"`
+	Args        []string          `json:"args"`
+	Params      json.RawMessage   `json:"params"`
+	Env         map[string]string `json:"env"`
+	Output      string            `json:"output"`
+	BatchSize   int               `json:"batch_size"`
+	MemoryLimit *int              `json:"memory_limit_mb"`
+	MaxLogLines *int              `json:"max_log_lines"`
+}
+
+func (e *ExecJob) Execute(ctx context.Context, job *db.ETLJob) error {
+	params, err := parseExecParams(job.Parameters)
+	if err != nil {
+		return err
+	}
+
+	argv, err := e.command(params)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := os.MkdirTemp("", "etl-exec-")
+	if err != nil {
+		return fmt.Errorf("failed to create script directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	paramsFile := filepath.Join(workDir, "params.json")
+	if err := e.writeParamsFile(paramsFile, job, params); err != nil {
+		return err
+	}
+	if err := chownForScript(workDir, paramsFile, e.config); err != nil {
+		return fmt.Errorf("failed to prepare script directory: %w", err)
+	}
+
+	memoryMB := e.config.DefaultMemoryMB
+	if params.MemoryLimit != nil {
+		memoryMB = *params.MemoryLimit
+	}
+	if memoryMB > 0 {
+		// The shell applies the limit to itself, then execs the script
+		argv = append([]string{"/bin/sh", "-c", `ulimit -v "$1" && shift && exec "$@"`, "sh",
+			strconv.Itoa(memoryMB * 1024)}, argv...)
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = execEnv(workDir, paramsFile, job, params)
+	configureSandbox(cmd, e.config)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	e.logger.Info(job.BatchID, "Starting script", map[string]interface{}{
+		"script":          params.Script,
+		"interpreter":     params.Interpreter,
+		"args":            params.Args,
+		"output":          params.Output,
+		"memory_limit_mb": memoryMB,
+		"job_name":        job.JobName,
+	})
+
+	startTime := time.Now()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start script: %w", err)
+	}
+
+	output := &scriptOutput{
+		logger:   e.logger.WithComponent(logger.ComponentScript),
+		job:      job,
+		maxLines: defaultExecMaxLogLines,
+	}
+	if params.MaxLogLines != nil {
+		output.maxLines = *params.MaxLogLines
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		output.scan(stderr, "stderr", logger.WARN, nil)
+	}()
+
+	var loader *ndjsonLoader
+	var onRecord func([]byte) bool
+	if params.Output == "ndjson" {
+		loader = &ndjsonLoader{
+			sink:      e.sink,
+			logger:    e.logger,
+			job:       job,
+			progress:  NewProgressTracker(e.db, e.logger, job, 0),
+			batchSize: params.BatchSize,
+		}
+		onRecord = loader.add
+	}
+	output.scan(stdout, "stdout", logger.INFO, onRecord)
+	wg.Wait()
+
+	waitErr := cmd.Wait()
+	if loader != nil {
+		loader.flush()
+	}
+	output.summarize()
+
+	processed, failed := 0, 0
+	if loader != nil {
+		processed, failed = loader.progress.Counts()
+	}
+
+	if waitErr != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			return fmt.Errorf("script %s failed: %s", params.Script, exitErr.ProcessState)
+		}
+		return fmt.Errorf("script %s failed: %w", params.Script, waitErr)
+	}
+
+	status := "completed"
+	if failed > 0 {
+		status = "completed_with_errors"
+	}
+
+	e.logger.Info(job.BatchID, "Script completed", map[string]interface{}{
+		"script":           params.Script,
+		"duration_seconds": time.Since(startTime).Seconds(),
+		"total_processed":  processed,
+		"total_failed":     failed,
+		"job_name":         job.JobName,
+	})
+
+	return e.db.UpdateJobStatus(job.BatchID, status, processed, failed, nil)
+}
+
+// parseExecParams decodes the run parameters through JSON so nested values
+// keep their types
+func parseExecParams(raw map[string]interface{}) (execParams, error) {
+	var params execParams
+	encoded, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(encoded, &params)
+	}
+	if err != nil {
+		return params, fmt.Errorf("invalid exec parameters: %w", err)
+	}
+	if params.Script == "" {
+		return params, fmt.Errorf("missing script parameter")
+	}
+	if params.Output == "" {
+		params.Output = "text"
+	}
+	if params.Output != "text" && params.Output != "ndjson" {
+		return params, fmt.Errorf("invalid output parameter %q", params.Output)
+	}
+	if params.BatchSize <= 0 {
+		params.BatchSize = defaultExecBatchSize
+	}
+	if err := checkExecEnv(params.Env); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// checkExecEnv rejects env parameters that are not valid names or that
+// would let a job definition load its own code into the interpreter
+func checkExecEnv(env map[string]string) error {
+	var rejected []string
+	for key := range env {
+		if !envNamePattern.MatchString(key) || reservedEnvPattern.MatchString(key) {
+			rejected = append(rejected, key)
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return fmt.Errorf("env parameter may not set %s", strings.Join(rejected, ", "))
+	}
+	return nil
+}
+
+// command returns the interpreter and script to run. The script must be a
+// regular file inside the scripts directory once symlinks are resolved, and
+// the interpreter must be allowed by the worker configuration.
+func (e *ExecJob) command(params execParams) ([]string, error) {
+	root, err := filepath.EvalSymlinks(e.config.ScriptsDir)
+	if err != nil {
+		return nil, fmt.Errorf("scripts directory unavailable: %w", err)
+	}
+
+	scriptPath, err := filepath.EvalSymlinks(filepath.Join(root, filepath.Clean("/"+params.Script)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid script %q: %w", params.Script, err)
+	}
+	if rel, err := filepath.Rel(root, scriptPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("invalid script %q: outside the scripts directory", params.Script)
+	}
+	info, err := os.Stat(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script %q: %w", params.Script, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("invalid script %q: not a regular file", params.Script)
+	}
+
+	argv := []string{scriptPath}
+	if params.Interpreter != "" {
+		allowed := false
+		for _, interpreter := range e.config.Interpreters {
+			if interpreter == params.Interpreter {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("invalid interpreter %q: not in the allowed interpreters", params.Interpreter)
+		}
+		interpreterPath, err := exec.LookPath(params.Interpreter)
+		if err != nil {
+			return nil, fmt.Errorf("interpreter %q not available: %w", params.Interpreter, err)
+		}
+		argv = []string{interpreterPath, scriptPath}
+	}
+	return append(argv, params.Args...), nil
+}
+
+// writeParamsFile writes the file the script reads its parameters from
+func (e *ExecJob) writeParamsFile(path string, job *db.ETLJob, params execParams) error {
+	scriptParams := params.Params
+	if len(scriptParams) == 0 {
+		scriptParams = json.RawMessage("{}")
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{
+		"run_id":     job.BatchID,
+		"job_name":   job.JobName,
+		"dry_run":    job.DryRun,
+		"parameters": scriptParams,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+// execEnv is the script's environment. It starts empty so worker secrets
+// such as DATABASE_URL are not inherited; the ETL_ variables are set last
+// so a run cannot override them.
+func execEnv(workDir, paramsFile string, job *db.ETLJob, params execParams) []string {
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"LANG=C.UTF-8",
+	}
+	for key, value := range params.Env {
+		// parseExecParams has rejected reserved names; skip any that get here
+		if reservedEnvPattern.MatchString(key) || !envNamePattern.MatchString(key) {
+			continue
+		}
+		env = append(env, key+"="+value)
+	}
+	return append(env,
+		"HOME="+workDir,
+		"TMPDIR="+workDir,
+		"ETL_RUN_ID="+job.BatchID.String(),
+		"ETL_PARAMS_FILE="+paramsFile,
+		"ETL_OUTPUT_FORMAT="+params.Output,
+		"ETL_DRY_RUN="+strconv.FormatBool(job.DryRun),
+	)
+}
+
+// scriptOutput copies a script's output lines into the run's logs, up to
+// maxLines lines across stdout and stderr
+type scriptOutput struct {
+	logger   *logger.ETLLogger
+	job      *db.ETLJob
+	maxLines int
+
+	logged     atomic.Int64
+	suppressed atomic.Int64
+}
+
+// scan logs each line read from r. Lines that onRecord accepts are data,
+// not log output.
+func (o *scriptOutput) scan(r io.Reader, stream string, level logger.LogLevel, onRecord func([]byte) bool) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), execMaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if onRecord != nil && onRecord(line) {
+			continue
+		}
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if o.logged.Add(1) > int64(o.maxLines) {
+			o.suppressed.Add(1)
+			continue
+		}
+		o.logger.Log(o.job.BatchID, level, string(line), map[string]interface{}{"stream": stream})
+	}
+	if err := scanner.Err(); err != nil {
+		o.logger.Warn(o.job.BatchID, "Failed to read script output", map[string]interface{}{
+			"stream": stream,
+			"error":  err.Error(),
+		})
+		// Keep draining so the script is not blocked on a full pipe
+		io.Copy(io.Discard, r)
+	}
+}
+
+// summarize logs how many lines were left out of the run's logs
+func (o *scriptOutput) summarize() {
+	if suppressed := o.suppressed.Load(); suppressed > 0 {
+		o.logger.Warn(o.job.BatchID, "Script output truncated", map[string]interface{}{
+			"max_log_lines":    o.maxLines,
+			"suppressed_lines": suppressed,
+		})
+	}
+}
+
+// ndjsonLoader loads the data points a script writes to stdout, one JSON
+// object per line, in batches
+type ndjsonLoader struct {
+	sink      ValueSink
+	logger    *logger.ETLLogger
+	job       *db.ETLJob
+	progress  *ProgressTracker
+	batchSize int
+
+	batch      int
+	values     []db.NumericValue
+	rejections []db.Rejection
+}
+
+// add takes a stdout line if it is a record
+func (l *ndjsonLoader) add(line []byte) bool {
+	trimmed := strings.TrimSpace(string(line))
+	if !strings.HasPrefix(trimmed, "{") {
+		return false
+	}
+
+	raw := json.RawMessage(trimmed)
+	value, rejection := toNumericValue(raw, 0)
+	if rejection != nil {
+		l.rejections = append(l.rejections, *rejection)
+	} else {
+		l.values = append(l.values, value)
+	}
+	if len(l.values)+len(l.rejections) >= l.batchSize {
+		l.flush()
+	}
+	return true
+}
+
+// flush writes the pending batch
+func (l *ndjsonLoader) flush() {
+	if len(l.values) == 0 && len(l.rejections) == 0 {
+		return
+	}
+	l.batch++
+
+	// Rows the database refuses are isolated and rejected individually
+	insertRejections, err := l.sink.Write(l.values)
+	if err != nil {
+		l.logger.Error(l.job.BatchID, "Failed to insert batch", map[string]interface{}{
+			"batch":      l.batch,
+			"batch_size": len(l.values),
+			"job_name":   l.job.JobName,
+			"error":      err.Error(),
+		})
+		insertRejections = rejectAll(l.values, err)
+	}
+	rejections := append(l.rejections, insertRejections...)
+	inserted := len(l.values) - len(insertRejections)
+	l.progress.Add(l.batch, inserted, len(rejections))
+
+	if len(rejections) > 0 {
+		if err := l.sink.Reject(l.job.BatchID, rejections); err != nil {
+			l.logger.Error(l.job.BatchID, "Failed to store rejected records", map[string]interface{}{
+				"batch":    l.batch,
+				"rejected": len(rejections),
+				"error":    err.Error(),
+			})
+		}
+		l.logger.WithComponent(logger.ComponentValidator).Warn(l.job.BatchID, "Records rejected", map[string]interface{}{
+			"batch":    l.batch,
+			"rejected": len(rejections),
+			"job_name": l.job.JobName,
+		})
+	}
+
+	l.logger.Debug(l.job.BatchID, "Inserted script records", map[string]interface{}{
+		"batch":            l.batch,
+		"records_inserted": inserted,
+		"dry_run":          l.job.DryRun,
+	})
+
+	l.values = nil
+	l.rejections = nil
+}
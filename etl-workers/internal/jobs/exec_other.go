@@ -0,0 +1,18 @@
+//go:build !unix
+
+package jobs
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// configureSandbox has no process group or user switching outside unix
+func configureSandbox(cmd *exec.Cmd, config ExecConfig) {}
+
+func chownForScript(workDir, paramsFile string, config ExecConfig) error {
+	if config.UID != nil || config.GID != nil {
+		return fmt.Errorf("running scripts as another user is not supported on this platform")
+	}
+	return nil
+}
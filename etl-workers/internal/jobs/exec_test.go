@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/google/uuid"
+)
+
+func TestParseExecParamsRejectsReservedEnv(t *testing.T) {
+	for _, key := range []string{"LD_PRELOAD", "LD_LIBRARY_PATH", "PATH", "PYTHONPATH", "PYTHONSTARTUP",
+		"R_LIBS", "HOME", "TMPDIR", "ETL_RUN_ID", "BAD=NAME", "1ABC", ""} {
+		_, err := parseExecParams(map[string]interface{}{
+			"script": "load.py",
+			"env":    map[string]interface{}{key: "x"},
+		})
+		if err == nil {
+			t.Errorf("env %q: expected an error", key)
+		}
+	}
+
+	params, err := parseExecParams(map[string]interface{}{
+		"script": "load.py",
+		"env":    map[string]interface{}{"SOURCE_URL": "https://example.com", "PATHWAY": "a", "LDAP_HOST": "b"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(params.Env) != 3 {
+		t.Errorf("env = %v, want 3 entries", params.Env)
+	}
+}
+
+func TestExecEnvKeepsWorkerVariables(t *testing.T) {
+	job := &db.ETLJob{BatchID: uuid.New()}
+	env := execEnv("/tmp/run", "/tmp/run/params.json", job, execParams{
+		Output: "text",
+		Env:    map[string]string{"LD_PRELOAD": "/tmp/evil.so", "SOURCE": "scada"},
+	})
+
+	joined := strings.Join(env, "\n")
+	if strings.Contains(joined, "LD_PRELOAD") {
+		t.Errorf("reserved variable passed to script: %v", env)
+	}
+	if !strings.Contains(joined, "SOURCE=scada") {
+		t.Errorf("job variable missing: %v", env)
+	}
+	if !strings.Contains(joined, "PATH=/usr/local/bin:/usr/bin:/bin") {
+		t.Errorf("worker PATH missing: %v", env)
+	}
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package jobs
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// execWaitDelay is how long a killed script's output pipes may stay open
+const execWaitDelay = 5 * time.Second
+
+// configureSandbox runs the script in its own process group, as the
+// configured user if any, and kills the whole group when the run's context
+// ends so child processes do not outlive the run. A script run as another
+// user has no supplementary groups, so it does not keep the worker's root,
+// disk or wheel membership.
+func configureSandbox(cmd *exec.Cmd, config ExecConfig) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if config.UID != nil || config.GID != nil {
+		credential := &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid()), Groups: []uint32{}}
+		if config.UID != nil {
+			credential.Uid = *config.UID
+		}
+		if config.GID != nil {
+			credential.Gid = *config.GID
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = execWaitDelay
+}
+
+// chownForScript hands the script directory to the configured user
+func chownForScript(workDir, paramsFile string, config ExecConfig) error {
+	if config.UID == nil && config.GID == nil {
+		return nil
+	}
+	uid, gid := -1, -1
+	if config.UID != nil {
+		uid = int(*config.UID)
+	}
+	if config.GID != nil {
+		gid = int(*config.GID)
+	}
+	for _, path := range []string{workDir, paramsFile} {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
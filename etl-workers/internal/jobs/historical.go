@@ -7,29 +7,72 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/aquaflow/etl-workers/internal/db"
 	"github.com/aquaflow/etl-workers/internal/logger"
+	"github.com/aquaflow/etl-workers/internal/metrics"
+	"github.com/aquaflow/etl-workers/internal/tracing"
 )
 
 type HistoricalLoadJob struct {
 	db     *db.Client
 	logger *logger.ETLLogger
+	sink   ValueSink
+}
+
+// historicalLoadSchema is the JSON Schema published for historical_load parameters
+const historicalLoadSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["source_url", "start_date", "end_date"],
+	"anyOf": [{"required": ["series_ids"]}, {"required": ["series_selector"]}],
+	"properties": {
+		"source_url": {"type": "string", "format": "uri"},
+		"start_date": {"type": "string", "format": "date"},
+		"end_date": {"type": "string", "format": "date"},
+		"series_ids": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "integer", "minimum": 1}
+		},
+		"series_selector": ` + seriesSelectorSchema + `,
+		"batch_size": {"type": "integer", "minimum": 1, "maximum": 10000},
+		"timeout_seconds": {"type": "integer", "minimum": 1}
+	},
+	"$defs": ` + seriesSelectorDefs + `
+}`
+
+// historicalSourceClient records source latency and errors under this job type
+var historicalSourceClient = metrics.SourceClient("historical_load")
+
+func init() {
+	Register(JobType{
+		Name:            "historical_load",
+		Description:     "Paged load of historical values for a date range",
+		ParameterSchema: json.RawMessage(historicalLoadSchema),
+		DefaultTimeout:  2 * time.Hour,
+		ShardModes:      []string{"month", "series"},
+		New: func(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) JobHandler {
+			return NewHistoricalLoadJob(dbClient, logger, sink)
+		},
+	})
 }
 
 type HistoricalDataResponse struct {
-	Data       []DataPoint `json:"data"`
-	Page       int         `json:"page"`
-	Limit      int         `json:"limit"`
-	TotalCount int         `json:"total_count"`
-	HasMore    bool        `json:"has_more"`
+	Data       []json.RawMessage `json:"data"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	TotalCount int               `json:"total_count"`
+	HasMore    bool              `json:"has_more"`
 }
 
 
-func NewHistoricalLoadJob(dbClient *db.Client, logger *logger.ETLLogger) *HistoricalLoadJob {
+func NewHistoricalLoadJob(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) *HistoricalLoadJob {
 	return &HistoricalLoadJob{
 		db:     dbClient,
 		logger: logger,
+		sink:   sink,
 	}
 }
 
@@ -54,28 +97,24 @@ func (h *HistoricalLoadJob) Execute(ctx context.Context, job *db.ETLJob) error {
 		return fmt.Errorf("missing or invalid end_date parameter")
 	}
 
-	seriesIDsRaw, ok := job.Parameters["series_ids"].([]interface{})
-	if !ok {
-		return fmt.Errorf("missing or invalid series_ids parameter")
-	}
-
 	batchSize := 1000
 	if bs, ok := job.Parameters["batch_size"].(float64); ok {
 		batchSize = int(bs)
 	}
 
-	// Convert series IDs to int slice
-	var seriesIDs []int
-	for _, id := range seriesIDsRaw {
-		if fid, ok := id.(float64); ok {
-			seriesIDs = append(seriesIDs, int(fid))
-		}
+	// Resolve series_ids and series_selector against the current catalogue
+	seriesIDs, err := resolveSeries(ctx, h.db, job)
+	if err != nil {
+		return err
 	}
+	h.logger.Info(job.BatchID, "Resolved series", map[string]interface{}{
+		"series_ids": seriesIDs,
+	})
 
-	totalProcessed := 0
-	totalFailed := 0
+	progress := NewProgressTracker(h.db, h.logger, job, len(seriesIDs))
 
 	// Process each series
+	var lastErr error
 	for _, seriesID := range seriesIDs {
 		h.logger.Info(job.BatchID, "Loading data for series", map[string]interface{}{
 			"series_id": seriesID,
@@ -83,21 +122,22 @@ func (h *HistoricalLoadJob) Execute(ctx context.Context, job *db.ETLJob) error {
 			"job_name":  job.JobName,
 		})
 
-		processed, failed, err := h.loadSeriesData(ctx, job, sourceURL, seriesID, startDate, endDate, batchSize)
+		progress.StartSeries(seriesID)
+		seriesCtx, seriesSpan := tracing.StartSeries(ctx, seriesID)
+		err := h.loadSeriesData(seriesCtx, job, progress, sourceURL, seriesID, startDate, endDate, batchSize)
+		tracing.EndSpan(seriesSpan, err)
 		if err != nil {
 			h.logger.Error(job.BatchID, "Failed to load series data", map[string]interface{}{
 				"series_id": seriesID,
 				"job_name":  job.JobName,
 				"error":     err.Error(),
 			})
-			totalFailed += failed
+			progress.FailSeries()
+			lastErr = err
 		}
 
-		totalProcessed += processed
-		totalFailed += failed
-
 		// Update job progress
-		h.db.UpdateJobStatus(job.BatchID, "running", totalProcessed, totalFailed, nil)
+		progress.FinishSeries()
 
 		// Check context cancellation
 		select {
@@ -108,106 +148,164 @@ func (h *HistoricalLoadJob) Execute(ctx context.Context, job *db.ETLJob) error {
 	}
 
 	// Final status update
-	status := "completed"
-	if totalFailed > 0 {
-		status = "completed_with_errors"
-	}
+	totalProcessed, totalFailed := progress.Counts()
+	failedSeries := progress.FailedSeries()
+	status := loadStatus(len(seriesIDs), failedSeries, totalFailed)
 
 	h.logger.Info(job.BatchID, "Historical load completed", map[string]interface{}{
 		"total_processed": totalProcessed,
 		"total_failed":    totalFailed,
+		"failed_series":   failedSeries,
 		"status":          status,
 	})
 
-	return h.db.UpdateJobStatus(job.BatchID, status, totalProcessed, totalFailed, nil)
+	var errMsg *string
+	if status == "failed" {
+		msg := fmt.Sprintf("all %d series failed to load, last error: %v", failedSeries, lastErr)
+		errMsg = &msg
+	}
+	return h.db.UpdateJobStatus(job.BatchID, status, totalProcessed, totalFailed, errMsg)
+}
+
+// loadStatus returns the final status of a load over seriesTotal series. A
+// load where every series failed has failed; one with failed series or
+// records completed with errors.
+func loadStatus(seriesTotal, failedSeries, failedRecords int) string {
+	switch {
+	case failedSeries > 0 && failedSeries == seriesTotal:
+		return "failed"
+	case failedSeries > 0 || failedRecords > 0:
+		return "completed_with_errors"
+	default:
+		return "completed"
+	}
 }
 
-func (h *HistoricalLoadJob) loadSeriesData(ctx context.Context, job *db.ETLJob, baseURL string, seriesID int, startDate, endDate string, batchSize int) (processed, failed int, err error) {
+func (h *HistoricalLoadJob) loadSeriesData(ctx context.Context, job *db.ETLJob, progress *ProgressTracker, baseURL string, seriesID int, startDate, endDate string, batchSize int) error {
 	page := 1
 	hasMore := true
 
 	for hasMore {
-		// Build URL with parameters
-		u, _ := url.Parse(baseURL)
-		q := u.Query()
-		q.Set("series_id", fmt.Sprintf("%d", seriesID))
-		q.Set("start_date", startDate)
-		q.Set("end_date", endDate)
-		q.Set("page", fmt.Sprintf("%d", page))
-		q.Set("limit", fmt.Sprintf("%d", batchSize))
-		u.RawQuery = q.Encode()
-
-		h.logger.Debug(job.BatchID, "Fetching page", map[string]interface{}{
-			"url":       u.String(),
-			"page":      page,
-			"series_id": seriesID,
-			"job_name":  job.JobName,
-		})
-
-		// Fetch data
-		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		pageCtx, pageSpan := tracing.StartPage(ctx, seriesID, page)
+		more, err := h.loadPage(pageCtx, job, progress, baseURL, seriesID, startDate, endDate, batchSize, page)
+		tracing.EndSpan(pageSpan, err)
 		if err != nil {
-			return processed, failed, err
+			return err
 		}
 
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return processed, failed, err
-		}
-		defer resp.Body.Close()
+		hasMore = more
+		page++
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return processed, failed, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		// Check context cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
 		}
+	}
 
-		// Parse response
-		var histResp HistoricalDataResponse
-		if err := json.NewDecoder(resp.Body).Decode(&histResp); err != nil {
-			return processed, failed, err
-		}
+	return nil
+}
 
-		// Convert to database format
-		var values []db.NumericValue
-		for _, dp := range histResp.Data {
-			values = append(values, db.NumericValue{
-				Timestamp: dp.Timestamp,
-				SeriesID:  dp.SeriesID,
-				Value:     dp.Value,
-			})
-		}
+// loadPage loads one page of a series and reports whether more pages follow
+func (h *HistoricalLoadJob) loadPage(ctx context.Context, job *db.ETLJob, progress *ProgressTracker, baseURL string, seriesID int, startDate, endDate string, batchSize, page int) (bool, error) {
+	// Build URL with parameters
+	u, _ := url.Parse(baseURL)
+	q := u.Query()
+	q.Set("series_id", fmt.Sprintf("%d", seriesID))
+	q.Set("start_date", startDate)
+	q.Set("end_date", endDate)
+	q.Set("page", fmt.Sprintf("%d", page))
+	q.Set("limit", fmt.Sprintf("%d", batchSize))
+	u.RawQuery = q.Encode()
+
+	h.logger.Debug(job.BatchID, "Fetching page", map[string]interface{}{
+		"url":       u.String(),
+		"page":      page,
+		"series_id": seriesID,
+		"job_name":  job.JobName,
+	})
 
-		// Insert batch
-		if err := h.db.InsertNumericValues(values); err != nil {
-			h.logger.Error(job.BatchID, "Failed to insert batch", map[string]interface{}{
-				"series_id":    seriesID,
-				"page":         page,
-				"batch_size":   len(values),
-				"job_name":     job.JobName,
-				"error":        err.Error(),
-			})
-			failed += len(values)
-		} else {
-			processed += len(values)
-			h.logger.Info(job.BatchID, "Inserted records successfully", map[string]interface{}{
-				"series_id":    seriesID,
-				"page":         page,
-				"batch_size":   len(values),
-				"job_name":     job.JobName,
-				"records_inserted": len(values),
-			})
+	// Fetch data
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := historicalSourceClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	// Parse response
+	var histResp HistoricalDataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&histResp); err != nil {
+		return false, err
+	}
+
+	if page == 1 {
+		progress.SetSeriesTotal(seriesID, histResp.TotalCount)
+	}
+
+	// Convert to database format, setting aside records that fail validation
+	var values []db.NumericValue
+	var rejections []db.Rejection
+	for _, raw := range histResp.Data {
+		value, rejection := toNumericValue(raw, seriesID)
+		if rejection != nil {
+			rejections = append(rejections, *rejection)
+			continue
 		}
+		values = append(values, value)
+	}
 
-		hasMore = histResp.HasMore
-		page++
+	// Insert batch; rows the database refuses are isolated and rejected individually
+	insertRejections, err := h.sink.Write(values)
+	if err != nil {
+		h.logger.Error(job.BatchID, "Failed to insert batch", map[string]interface{}{
+			"series_id":    seriesID,
+			"page":         page,
+			"batch_size":   len(values),
+			"job_name":     job.JobName,
+			"error":        err.Error(),
+		})
+		insertRejections = rejectAll(values, err)
+	}
+	rejections = append(rejections, insertRejections...)
+	inserted := len(values) - len(insertRejections)
+	progress.Add(page, inserted, len(rejections))
 
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return processed, failed, ctx.Err()
-		default:
+	if len(rejections) > 0 {
+		if err := h.sink.Reject(job.BatchID, rejections); err != nil {
+			h.logger.Error(job.BatchID, "Failed to store rejected records", map[string]interface{}{
+				"series_id": seriesID,
+				"page":      page,
+				"rejected":  len(rejections),
+				"error":     err.Error(),
+			})
 		}
+		h.logger.WithComponent(logger.ComponentValidator).Warn(job.BatchID, "Records rejected", map[string]interface{}{
+			"series_id": seriesID,
+			"page":      page,
+			"rejected":  len(rejections),
+			"job_name":  job.JobName,
+		})
 	}
 
-	return processed, failed, nil
-}
\ No newline at end of file
+	h.logger.Info(job.BatchID, "Inserted records successfully", map[string]interface{}{
+		"series_id":    seriesID,
+		"page":         page,
+		"batch_size":   len(histResp.Data),
+		"job_name":     job.JobName,
+		"records_inserted": inserted,
+		"dry_run":          job.DryRun,
+	})
+
+	return histResp.HasMore, nil
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import "testing"
+
+func TestLoadStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		seriesTotal   int
+		failedSeries  int
+		failedRecords int
+		want          string
+	}{
+		{"clean load", 3, 0, 0, "completed"},
+		{"rejected records", 3, 0, 5, "completed_with_errors"},
+		{"some series failed", 3, 1, 0, "completed_with_errors"},
+		{"every series failed", 3, 3, 0, "failed"},
+		{"every series failed after some records", 2, 2, 4, "failed"},
+		{"no series", 0, 0, 0, "completed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loadStatus(tt.seriesTotal, tt.failedSeries, tt.failedRecords); got != tt.want {
+				t.Errorf("loadStatus(%d, %d, %d) = %q, want %q", tt.seriesTotal, tt.failedSeries, tt.failedRecords, got, tt.want)
+			}
+		})
+	}
+}
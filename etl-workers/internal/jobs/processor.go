@@ -2,18 +2,29 @@ package jobs
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aquaflow/etl-workers/internal/db"
 	"github.com/aquaflow/etl-workers/internal/logger"
+	"github.com/aquaflow/etl-workers/internal/metrics"
+	"github.com/aquaflow/etl-workers/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrNoJobsAvailable = errors.New("no jobs available")
 	ErrMaxRetriesExceeded = errors.New("max retries exceeded")
+	// ErrRunRequeued is returned by handlers that handed their run back to the queue
+	ErrRunRequeued = errors.New("run requeued")
 )
 
 // ErrorType categorizes errors for retry logic
@@ -26,24 +37,44 @@ const (
 )
 
 type Processor struct {
-	db     *db.Client
-	logger *logger.ETLLogger
+	db        *db.Client
+	logger    *logger.ETLLogger
+	// jobLogger is handed to job handlers, which log as the worker component
+	jobLogger *logger.ETLLogger
+
+	// draining stops new claims; active holds the runs being executed
+	draining atomic.Bool
+	activeMu sync.Mutex
+	active   map[uuid.UUID]ActiveRun
+	// runsCtx is cancelled by StopRuns
+	runsCtx  context.Context
+	stopRuns context.CancelFunc
 }
 
 type JobHandler interface {
 	Execute(ctx context.Context, job *db.ETLJob) error
 }
 
-func NewProcessor(dbClient *db.Client, logger *logger.ETLLogger) *Processor {
+func NewProcessor(dbClient *db.Client, etlLogger *logger.ETLLogger) *Processor {
+	runsCtx, stopRuns := context.WithCancel(context.Background())
 	return &Processor{
-		db:     dbClient,
-		logger: logger,
+		db:        dbClient,
+		logger:    etlLogger.WithComponent(logger.ComponentProcessor),
+		jobLogger: etlLogger,
+		active:    make(map[uuid.UUID]ActiveRun),
+		runsCtx:   runsCtx,
+		stopRuns:  stopRuns,
 	}
 }
 
-func (p *Processor) ProcessNextJob(ctx context.Context) error {
+// ProcessNextJob claims and executes the next run allowed by opts
+func (p *Processor) ProcessNextJob(ctx context.Context, opts db.ClaimOptions) error {
+	if p.Draining() {
+		return ErrNoJobsAvailable
+	}
+
 	// Get next pending job
-	job, err := p.db.GetNextPendingJob()
+	job, err := p.db.GetNextPendingJob(opts)
 	if err != nil {
 		return fmt.Errorf("failed to get next job: %w", err)
 	}
@@ -51,27 +82,96 @@ func (p *Processor) ProcessNextJob(ctx context.Context) error {
 	if job == nil {
 		return ErrNoJobsAvailable
 	}
+	p.trackRun(job)
+	defer p.untrackRun(job.BatchID)
+
+	// The run's span joins the trace of the request or cycle that created it
+	ctx, span := tracing.Tracer.Start(tracing.Extract(ctx, job.TraceContext), "run "+job.JobType,
+		trace.WithAttributes(
+			attribute.String("etl.run_id", job.BatchID.String()),
+			attribute.String("etl.job_name", job.JobName),
+			attribute.String("etl.job_type", job.JobType),
+			attribute.String("etl.correlation_id", job.CorrelationID.String()),
+			attribute.Int("etl.priority", job.Priority),
+			attribute.Bool("etl.dry_run", job.DryRun),
+		))
+	defer span.End()
+	defer p.observeRun(job, span, time.Now())
+
+	// Tag the run's log entries with its correlation ID and minimum level
+	level, _ := logger.ParseLevel(job.LogLevel)
+	p.logger.BeginRun(job.BatchID, logger.RunSettings{MinLevel: level, CorrelationID: job.CorrelationID})
+	defer p.logger.EndRun(job.BatchID)
 
 	// Log job start
 	p.logger.LogJobStart(job.BatchID, job.JobName, job.JobType, job.Parameters)
 	startTime := time.Now()
 
 	// Select handler based on job type
-	var handler JobHandler
-	switch job.JobType {
-	case "historical_load":
-		handler = NewHistoricalLoadJob(p.db, p.logger)
-	case "realtime_sync":
-		handler = NewRealtimeSyncJob(p.db, p.logger)
-	default:
+	jobType, ok := Lookup(job.JobType)
+	if !ok {
 		errMsg := fmt.Sprintf("unknown job type: %s", job.JobType)
 		p.logger.Error(job.BatchID, errMsg)
 		p.db.UpdateJobStatus(job.BatchID, "failed", 0, 0, &errMsg)
-		return fmt.Errorf(errMsg)
+		return errors.New(errMsg)
+	}
+
+	// A backfill parent is split into child runs that workers pick up in parallel
+	if job.ShardBy != "" {
+		return p.planShards(ctx, jobType, job)
 	}
 
-	// Execute the job
-	if err := handler.Execute(ctx, job); err != nil {
+	// Dry runs go through the same handler but collect a report instead of writing
+	var sink ValueSink = NewDBSink(p.db)
+	var dryRun *DryRunSink
+	if job.DryRun {
+		dryRun = NewDryRunSink(p.db)
+		sink = dryRun
+		p.logger.Info(job.BatchID, "Dry run: no values or rejected records will be written")
+	}
+	handler := jobType.New(p.db, p.jobLogger, sink)
+
+	// Execute the job under its deadline. Worker shutdown does not cancel
+	// the run: streaming runs requeue themselves, other runs get the
+	// shutdown grace period to finish before StopRuns requeues them.
+	timeout := runTimeout(jobType, job)
+	runCtx, stopRun := p.runContext(ctx)
+	defer stopRun()
+	cancelRun := context.CancelFunc(func() {})
+	if timeout > 0 {
+		runCtx, cancelRun = context.WithTimeout(runCtx, timeout)
+	}
+	defer cancelRun()
+	stopWatch := p.watchDeadline(runCtx, job, timeout)
+	cancelled, stopCancelWatch := p.watchCancellation(runCtx, job, cancelRun)
+
+	err = handler.Execute(runCtx, job)
+	stopWatch()
+	stopCancelWatch()
+	if errors.Is(err, ErrRunRequeued) {
+		return nil
+	}
+	if err != nil && p.runsStopped() {
+		return p.requeueStopped(job)
+	}
+	if dryRun != nil {
+		p.saveDryRunReport(job, dryRun)
+	} else {
+		p.saveWatermark(job, sink)
+	}
+	if cancelled() {
+		// The run keeps the cancelled status set by whoever cancelled it
+		p.logger.Info(job.BatchID, "Run cancelled", map[string]interface{}{
+			"job_name":         job.JobName,
+			"duration_seconds": time.Since(startTime).Seconds(),
+		})
+		return nil
+	}
+	if err != nil && runCtx.Err() == context.DeadlineExceeded {
+		p.failTimedOut(job, timeout, time.Since(startTime))
+		return err
+	}
+	if err != nil {
 		duration := time.Since(startTime)
 		errMsg := err.Error()
 		
@@ -128,6 +228,183 @@ func (p *Processor) ProcessNextJob(ctx context.Context) error {
 	return nil
 }
 
+// observeRun records the run's final status in the worker metrics and on
+// its span. Runs handed back to the queue are not counted.
+func (p *Processor) observeRun(job *db.ETLJob, span trace.Span, claimedAt time.Time) {
+	status, err := p.db.GetRunStatus(job.BatchID)
+	if err != nil {
+		p.logger.Warn(job.BatchID, "Failed to read run status for metrics", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	span.SetAttributes(attribute.String("etl.status", status))
+	if status == "failed" {
+		span.SetStatus(codes.Error, "run failed")
+	}
+	if status == "queued" {
+		return
+	}
+	metrics.ObserveRun(job.JobName, job.JobType, status, time.Since(claimedAt))
+}
+
+// streamingRun reports whether a run streams until stopped. Dry runs make a
+// single pass.
+func streamingRun(job *db.ETLJob) bool {
+	streaming, _ := job.Parameters["streaming"].(bool)
+	return streaming && !job.DryRun
+}
+
+// runTimeout returns the deadline of a run: timeout_seconds from its
+// parameters, otherwise the job type default. Streaming runs have none; they
+// are bounded by stream_duration instead.
+func runTimeout(jobType JobType, job *db.ETLJob) time.Duration {
+	if streamingRun(job) {
+		return 0
+	}
+	if ts, ok := job.Parameters["timeout_seconds"].(float64); ok && ts > 0 {
+		return time.Duration(ts) * time.Second
+	}
+	return jobType.DefaultTimeout
+}
+
+// deadlineWarnings are the fractions of the timeout after which a run logs
+// that it is nearing its deadline
+var deadlineWarnings = []float64{0.8, 0.95}
+
+// watchDeadline logs warnings as the run approaches its deadline. The
+// returned function stops the watch.
+func (p *Processor) watchDeadline(runCtx context.Context, job *db.ETLJob, timeout time.Duration) func() {
+	if timeout <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+
+	go func() {
+		start := time.Now()
+		for _, fraction := range deadlineWarnings {
+			warnAt := time.Duration(float64(timeout) * fraction)
+			timer := time.NewTimer(warnAt - time.Since(start))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-runCtx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				p.logger.Warn(job.BatchID, "Run nearing its deadline", map[string]interface{}{
+					"job_name":          job.JobName,
+					"timeout_seconds":   timeout.Seconds(),
+					"remaining_seconds": (timeout - time.Since(start)).Seconds(),
+				})
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// cancelPollInterval is how often a run's status is checked for cancellation
+const cancelPollInterval = 10 * time.Second
+
+// watchCancellation stops the run when its status is set to cancelled, for
+// example when a newer run replaces it. Streaming runs notice cancellation
+// through their heartbeat instead. It returns whether the run was cancelled
+// and a function that stops the watch.
+func (p *Processor) watchCancellation(runCtx context.Context, job *db.ETLJob, cancelRun context.CancelFunc) (func() bool, func()) {
+	var cancelled atomic.Bool
+	if streamingRun(job) {
+		return cancelled.Load, func() {}
+	}
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				status, err := p.db.GetRunStatus(job.BatchID)
+				if err != nil || status != "cancelled" {
+					continue
+				}
+				cancelled.Store(true)
+				cancelRun()
+				return
+			}
+		}
+	}()
+
+	return cancelled.Load, func() { close(done) }
+}
+
+// failTimedOut marks a run that exceeded its deadline
+func (p *Processor) failTimedOut(job *db.ETLJob, timeout, duration time.Duration) {
+	errMsg := fmt.Sprintf("run exceeded its timeout of %s", timeout)
+	p.logger.Error(job.BatchID, "Run timed out", map[string]interface{}{
+		"job_name":         job.JobName,
+		"timeout_seconds":  timeout.Seconds(),
+		"duration_seconds": duration.Seconds(),
+	})
+	if err := p.db.FailJobRun(job.BatchID, errMsg, "timeout"); err != nil {
+		p.logger.Error(job.BatchID, "Failed to mark run as timed out", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	p.logger.LogJobComplete(job.BatchID, job.JobName, job.RecordsProcessed, job.RecordsFailed, duration)
+}
+
+// requeueStopped hands a run stopped by StopRuns back to the queue, so
+// another worker runs it again
+func (p *Processor) requeueStopped(job *db.ETLJob) error {
+	if err := p.db.RequeueRun(job.BatchID); err != nil {
+		p.logger.Error(job.BatchID, "Failed to requeue stopped run", map[string]interface{}{
+			"job_name": job.JobName,
+			"error":    err.Error(),
+		})
+		return err
+	}
+	p.logger.Warn(job.BatchID, "Worker stopping, run requeued", map[string]interface{}{
+		"job_name": job.JobName,
+	})
+	return nil
+}
+
+// saveDryRunReport stores the dry-run report on the run, including for runs
+// that failed part way
+func (p *Processor) saveDryRunReport(job *db.ETLJob, dryRun *DryRunSink) {
+	report, err := json.Marshal(dryRun.Report())
+	if err == nil {
+		err = p.db.SaveDryRunReport(job.BatchID, report)
+	}
+	if err != nil {
+		p.logger.Error(job.BatchID, "Failed to save dry-run report", map[string]interface{}{
+			"job_name": job.JobName,
+			"error":    err.Error(),
+		})
+	}
+}
+
+// saveWatermark records the latest timestamp the run wrote, including for
+// runs that failed part way, so later runs can pick up from it
+func (p *Processor) saveWatermark(job *db.ETLJob, sink ValueSink) {
+	w, ok := sink.(watermarker)
+	if !ok || w.Watermark().IsZero() {
+		return
+	}
+	if err := p.db.SaveWatermark(job.BatchID, w.Watermark()); err != nil {
+		p.logger.Error(job.BatchID, "Failed to save watermark", map[string]interface{}{
+			"job_name": job.JobName,
+			"error":    err.Error(),
+		})
+	}
+}
+
 // categorizeError determines the type of error for retry logic
 func (p *Processor) categorizeError(err error) ErrorType {
 	errStr := err.Error()
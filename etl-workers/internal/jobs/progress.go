@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/logger"
+)
+
+// progressSaveInterval limits how often progress is written to the run
+const progressSaveInterval = 2 * time.Second
+
+// RunProgress is the structured progress stored on a run
+type RunProgress struct {
+	TotalRecords        int        `json:"total_records"`
+	TotalEstimated      bool       `json:"total_estimated"`
+	RecordsProcessed    int        `json:"records_processed"`
+	RecordsFailed       int        `json:"records_failed"`
+	PercentComplete     float64    `json:"percent_complete"`
+	SeriesTotal         int        `json:"series_total"`
+	SeriesCompleted     int        `json:"series_completed"`
+	SeriesFailed        int        `json:"series_failed"`
+	CurrentSeriesID     *int       `json:"current_series_id,omitempty"`
+	CurrentPage         int        `json:"current_page,omitempty"`
+	ThroughputPerSecond float64    `json:"throughput_per_second"`
+	ETASeconds          *float64   `json:"eta_seconds,omitempty"`
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// ProgressTracker keeps a run's progress up to date while a handler works
+// through its series. The expected total is the sum of the totals reported
+// for the series seen so far, extrapolated to the remaining series.
+type ProgressTracker struct {
+	db     *db.Client
+	logger *logger.ETLLogger
+	job    *db.ETLJob
+
+	started     time.Time
+	lastSaved   time.Time
+	seriesTotal int
+	seriesDone  int
+	seriesFail  int
+	expected    map[int]int
+	processed   int
+	failed      int
+	current     *int
+	page        int
+}
+
+func NewProgressTracker(dbClient *db.Client, logger *logger.ETLLogger, job *db.ETLJob, seriesTotal int) *ProgressTracker {
+	return &ProgressTracker{
+		db:          dbClient,
+		logger:      logger,
+		job:         job,
+		started:     time.Now(),
+		seriesTotal: seriesTotal,
+		expected:    make(map[int]int),
+	}
+}
+
+// StartSeries marks the series being worked on
+func (t *ProgressTracker) StartSeries(seriesID int) {
+	t.current = &seriesID
+	t.page = 0
+	t.save(true)
+}
+
+// SetSeriesTotal records the number of records expected for a series
+func (t *ProgressTracker) SetSeriesTotal(seriesID, total int) {
+	t.expected[seriesID] = total
+}
+
+// Add counts handled records on the current page
+func (t *ProgressTracker) Add(page, processed, failed int) {
+	t.page = page
+	t.processed += processed
+	t.failed += failed
+	t.save(false)
+}
+
+// FinishSeries marks the current series as done
+func (t *ProgressTracker) FinishSeries() {
+	t.seriesDone++
+	t.current = nil
+	t.page = 0
+
+	progress := t.snapshot()
+	t.logger.LogJobProgress(t.job.BatchID, t.job.JobName, progress.RecordsProcessed, progress.RecordsFailed, progress.TotalRecords)
+	t.save(true)
+}
+
+// FailSeries counts the current series as failed. FinishSeries still marks
+// it done.
+func (t *ProgressTracker) FailSeries() {
+	t.seriesFail++
+}
+
+// FailedSeries returns the number of series that failed so far
+func (t *ProgressTracker) FailedSeries() int {
+	return t.seriesFail
+}
+
+// Counts returns the records processed and failed so far
+func (t *ProgressTracker) Counts() (processed, failed int) {
+	return t.processed, t.failed
+}
+
+func (t *ProgressTracker) snapshot() RunProgress {
+	now := time.Now()
+	handled := t.processed + t.failed
+
+	known := 0
+	for _, total := range t.expected {
+		known += total
+	}
+	total := known
+	estimated := false
+	if len(t.expected) < t.seriesTotal {
+		estimated = true
+		if len(t.expected) > 0 {
+			total = known + known/len(t.expected)*(t.seriesTotal-len(t.expected))
+		}
+	}
+	if total < handled {
+		total = handled
+	}
+
+	progress := RunProgress{
+		TotalRecords:     total,
+		TotalEstimated:   estimated,
+		RecordsProcessed: t.processed,
+		RecordsFailed:    t.failed,
+		SeriesTotal:      t.seriesTotal,
+		SeriesCompleted:  t.seriesDone,
+		SeriesFailed:     t.seriesFail,
+		CurrentSeriesID:  t.current,
+		CurrentPage:      t.page,
+		UpdatedAt:        now,
+	}
+
+	if total > 0 {
+		progress.PercentComplete = float64(handled) / float64(total) * 100
+	} else if t.seriesTotal > 0 {
+		progress.PercentComplete = float64(t.seriesDone) / float64(t.seriesTotal) * 100
+	}
+
+	if elapsed := now.Sub(t.started).Seconds(); elapsed > 0 {
+		progress.ThroughputPerSecond = float64(handled) / elapsed
+	}
+	if progress.ThroughputPerSecond > 0 && total > handled {
+		eta := float64(total-handled) / progress.ThroughputPerSecond
+		completion := now.Add(time.Duration(eta * float64(time.Second)))
+		progress.ETASeconds = &eta
+		progress.EstimatedCompletion = &completion
+	}
+
+	return progress
+}
+
+// save writes the progress to the run, at most every progressSaveInterval
+// unless force is set
+func (t *ProgressTracker) save(force bool) {
+	if !force && time.Since(t.lastSaved) < progressSaveInterval {
+		return
+	}
+	t.lastSaved = time.Now()
+
+	progressJSON, err := json.Marshal(t.snapshot())
+	if err == nil {
+		err = t.db.UpdateRunProgress(t.job.BatchID, t.processed, t.failed, progressJSON)
+	}
+	if err != nil {
+		t.logger.Warn(t.job.BatchID, "Failed to save run progress", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
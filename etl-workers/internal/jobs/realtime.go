@@ -6,20 +6,64 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/aquaflow/etl-workers/internal/db"
 	"github.com/aquaflow/etl-workers/internal/logger"
+	"github.com/aquaflow/etl-workers/internal/metrics"
+	"github.com/aquaflow/etl-workers/internal/tracing"
 )
 
+// realtimeSyncSchema is the JSON Schema published for realtime_sync parameters
+const realtimeSyncSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["source_url"],
+	"anyOf": [{"required": ["series_ids"]}, {"required": ["series_selector"]}],
+	"properties": {
+		"source_url": {"type": "string", "format": "uri"},
+		"series_ids": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "integer", "minimum": 1}
+		},
+		"series_selector": ` + seriesSelectorSchema + `,
+		"sync_interval": {"type": "integer", "minimum": 1},
+		"streaming": {"type": "boolean"},
+		"stream_duration": {"type": "integer", "minimum": 1},
+		"stats_interval": {"type": "integer", "minimum": 1},
+		"timeout_seconds": {"type": "integer", "minimum": 1},
+		"batch_size": {"type": "integer", "minimum": 1}
+	},
+	"$defs": ` + seriesSelectorDefs + `
+}`
+
+// realtimeSourceClient records source latency and errors under this job type
+var realtimeSourceClient = metrics.SourceClient("realtime_sync")
+
+func init() {
+	Register(JobType{
+		Name:            "realtime_sync",
+		Description:     "Fetch of the latest value for each series",
+		ParameterSchema: json.RawMessage(realtimeSyncSchema),
+		DefaultTimeout:  5 * time.Minute,
+		New: func(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) JobHandler {
+			return NewRealtimeSyncJob(dbClient, logger, sink)
+		},
+	})
+}
+
 type RealtimeSyncJob struct {
 	db     *db.Client
 	logger *logger.ETLLogger
+	sink   ValueSink
 }
 
-func NewRealtimeSyncJob(dbClient *db.Client, logger *logger.ETLLogger) *RealtimeSyncJob {
+func NewRealtimeSyncJob(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) *RealtimeSyncJob {
 	return &RealtimeSyncJob{
 		db:     dbClient,
 		logger: logger,
+		sink:   sink,
 	}
 }
 
@@ -34,22 +78,23 @@ func (r *RealtimeSyncJob) Execute(ctx context.Context, job *db.ETLJob) error {
 		return fmt.Errorf("missing or invalid source_url parameter")
 	}
 
-	seriesIDsRaw, ok := job.Parameters["series_ids"].([]interface{})
-	if !ok {
-		return fmt.Errorf("missing or invalid series_ids parameter")
-	}
-
 	syncInterval := 30
 	if si, ok := job.Parameters["sync_interval"].(float64); ok {
 		syncInterval = int(si)
 	}
 
-	// Convert series IDs to int slice
-	var seriesIDs []int
-	for _, id := range seriesIDsRaw {
-		if fid, ok := id.(float64); ok {
-			seriesIDs = append(seriesIDs, int(fid))
-		}
+	// Resolve series_ids and series_selector against the current catalogue
+	seriesIDs, err := resolveSeries(ctx, r.db, job)
+	if err != nil {
+		return err
+	}
+	r.logger.Info(job.BatchID, "Resolved series", map[string]interface{}{
+		"series_ids": seriesIDs,
+	})
+
+	// Streaming runs keep polling until cancelled; dry runs always do a single pass
+	if streaming, _ := job.Parameters["streaming"].(bool); streaming && !job.DryRun {
+		return r.stream(ctx, job, sourceURL, seriesIDs, time.Duration(syncInterval)*time.Second)
 	}
 
 	totalProcessed := 0
@@ -57,7 +102,10 @@ func (r *RealtimeSyncJob) Execute(ctx context.Context, job *db.ETLJob) error {
 
 	// Single sync cycle for all series
 	for _, seriesID := range seriesIDs {
-		if err := r.syncSeriesData(ctx, job, sourceURL, seriesID); err != nil {
+		seriesCtx, seriesSpan := tracing.StartSeries(ctx, seriesID)
+		err := r.syncSeriesData(seriesCtx, job, sourceURL, seriesID)
+		tracing.EndSpan(seriesSpan, err)
+		if err != nil {
 			r.logger.Errorf(job.BatchID, "Failed to sync series %d: %v", seriesID, err)
 			totalFailed++
 		} else {
@@ -84,66 +132,84 @@ func (r *RealtimeSyncJob) Execute(ctx context.Context, job *db.ETLJob) error {
 		"sync_interval":   syncInterval,
 	})
 
-	// Update the next run time if this is a scheduled job
-	if job.Parameters["schedule"] != nil {
-		r.logger.Info(job.BatchID, "Scheduling next run", map[string]interface{}{
-			"next_run_seconds": syncInterval,
-		})
-	}
-
 	return r.db.UpdateJobStatus(job.BatchID, status, totalProcessed, totalFailed, nil)
 }
 
 func (r *RealtimeSyncJob) syncSeriesData(ctx context.Context, job *db.ETLJob, baseURL string, seriesID int) error {
+	r.logger.Debug(job.BatchID, "Fetching realtime data", map[string]interface{}{
+		"source_url": baseURL,
+		"series_id":  seriesID,
+	})
+
+	value, rejection, err := r.fetchLatest(ctx, job, baseURL, seriesID)
+	if err != nil {
+		return err
+	}
+
+	// Validate and insert single value, keeping it in the dead-letter store if rejected
+	if rejection == nil {
+		rejection = r.writeValue(value)
+	}
+	if rejection != nil {
+		if err := r.sink.Reject(job.BatchID, []db.Rejection{*rejection}); err != nil {
+			r.logger.Errorf(job.BatchID, "Failed to store rejected record for series %d: %v", seriesID, err)
+		}
+		return fmt.Errorf("value rejected (%s): %s", rejection.Category, rejection.Reason)
+	}
+
+	r.logger.Debug(job.BatchID, "Inserted realtime value", map[string]interface{}{
+		"series_id": seriesID,
+		"value":     value.Value,
+		"timestamp": value.Timestamp,
+	})
+
+	return nil
+}
+
+// fetchLatest fetches the latest value of a series. Records that fail
+// validation come back as a rejection; the error is only set when the
+// source could not be read.
+func (r *RealtimeSyncJob) fetchLatest(ctx context.Context, job *db.ETLJob, baseURL string, seriesID int) (db.NumericValue, *db.Rejection, error) {
 	// Build URL with series_id parameter
 	u, _ := url.Parse(baseURL)
 	q := u.Query()
 	q.Set("series_id", fmt.Sprintf("%d", seriesID))
 	u.RawQuery = q.Encode()
 
-	r.logger.Debug(job.BatchID, "Fetching realtime data", map[string]interface{}{
-		"url":       u.String(),
-		"series_id": seriesID,
-	})
-
 	// Fetch data
 	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
-		return err
+		return db.NumericValue{}, nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := realtimeSourceClient.Do(req)
 	if err != nil {
-		return err
+		return db.NumericValue{}, nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+		return db.NumericValue{}, nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
 	// Parse response
-	var dataPoint DataPoint
-	if err := json.NewDecoder(resp.Body).Decode(&dataPoint); err != nil {
-		return err
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return db.NumericValue{}, nil, err
 	}
 
-	// Insert single value
-	values := []db.NumericValue{{
-		Timestamp: dataPoint.Timestamp,
-		SeriesID:  dataPoint.SeriesID,
-		Value:     dataPoint.Value,
-	}}
+	value, rejection := toNumericValue(raw, seriesID)
+	return value, rejection, nil
+}
 
-	if err := r.db.InsertNumericValues(values); err != nil {
-		return fmt.Errorf("failed to insert value: %w", err)
+// writeValue writes a single value and returns its rejection if it was refused
+func (r *RealtimeSyncJob) writeValue(value db.NumericValue) *db.Rejection {
+	rejections, err := r.sink.Write([]db.NumericValue{value})
+	if err != nil {
+		rejections = rejectAll([]db.NumericValue{value}, err)
+	}
+	if len(rejections) > 0 {
+		return &rejections[0]
 	}
-
-	r.logger.Debug(job.BatchID, "Inserted realtime value", map[string]interface{}{
-		"series_id": seriesID,
-		"value":     dataPoint.Value,
-		"timestamp": dataPoint.Timestamp,
-	})
-
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,319 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+)
+
+const (
+	// streamHeartbeatInterval must stay well below db.StaleHeartbeatAfter
+	streamHeartbeatInterval = 30 * time.Second
+	defaultStatsInterval    = 5 * time.Minute
+)
+
+// streamCheckpoint is the resume state of a streaming run. RecordsFailed
+// counts rejected readings; FetchErrors counts fetches that failed and so
+// produced no reading.
+type streamCheckpoint struct {
+	StreamStartedAt  time.Time         `json:"stream_started_at"`
+	Cycles           int               `json:"cycles"`
+	RecordsProcessed int               `json:"records_processed"`
+	RecordsFailed    int               `json:"records_failed"`
+	FetchErrors      int               `json:"fetch_errors"`
+	LastTimestamps   map[int]time.Time `json:"last_timestamps"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// streamStats are the counters of one stats window
+type streamStats struct {
+	started     time.Time
+	cycles      int
+	written     int
+	unchanged   int
+	rejected    int
+	fetchErrors int
+	cycleTime   time.Duration
+}
+
+// stream keeps the run active and syncs all series every interval. It
+// heartbeats and checkpoints the run, logs rolled-up statistics every
+// stats_interval instead of a line per value, and stops when the run is
+// cancelled or stream_duration has passed. On worker shutdown the run is
+// requeued so another worker can resume it from the checkpoint.
+func (r *RealtimeSyncJob) stream(ctx context.Context, job *db.ETLJob, sourceURL string, seriesIDs []int, interval time.Duration) error {
+	ctx, stop := untilShutdown(ctx)
+	defer stop()
+
+	statsInterval := defaultStatsInterval
+	if si, ok := job.Parameters["stats_interval"].(float64); ok {
+		statsInterval = time.Duration(si) * time.Second
+	}
+
+	checkpoint := r.loadCheckpoint(job)
+
+	// Without stream_duration the run streams until cancelled
+	var deadline <-chan time.Time
+	if sd, ok := job.Parameters["stream_duration"].(float64); ok {
+		timer := time.NewTimer(time.Until(checkpoint.StreamStartedAt.Add(time.Duration(sd) * time.Second)))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	r.logger.Info(job.BatchID, "Starting streaming sync", map[string]interface{}{
+		"series_count":   len(seriesIDs),
+		"sync_interval":  interval.Seconds(),
+		"stats_interval": statsInterval.Seconds(),
+		"resumed_cycles": checkpoint.Cycles,
+	})
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	statsTicker := time.NewTicker(statsInterval)
+	defer statsTicker.Stop()
+	cycleTimer := time.NewTimer(0)
+	defer cycleTimer.Stop()
+
+	window := &streamStats{started: time.Now()}
+	failing := make(map[int]string)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logStats(job, window, checkpoint, failing)
+			r.heartbeat(job, checkpoint)
+			if err := r.db.RequeueRun(job.BatchID); err != nil {
+				r.logger.Errorf(job.BatchID, "Failed to requeue streaming run: %v", err)
+				return ctx.Err()
+			}
+			r.logger.Info(job.BatchID, "Worker stopping, streaming run requeued", map[string]interface{}{
+				"cycles": checkpoint.Cycles,
+			})
+			return ErrRunRequeued
+
+		case <-deadline:
+			r.logStats(job, window, checkpoint, failing)
+			r.heartbeat(job, checkpoint)
+			status := "completed"
+			if checkpoint.RecordsFailed > 0 || checkpoint.FetchErrors > 0 {
+				status = "completed_with_errors"
+			}
+			r.logger.Info(job.BatchID, "Streaming sync completed", map[string]interface{}{
+				"cycles":             checkpoint.Cycles,
+				"total_processed":    checkpoint.RecordsProcessed,
+				"total_failed":       checkpoint.RecordsFailed,
+				"total_fetch_errors": checkpoint.FetchErrors,
+			})
+			return r.db.UpdateJobStatus(job.BatchID, status, checkpoint.RecordsProcessed, checkpoint.RecordsFailed, nil)
+
+		case <-heartbeat.C:
+			if r.heartbeat(job, checkpoint) == "cancelled" {
+				return r.stopCancelled(job, window, checkpoint, failing)
+			}
+
+		case <-statsTicker.C:
+			r.logStats(job, window, checkpoint, failing)
+			window = &streamStats{started: time.Now()}
+
+		case <-cycleTimer.C:
+			cycleStart := time.Now()
+			r.streamCycle(ctx, job, sourceURL, seriesIDs, checkpoint, window, failing)
+			elapsed := time.Since(cycleStart)
+			window.cycles++
+			window.cycleTime += elapsed
+			checkpoint.Cycles++
+
+			if r.heartbeat(job, checkpoint) == "cancelled" {
+				return r.stopCancelled(job, window, checkpoint, failing)
+			}
+
+			next := interval - elapsed
+			if next < 0 {
+				next = 0
+			}
+			cycleTimer.Reset(next)
+		}
+	}
+}
+
+// streamCycle syncs every series once. Values whose timestamp is not newer
+// than the checkpoint are counted as unchanged and not written again.
+func (r *RealtimeSyncJob) streamCycle(ctx context.Context, job *db.ETLJob, sourceURL string, seriesIDs []int, checkpoint *streamCheckpoint, window *streamStats, failing map[int]string) {
+	for _, seriesID := range seriesIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		value, rejection, err := r.fetchLatest(ctx, job, sourceURL, seriesID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			window.fetchErrors++
+			checkpoint.FetchErrors++
+			r.markFailing(job, failing, seriesID, err.Error())
+			continue
+		}
+
+		if last, ok := checkpoint.LastTimestamps[seriesID]; ok && !value.Timestamp.IsZero() && !value.Timestamp.After(last) {
+			window.unchanged++
+			continue
+		}
+
+		if rejection == nil {
+			rejection = r.writeValue(value)
+		}
+		if rejection != nil {
+			window.rejected++
+			checkpoint.RecordsFailed++
+			newFailure := r.markFailing(job, failing, seriesID, rejection.Reason)
+
+			// A reading with a timestamp is only seen once thanks to the checkpoint;
+			// unparseable responses are stored when the failure starts or changes
+			if !value.Timestamp.IsZero() {
+				checkpoint.LastTimestamps[seriesID] = value.Timestamp
+			}
+			if newFailure || !value.Timestamp.IsZero() {
+				if err := r.sink.Reject(job.BatchID, []db.Rejection{*rejection}); err != nil {
+					r.logger.Errorf(job.BatchID, "Failed to store rejected record for series %d: %v", seriesID, err)
+				}
+			}
+			continue
+		}
+
+		checkpoint.LastTimestamps[seriesID] = value.Timestamp
+		window.written++
+		checkpoint.RecordsProcessed++
+		r.markHealthy(job, failing, seriesID)
+	}
+}
+
+// markFailing records a failing series and logs the transition. It returns
+// true when the series was healthy before or failed for a different reason.
+func (r *RealtimeSyncJob) markFailing(job *db.ETLJob, failing map[int]string, seriesID int, reason string) bool {
+	if previous, ok := failing[seriesID]; ok && previous == reason {
+		return false
+	}
+	failing[seriesID] = reason
+	r.logger.Warn(job.BatchID, "Series sync failing", map[string]interface{}{
+		"series_id": seriesID,
+		"reason":    reason,
+	})
+	return true
+}
+
+func (r *RealtimeSyncJob) markHealthy(job *db.ETLJob, failing map[int]string, seriesID int) {
+	if _, ok := failing[seriesID]; !ok {
+		return
+	}
+	delete(failing, seriesID)
+	r.logger.Info(job.BatchID, "Series sync recovered", map[string]interface{}{
+		"series_id": seriesID,
+	})
+}
+
+// heartbeat stores the checkpoint and running totals and returns the run
+// status, or an empty string if the database could not be reached
+func (r *RealtimeSyncJob) heartbeat(job *db.ETLJob, checkpoint *streamCheckpoint) string {
+	checkpoint.UpdatedAt = time.Now()
+	checkpointJSON, err := json.Marshal(checkpoint)
+	if err != nil {
+		r.logger.Errorf(job.BatchID, "Failed to encode checkpoint: %v", err)
+		checkpointJSON = nil
+	}
+
+	status, err := r.db.RecordHeartbeat(job.BatchID, checkpoint.RecordsProcessed, checkpoint.RecordsFailed, checkpointJSON)
+	if err != nil {
+		r.logger.Warn(job.BatchID, "Failed to record heartbeat", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return ""
+	}
+	return status
+}
+
+// loadCheckpoint restores the checkpoint of a reclaimed or requeued run
+func (r *RealtimeSyncJob) loadCheckpoint(job *db.ETLJob) *streamCheckpoint {
+	checkpoint := &streamCheckpoint{StreamStartedAt: time.Now(), LastTimestamps: make(map[int]time.Time)}
+
+	raw, err := r.db.GetRunCheckpoint(job.BatchID)
+	if err != nil {
+		r.logger.Warn(job.BatchID, "Failed to load checkpoint, starting fresh", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return checkpoint
+	}
+	if len(raw) == 0 {
+		return checkpoint
+	}
+	if err := json.Unmarshal(raw, checkpoint); err != nil {
+		r.logger.Warn(job.BatchID, "Ignoring unreadable checkpoint", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return &streamCheckpoint{StreamStartedAt: time.Now(), LastTimestamps: make(map[int]time.Time)}
+	}
+	if checkpoint.StreamStartedAt.IsZero() {
+		checkpoint.StreamStartedAt = time.Now()
+	}
+	if checkpoint.LastTimestamps == nil {
+		checkpoint.LastTimestamps = make(map[int]time.Time)
+	}
+	return checkpoint
+}
+
+// logStats writes one rolled-up statistics entry for the current window
+func (r *RealtimeSyncJob) logStats(job *db.ETLJob, window *streamStats, checkpoint *streamCheckpoint, failing map[int]string) {
+	if window.cycles == 0 && window.fetchErrors == 0 {
+		return
+	}
+
+	avgCycle := time.Duration(0)
+	if window.cycles > 0 {
+		avgCycle = window.cycleTime / time.Duration(window.cycles)
+	}
+
+	failingSeries := make([]int, 0, len(failing))
+	for seriesID := range failing {
+		failingSeries = append(failingSeries, seriesID)
+	}
+	sort.Ints(failingSeries)
+
+	// Lag of the stalest series tells whether the freshness target is met
+	var maxLag time.Duration
+	for _, ts := range checkpoint.LastTimestamps {
+		if lag := time.Since(ts); lag > maxLag {
+			maxLag = lag
+		}
+	}
+
+	r.logger.Info(job.BatchID, "Streaming sync stats", map[string]interface{}{
+		"window_seconds":     int(time.Since(window.started).Seconds()),
+		"cycles":             window.cycles,
+		"values_written":     window.written,
+		"values_unchanged":   window.unchanged,
+		"values_rejected":    window.rejected,
+		"fetch_errors":       window.fetchErrors,
+		"avg_cycle_ms":       avgCycle.Milliseconds(),
+		"max_lag_seconds":    int(maxLag.Seconds()),
+		"failing_series":     failingSeries,
+		"total_processed":    checkpoint.RecordsProcessed,
+		"total_failed":       checkpoint.RecordsFailed,
+		"total_fetch_errors": checkpoint.FetchErrors,
+	})
+}
+
+// stopCancelled ends a streaming run that was cancelled; the run keeps its
+// cancelled status
+func (r *RealtimeSyncJob) stopCancelled(job *db.ETLJob, window *streamStats, checkpoint *streamCheckpoint, failing map[int]string) error {
+	r.logStats(job, window, checkpoint, failing)
+	r.logger.Info(job.BatchID, "Streaming run cancelled", map[string]interface{}{
+		"cycles":             checkpoint.Cycles,
+		"total_processed":    checkpoint.RecordsProcessed,
+		"total_failed":       checkpoint.RecordsFailed,
+		"total_fetch_errors": checkpoint.FetchErrors,
+	})
+	return nil
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+)
+
+// toNumericValue decodes and validates a single source record. Records that
+// cannot be used are returned as a rejection carrying the raw payload.
+func toNumericValue(raw json.RawMessage, expectedSeriesID int) (db.NumericValue, *db.Rejection) {
+	var dp DataPoint
+	if err := json.Unmarshal(raw, &dp); err != nil {
+		return db.NumericValue{}, &db.Rejection{
+			Value:    db.NumericValue{SeriesID: expectedSeriesID, Payload: raw},
+			Reason:   fmt.Sprintf("failed to parse record: %v", err),
+			Category: db.RejectParse,
+		}
+	}
+
+	value := db.NumericValue{
+		Timestamp: dp.Timestamp,
+		SeriesID:  dp.SeriesID,
+		Value:     dp.Value,
+		Payload:   raw,
+	}
+
+	var reason string
+	switch {
+	case dp.Timestamp.IsZero():
+		reason = "missing timestamp"
+	case math.IsNaN(dp.Value) || math.IsInf(dp.Value, 0):
+		reason = fmt.Sprintf("value %v is not a finite number", dp.Value)
+	case expectedSeriesID != 0 && dp.SeriesID != expectedSeriesID:
+		reason = fmt.Sprintf("record is for series %d, expected %d", dp.SeriesID, expectedSeriesID)
+	}
+	if reason != "" {
+		return value, &db.Rejection{Value: value, Reason: reason, Category: db.RejectValidation}
+	}
+
+	return value, nil
+}
+
+// rejectAll turns every value of a batch that could not be written into a rejection
+func rejectAll(values []db.NumericValue, err error) []db.Rejection {
+	rejections := make([]db.Rejection, 0, len(values))
+	for _, v := range values {
+		rejections = append(rejections, db.Rejection{
+			Value:    v,
+			Reason:   err.Error(),
+			Category: db.CategorizeInsertError(err),
+		})
+	}
+	return rejections
+}
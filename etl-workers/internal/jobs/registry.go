@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/logger"
+)
+
+// JobType describes a job type this worker can execute
+type JobType struct {
+	Name            string
+	Description     string
+	ParameterSchema json.RawMessage
+	// DefaultTimeout is the run deadline when timeout_seconds is not set
+	DefaultTimeout time.Duration
+	// ShardModes lists how backfills of this type may be split: "month"
+	// needs start_date and end_date, "series" needs series parameters
+	ShardModes []string
+	New        func(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) JobHandler
+}
+
+var registry = map[string]JobType{}
+
+// Register makes a job type available to the processor
+func Register(jobType JobType) {
+	registry[jobType.Name] = jobType
+}
+
+// Lookup returns the registered job type with the given name
+func Lookup(name string) (JobType, bool) {
+	jobType, ok := registry[name]
+	return jobType, ok
+}
+
+// RegisteredJobTypes returns all registered job types ordered by name
+func RegisteredJobTypes() []JobType {
+	types := make([]JobType, 0, len(registry))
+	for _, jobType := range registry {
+		types = append(types, jobType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// PublishJobTypes stores the parameter schema of every registered job type
+// so the backend and scheduler can validate parameters before a run starts
+func PublishJobTypes(dbClient *db.Client) error {
+	for _, jobType := range RegisteredJobTypes() {
+		if err := dbClient.UpsertJobType(jobType.Name, jobType.Description, jobType.ParameterSchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
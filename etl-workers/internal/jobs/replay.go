@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/logger"
+)
+
+// replayRejectedSchema is the JSON Schema published for replay_rejected parameters
+const replayRejectedSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["record_ids"],
+	"properties": {
+		"record_ids": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "integer", "minimum": 1}
+		},
+		"timeout_seconds": {"type": "integer", "minimum": 1}
+	}
+}`
+
+func init() {
+	Register(JobType{
+		Name:            "replay_rejected",
+		Description:     "Re-insert rejected records from the dead-letter store",
+		ParameterSchema: json.RawMessage(replayRejectedSchema),
+		DefaultTimeout:  30 * time.Minute,
+		New: func(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) JobHandler {
+			return NewReplayRejectedJob(dbClient, logger, sink)
+		},
+	})
+}
+
+// ReplayRejectedJob re-runs dead-letter records through the normal
+// validation and insert path
+type ReplayRejectedJob struct {
+	db     *db.Client
+	logger *logger.ETLLogger
+	sink   ValueSink
+}
+
+func NewReplayRejectedJob(dbClient *db.Client, logger *logger.ETLLogger, sink ValueSink) *ReplayRejectedJob {
+	return &ReplayRejectedJob{
+		db:     dbClient,
+		logger: logger,
+		sink:   sink,
+	}
+}
+
+func (r *ReplayRejectedJob) Execute(ctx context.Context, job *db.ETLJob) error {
+	recordIDsRaw, ok := job.Parameters["record_ids"].([]interface{})
+	if !ok {
+		return fmt.Errorf("missing or invalid record_ids parameter")
+	}
+
+	ids, err := parseIntList("record_ids", recordIDsRaw)
+	if err != nil {
+		return err
+	}
+	recordIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		recordIDs = append(recordIDs, int64(id))
+	}
+
+	records, err := r.db.GetPendingRejectedRecords(recordIDs)
+	if err != nil {
+		return err
+	}
+
+	r.logger.Info(job.BatchID, "Starting rejected record replay", map[string]interface{}{
+		"requested": len(recordIDs),
+		"pending":   len(records),
+	})
+
+	totalProcessed := 0
+	totalFailed := 0
+
+	for _, record := range records {
+		expectedSeriesID := 0
+		if record.SeriesID != nil {
+			expectedSeriesID = *record.SeriesID
+		}
+
+		value, rejection := toNumericValue(record.RawPayload, expectedSeriesID)
+		if rejection == nil {
+			rejections, err := r.sink.Write([]db.NumericValue{value})
+			if err != nil {
+				rejections = rejectAll([]db.NumericValue{value}, err)
+			}
+			if len(rejections) > 0 {
+				rejection = &rejections[0]
+			}
+		}
+
+		if job.DryRun {
+			// Only report the outcome; the dead-letter records stay pending
+			if rejection != nil {
+				totalFailed++
+				if err := r.sink.Reject(job.BatchID, []db.Rejection{*rejection}); err != nil {
+					r.logger.Errorf(job.BatchID, "Failed to report rejected record %d: %v", record.RecordID, err)
+				}
+			} else {
+				totalProcessed++
+			}
+		} else if rejection != nil {
+			totalFailed++
+			if err := r.db.MarkRejectedRecordReplayFailed(record.RecordID, job.BatchID, rejection.Reason, rejection.Category); err != nil {
+				return fmt.Errorf("failed to update rejected record %d: %w", record.RecordID, err)
+			}
+			r.logger.WithComponent(logger.ComponentValidator).Warn(job.BatchID, "Replay rejected again", map[string]interface{}{
+				"record_id": record.RecordID,
+				"series_id": expectedSeriesID,
+				"category":  rejection.Category,
+				"reason":    rejection.Reason,
+			})
+		} else {
+			totalProcessed++
+			if err := r.db.MarkRejectedRecordReplayed(record.RecordID, job.BatchID); err != nil {
+				return fmt.Errorf("failed to update rejected record %d: %w", record.RecordID, err)
+			}
+		}
+
+		// Check context cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+
+	status := "completed"
+	if totalFailed > 0 {
+		status = "completed_with_errors"
+	}
+
+	r.logger.Info(job.BatchID, "Rejected record replay completed", map[string]interface{}{
+		"total_processed": totalProcessed,
+		"total_failed":    totalFailed,
+		"skipped":         len(recordIDs) - len(records),
+	})
+
+	return r.db.UpdateJobStatus(job.BatchID, status, totalProcessed, totalFailed, nil)
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+)
+
+// seriesSelectorSchema is shared by job types that accept series_selector
+const seriesSelectorSchema = `{
+	"oneOf": [
+		{"$ref": "#/$defs/selector"},
+		{"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/selector"}}
+	]
+}`
+
+// seriesSelectorDefs holds the selector definition referenced above
+const seriesSelectorDefs = `{
+	"selector": {
+		"type": "object",
+		"minProperties": 1,
+		"additionalProperties": false,
+		"properties": {
+			"dataset": {"type": "string", "minLength": 1},
+			"parameter": {"type": "string", "minLength": 1},
+			"dimensions": {"type": "object", "additionalProperties": {"type": "string"}},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}
+}`
+
+// resolveSeries combines explicit series_ids with any series_selector,
+// resolving selectors against the current series catalogue. The resolved list
+// is recorded on the run so it is clear afterwards what was loaded.
+func resolveSeries(ctx context.Context, dbClient *db.Client, job *db.ETLJob) ([]int, error) {
+	seen := make(map[int]bool)
+
+	if raw, exists := job.Parameters["series_ids"]; exists {
+		seriesIDsRaw, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid series_ids parameter: expected an array")
+		}
+		seriesIDs, err := parseIntList("series_ids", seriesIDsRaw)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range seriesIDs {
+			seen[id] = true
+		}
+	}
+
+	if raw, exists := job.Parameters["series_selector"]; exists {
+		selectors, err := parseSeriesSelectors(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, selector := range selectors {
+			seriesIDs, err := dbClient.ResolveSeriesSelector(ctx, selector)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range seriesIDs {
+				seen[id] = true
+			}
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("invalid series parameters: series_ids and series_selector matched no series")
+	}
+
+	resolved := make([]int, 0, len(seen))
+	for id := range seen {
+		resolved = append(resolved, id)
+	}
+	sort.Ints(resolved)
+
+	if err := dbClient.SetResolvedSeries(job.BatchID, resolved); err != nil {
+		return nil, fmt.Errorf("failed to record resolved series: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// parseSeriesSelectors accepts a single selector object or an array of them
+func parseSeriesSelectors(raw interface{}) ([]db.SeriesSelector, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid series_selector parameter: %w", err)
+	}
+
+	if _, isObject := raw.(map[string]interface{}); isObject {
+		var selector db.SeriesSelector
+		if err := json.Unmarshal(data, &selector); err != nil {
+			return nil, fmt.Errorf("invalid series_selector parameter: %w", err)
+		}
+		return []db.SeriesSelector{selector}, nil
+	}
+
+	var selectors []db.SeriesSelector
+	if err := json.Unmarshal(data, &selectors); err != nil {
+		return nil, fmt.Errorf("invalid series_selector parameter: %w", err)
+	}
+	return selectors, nil
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/tracing"
+)
+
+// shardDateLayout is the format of start_date and end_date
+const shardDateLayout = "2006-01-02"
+
+// planShards splits a backfill parent into child runs. The parent stays
+// running; its status and counts are rolled up from the children by the
+// database as they finish.
+func (p *Processor) planShards(ctx context.Context, jobType JobType, job *db.ETLJob) error {
+	shards, err := splitBackfill(ctx, p.db, jobType, job)
+	if err == nil && len(shards) == 0 {
+		err = fmt.Errorf("backfill produced no shards")
+	}
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to split backfill: %v", err)
+		p.logger.Error(job.BatchID, "Failed to split backfill", map[string]interface{}{
+			"shard_by": job.ShardBy,
+			"error":    err.Error(),
+		})
+		if dbErr := p.db.FailJobRun(job.BatchID, errMsg, "configuration"); dbErr != nil {
+			p.logger.Errorf(job.BatchID, "Failed to mark backfill as failed: %v", dbErr)
+		}
+		return errors.New(errMsg)
+	}
+
+	runIDs, err := p.db.CreateShardRuns(job.BatchID, shards, tracing.Inject(ctx))
+	if err != nil {
+		errMsg := err.Error()
+		p.logger.Error(job.BatchID, "Failed to create shard runs", map[string]interface{}{
+			"error": errMsg,
+		})
+		if dbErr := p.db.FailJobRun(job.BatchID, errMsg, "system"); dbErr != nil {
+			p.logger.Errorf(job.BatchID, "Failed to mark backfill as failed: %v", dbErr)
+		}
+		return err
+	}
+
+	keys := make([]string, len(shards))
+	for i, shard := range shards {
+		keys[i] = shard.Key
+	}
+	p.logger.Info(job.BatchID, "Backfill split into shards", map[string]interface{}{
+		"shard_by":    job.ShardBy,
+		"shard_count": len(shards),
+		"shards":      keys,
+		"run_ids":     runIDs,
+	})
+	return nil
+}
+
+// splitBackfill returns the shards of a backfill parent
+func splitBackfill(ctx context.Context, dbClient *db.Client, jobType JobType, job *db.ETLJob) ([]db.Shard, error) {
+	supported := false
+	for _, mode := range jobType.ShardModes {
+		if mode == job.ShardBy {
+			supported = true
+		}
+	}
+	if !supported {
+		return nil, fmt.Errorf("job type %s cannot be sharded by %s", job.JobType, job.ShardBy)
+	}
+
+	switch job.ShardBy {
+	case "month":
+		return shardByMonth(job.Parameters)
+	case "series":
+		seriesIDs, err := resolveSeries(ctx, dbClient, job)
+		if err != nil {
+			return nil, err
+		}
+		if err := dbClient.SetResolvedSeries(job.BatchID, seriesIDs); err != nil {
+			return nil, err
+		}
+		return shardBySeries(job.Parameters, seriesIDs), nil
+	}
+	return nil, fmt.Errorf("unknown shard_by %q", job.ShardBy)
+}
+
+// shardByMonth splits start_date..end_date into calendar months, clipping
+// the first and last month to the range. The source treats end_date as
+// exclusive, so each shard but the last ends on the first of the next month
+// and the last keeps the requested end_date.
+func shardByMonth(params map[string]interface{}) ([]db.Shard, error) {
+	startStr, _ := params["start_date"].(string)
+	endStr, _ := params["end_date"].(string)
+	start, err := time.Parse(shardDateLayout, startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start_date %q", startStr)
+	}
+	end, err := time.Parse(shardDateLayout, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end_date %q", endStr)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date %s is before start_date %s", endStr, startStr)
+	}
+
+	var shards []db.Shard
+	for monthStart := start; !monthStart.After(end); {
+		nextMonth := time.Date(monthStart.Year(), monthStart.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		monthEnd := nextMonth
+		if !monthEnd.Before(end) {
+			monthEnd = end
+		}
+
+		shardParams := copyParams(params)
+		shardParams["start_date"] = monthStart.Format(shardDateLayout)
+		shardParams["end_date"] = monthEnd.Format(shardDateLayout)
+		shards = append(shards, db.Shard{Key: monthStart.Format("2006-01"), Parameters: shardParams})
+
+		if monthEnd.Equal(end) {
+			break
+		}
+		monthStart = nextMonth
+	}
+	return shards, nil
+}
+
+// shardBySeries creates one shard per resolved series
+func shardBySeries(params map[string]interface{}, seriesIDs []int) []db.Shard {
+	shards := make([]db.Shard, 0, len(seriesIDs))
+	for _, seriesID := range seriesIDs {
+		shardParams := copyParams(params)
+		delete(shardParams, "series_selector")
+		shardParams["series_ids"] = []int{seriesID}
+		shards = append(shards, db.Shard{Key: fmt.Sprintf("series %d", seriesID), Parameters: shardParams})
+	}
+	return shards
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		copied[key] = value
+	}
+	return copied
+}
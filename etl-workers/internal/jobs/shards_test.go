@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShardByMonthCoversRange(t *testing.T) {
+	tests := []struct {
+		name, start, end string
+		wantKeys         []string
+	}{
+		{"single day", "2024-03-10", "2024-03-10", []string{"2024-03"}},
+		{"within a month", "2024-03-10", "2024-03-20", []string{"2024-03"}},
+		{"ends on the first", "2024-01-15", "2024-03-01", []string{"2024-01", "2024-02"}},
+		{"ends mid month", "2024-01-31", "2024-03-15", []string{"2024-01", "2024-02", "2024-03"}},
+		{"across a year", "2023-12-01", "2024-01-31", []string{"2023-12", "2024-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := map[string]interface{}{"start_date": tt.start, "end_date": tt.end, "source_url": "http://source"}
+			shards, err := shardByMonth(params)
+			if err != nil {
+				t.Fatalf("shardByMonth: %v", err)
+			}
+			if len(shards) != len(tt.wantKeys) {
+				t.Fatalf("got %d shards, want %d", len(shards), len(tt.wantKeys))
+			}
+
+			// The shards must cover exactly what the unsharded run would,
+			// each one starting where the previous one ended
+			covered := tt.start
+			for i, shard := range shards {
+				if shard.Key != tt.wantKeys[i] {
+					t.Errorf("shard %d key = %s, want %s", i, shard.Key, tt.wantKeys[i])
+				}
+				if got := shard.Parameters["start_date"]; got != covered {
+					t.Errorf("shard %s starts at %v, want %s", shard.Key, got, covered)
+				}
+				if got := shard.Parameters["source_url"]; got != "http://source" {
+					t.Errorf("shard %s lost source_url: %v", shard.Key, got)
+				}
+				covered = shard.Parameters["end_date"].(string)
+				if i < len(shards)-1 {
+					end, _ := time.Parse(shardDateLayout, covered)
+					if end.Day() != 1 {
+						t.Errorf("shard %s ends at %s, want the first of the next month", shard.Key, covered)
+					}
+				}
+			}
+			if covered != tt.end {
+				t.Errorf("shards end at %s, want %s", covered, tt.end)
+			}
+		})
+	}
+}
+
+func TestShardByMonthRejectsInvertedRange(t *testing.T) {
+	params := map[string]interface{}{"start_date": "2024-03-10", "end_date": "2024-03-01"}
+	if _, err := shardByMonth(params); err == nil {
+		t.Fatal("expected an error for end_date before start_date")
+	}
+}
@@ -0,0 +1,271 @@
+package jobs
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/aquaflow/etl-workers/internal/metrics"
+	"github.com/google/uuid"
+)
+
+// ValueSink is where job handlers send loaded values and rejected records
+type ValueSink interface {
+	// Write stores validated values and returns the ones that were refused
+	Write(values []db.NumericValue) ([]db.Rejection, error)
+	// Reject stores records that could not be loaded
+	Reject(runID uuid.UUID, rejections []db.Rejection) error
+}
+
+// watermarker is a sink that tracks the latest timestamp it has written
+type watermarker interface {
+	// Watermark returns the latest value timestamp written, or the zero time
+	Watermark() time.Time
+}
+
+// dbSink writes to numeric_values and the dead-letter store
+type dbSink struct {
+	db        *db.Client
+	watermark time.Time
+}
+
+func NewDBSink(dbClient *db.Client) ValueSink {
+	return &dbSink{db: dbClient}
+}
+
+func (s *dbSink) Write(values []db.NumericValue) ([]db.Rejection, error) {
+	rejections, err := s.db.InsertNumericValues(values)
+	if err != nil {
+		return rejections, err
+	}
+
+	written := make(map[int]int)
+	for _, v := range values {
+		written[v.SeriesID]++
+	}
+	type point struct {
+		seriesID int
+		unixNano int64
+	}
+	refused := make(map[point]bool, len(rejections))
+	for _, r := range rejections {
+		written[r.Value.SeriesID]--
+		refused[point{r.Value.SeriesID, r.Value.Timestamp.UnixNano()}] = true
+	}
+	for seriesID, count := range written {
+		if count > 0 {
+			metrics.RecordsIngested.WithLabelValues(metrics.SeriesLabel(seriesID)).Add(float64(count))
+		}
+	}
+	for _, v := range values {
+		if v.Timestamp.After(s.watermark) && !refused[point{v.SeriesID, v.Timestamp.UnixNano()}] {
+			s.watermark = v.Timestamp
+		}
+	}
+	return rejections, nil
+}
+
+func (s *dbSink) Watermark() time.Time {
+	return s.watermark
+}
+
+func (s *dbSink) Reject(runID uuid.UUID, rejections []db.Rejection) error {
+	if err := s.db.InsertRejectedRecords(runID, rejections); err != nil {
+		return err
+	}
+	for _, r := range rejections {
+		metrics.RecordsRejected.WithLabelValues(metrics.SeriesLabel(r.Value.SeriesID), r.Category).Inc()
+	}
+	return nil
+}
+
+// dryRunSampleSize is the number of sample rows kept per series in a dry-run report
+const dryRunSampleSize = 5
+
+// DryRunReport summarizes what a dry run would have written
+type DryRunReport struct {
+	GeneratedAt          time.Time       `json:"generated_at"`
+	Series               []*DryRunSeries `json:"series"`
+	Totals               DryRunCounts    `json:"totals"`
+	FirstTimestamp       *time.Time      `json:"first_timestamp,omitempty"`
+	LastTimestamp        *time.Time      `json:"last_timestamp,omitempty"`
+	RejectionsByCategory map[string]int  `json:"rejections_by_category"`
+}
+
+// DryRunCounts are the record counts of a dry run. Each record is counted
+// once, so Records is WouldInsert + Duplicates + Rejected. Duplicates are
+// values already stored and timestamps the run has already written.
+type DryRunCounts struct {
+	Records     int `json:"records"`
+	WouldInsert int `json:"would_insert"`
+	Duplicates  int `json:"duplicates"`
+	Rejected    int `json:"rejected"`
+}
+
+// DryRunSeries is the dry-run summary of one series
+type DryRunSeries struct {
+	SeriesID int `json:"series_id"`
+	DryRunCounts
+	FirstTimestamp *time.Time     `json:"first_timestamp,omitempty"`
+	LastTimestamp  *time.Time     `json:"last_timestamp,omitempty"`
+	Samples        []DryRunSample `json:"samples"`
+
+	// seen holds the timestamps written so far, which a real run would
+	// have stored by the time they come again
+	seen map[int64]bool
+}
+
+// DryRunSample is a row that would have been written
+type DryRunSample struct {
+	Timestamp time.Time `json:"timestamp"`
+	SeriesID  int       `json:"series_id"`
+	Value     float64   `json:"value"`
+}
+
+// DryRunSink runs the read-only checks of the insert path and collects a
+// report instead of writing anything
+type DryRunSink struct {
+	db         *db.Client
+	series     map[int]*DryRunSeries
+	categories map[string]int
+}
+
+func NewDryRunSink(dbClient *db.Client) *DryRunSink {
+	return &DryRunSink{
+		db:         dbClient,
+		series:     make(map[int]*DryRunSeries),
+		categories: make(map[string]int),
+	}
+}
+
+func (s *DryRunSink) Write(values []db.NumericValue) ([]db.Rejection, error) {
+	bySeries := make(map[int][]db.NumericValue)
+	for _, v := range values {
+		bySeries[v.SeriesID] = append(bySeries[v.SeriesID], v)
+	}
+
+	var rejections []db.Rejection
+	for seriesID, seriesValues := range bySeries {
+		summary := s.seriesSummary(seriesID)
+		s.observeTimestamps(summary, seriesValues)
+
+		exists, err := s.db.SeriesExists(seriesID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			for _, v := range seriesValues {
+				rejections = append(rejections, db.Rejection{
+					Value:    v,
+					Reason:   "series does not exist",
+					Category: db.RejectUnknownSeries,
+				})
+			}
+			continue
+		}
+
+		fresh, repeats := summary.unseen(seriesValues)
+		timestamps := make([]time.Time, 0, len(fresh))
+		for _, v := range fresh {
+			timestamps = append(timestamps, v.Timestamp)
+		}
+		duplicates := 0
+		if len(timestamps) > 0 {
+			if duplicates, err = s.db.CountExistingValues(seriesID, timestamps); err != nil {
+				return nil, err
+			}
+		}
+
+		summary.Records += len(seriesValues)
+		summary.Duplicates += repeats + duplicates
+		summary.WouldInsert += len(fresh) - duplicates
+		for _, v := range fresh {
+			if len(summary.Samples) >= dryRunSampleSize {
+				break
+			}
+			summary.Samples = append(summary.Samples, DryRunSample{Timestamp: v.Timestamp, SeriesID: v.SeriesID, Value: v.Value})
+		}
+	}
+
+	// Rejections are reported back to the handler, which passes them to
+	// Reject; they are counted there, with the ones the handler rejected
+	// before writing
+	return rejections, nil
+}
+
+// Reject counts rejected records. Every record is counted once, either here
+// or by Write as one that would be inserted or is a duplicate.
+func (s *DryRunSink) Reject(runID uuid.UUID, rejections []db.Rejection) error {
+	for _, r := range rejections {
+		summary := s.seriesSummary(r.Value.SeriesID)
+		summary.Records++
+		summary.Rejected++
+		s.categories[r.Category]++
+	}
+	return nil
+}
+
+// Report builds the dry-run report from everything seen so far
+func (s *DryRunSink) Report() *DryRunReport {
+	report := &DryRunReport{
+		GeneratedAt:          time.Now(),
+		Series:               make([]*DryRunSeries, 0, len(s.series)),
+		RejectionsByCategory: s.categories,
+	}
+
+	for _, summary := range s.series {
+		report.Series = append(report.Series, summary)
+		report.Totals.Records += summary.Records
+		report.Totals.WouldInsert += summary.WouldInsert
+		report.Totals.Duplicates += summary.Duplicates
+		report.Totals.Rejected += summary.Rejected
+		if summary.FirstTimestamp != nil && (report.FirstTimestamp == nil || summary.FirstTimestamp.Before(*report.FirstTimestamp)) {
+			report.FirstTimestamp = summary.FirstTimestamp
+		}
+		if summary.LastTimestamp != nil && (report.LastTimestamp == nil || summary.LastTimestamp.After(*report.LastTimestamp)) {
+			report.LastTimestamp = summary.LastTimestamp
+		}
+	}
+	sort.Slice(report.Series, func(i, j int) bool { return report.Series[i].SeriesID < report.Series[j].SeriesID })
+
+	return report
+}
+
+func (s *DryRunSink) seriesSummary(seriesID int) *DryRunSeries {
+	summary, ok := s.series[seriesID]
+	if !ok {
+		summary = &DryRunSeries{SeriesID: seriesID, Samples: []DryRunSample{}}
+		s.series[seriesID] = summary
+	}
+	return summary
+}
+
+// unseen returns the values whose timestamps the run has not written before,
+// each timestamp once, and marks them seen. repeats counts the others.
+func (summary *DryRunSeries) unseen(values []db.NumericValue) (fresh []db.NumericValue, repeats int) {
+	if summary.seen == nil {
+		summary.seen = make(map[int64]bool)
+	}
+	for _, v := range values {
+		key := v.Timestamp.UnixNano()
+		if summary.seen[key] {
+			repeats++
+			continue
+		}
+		summary.seen[key] = true
+		fresh = append(fresh, v)
+	}
+	return fresh, repeats
+}
+
+func (s *DryRunSink) observeTimestamps(summary *DryRunSeries, values []db.NumericValue) {
+	for _, v := range values {
+		ts := v.Timestamp
+		if summary.FirstTimestamp == nil || ts.Before(*summary.FirstTimestamp) {
+			summary.FirstTimestamp = &ts
+		}
+		if summary.LastTimestamp == nil || ts.After(*summary.LastTimestamp) {
+			summary.LastTimestamp = &ts
+		}
+	}
+}
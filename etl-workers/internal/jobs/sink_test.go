@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/google/uuid"
+)
+
+func TestDryRunSinkCountsRejectsOnce(t *testing.T) {
+	sink := NewDryRunSink(nil)
+	ts := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// Rejected by the handler before writing, and by Write for an unknown series
+	rejections := []db.Rejection{
+		{Value: db.NumericValue{SeriesID: 7, Timestamp: ts}, Reason: "missing value", Category: db.RejectValidation},
+		{Value: db.NumericValue{SeriesID: 7, Timestamp: ts.Add(time.Hour)}, Reason: "not a number", Category: db.RejectParse},
+		{Value: db.NumericValue{SeriesID: 9, Timestamp: ts}, Reason: "series does not exist", Category: db.RejectUnknownSeries},
+	}
+	if err := sink.Reject(uuid.New(), rejections); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+
+	report := sink.Report()
+	if report.Totals.Records != 3 || report.Totals.Rejected != 3 || report.Totals.WouldInsert != 0 {
+		t.Errorf("totals = %+v, want 3 records, all rejected", report.Totals)
+	}
+	for _, series := range report.Series {
+		if series.Records != series.WouldInsert+series.Duplicates+series.Rejected {
+			t.Errorf("series %d counts do not add up: %+v", series.SeriesID, series.DryRunCounts)
+		}
+	}
+	want := map[string]int{db.RejectValidation: 1, db.RejectParse: 1, db.RejectUnknownSeries: 1}
+	for category, count := range want {
+		if report.RejectionsByCategory[category] != count {
+			t.Errorf("rejections of %s = %d, want %d", category, report.RejectionsByCategory[category], count)
+		}
+	}
+}
+
+func TestDryRunSeriesCountsRepeatedTimestamps(t *testing.T) {
+	summary := &DryRunSeries{SeriesID: 7}
+	ts := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	value := func(offset time.Duration) db.NumericValue {
+		return db.NumericValue{SeriesID: 7, Timestamp: ts.Add(offset)}
+	}
+
+	// A timestamp repeated inside the first page
+	fresh, repeats := summary.unseen([]db.NumericValue{value(0), value(time.Hour), value(0)})
+	if len(fresh) != 2 || repeats != 1 {
+		t.Errorf("first page: %d fresh, %d repeats; want 2, 1", len(fresh), repeats)
+	}
+
+	// An overlapping second page
+	fresh, repeats = summary.unseen([]db.NumericValue{value(time.Hour), value(2 * time.Hour)})
+	if len(fresh) != 1 || repeats != 1 {
+		t.Errorf("second page: %d fresh, %d repeats; want 1, 1", len(fresh), repeats)
+	}
+	if len(fresh) == 1 && !fresh[0].Timestamp.Equal(ts.Add(2*time.Hour)) {
+		t.Errorf("second page fresh value at %s, want %s", fresh[0].Timestamp, ts.Add(2*time.Hour))
+	}
+}
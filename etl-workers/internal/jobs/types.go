@@ -1,10 +1,28 @@
 package jobs
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 type DataPoint struct {
 	Timestamp time.Time `json:"timestamp"`
 	SeriesID  int       `json:"series_id"`
 	Value     float64   `json:"value"`
 	Unit      string    `json:"unit"`
-}
\ No newline at end of file
+}
+
+// parseIntList converts a decoded JSON array parameter to ints, rejecting
+// anything that is not a whole number instead of silently dropping it
+func parseIntList(param string, raw []interface{}) ([]int, error) {
+	ids := make([]int, 0, len(raw))
+	for i, id := range raw {
+		fid, ok := id.(float64)
+		if !ok || fid != math.Trunc(fid) {
+			return nil, fmt.Errorf("invalid %s parameter: element %d (%v) is not an integer", param, i, id)
+		}
+		ids = append(ids, int(fid))
+	}
+	return ids, nil
+}
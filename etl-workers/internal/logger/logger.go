@@ -4,15 +4,40 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aquaflow/etl-workers/internal/logging"
 	"github.com/google/uuid"
 )
 
+// ETLLogger writes run logs to stdout and etl_job_logs_v2. Loggers derived
+// with WithComponent share the queue and the per-run settings.
 type ETLLogger struct {
-	db *sql.DB
+	db        *sql.DB
+	component Component
+	shared    *sharedState
+}
+
+// sharedState is common to an ETLLogger and the loggers derived from it
+type sharedState struct {
+	writer *batchWriter
+
+	mu       sync.RWMutex
+	minLevel LogLevel
+	runs     map[uuid.UUID]RunSettings
+}
+
+// RunSettings are the logging settings of a single run
+type RunSettings struct {
+	// MinLevel overrides the logger's minimum level when set
+	MinLevel LogLevel
+	// CorrelationID is written with every entry of the run
+	CorrelationID uuid.UUID
 }
 
 type LogLevel string
@@ -24,54 +49,173 @@ const (
 	ERROR LogLevel = "ERROR"
 )
 
+// Component identifies the part of the system that wrote a log entry
+type Component string
+
+const (
+	ComponentWorker    Component = "worker"
+	ComponentProcessor Component = "processor"
+	ComponentValidator Component = "validator"
+	ComponentScript    Component = "script" // output captured from exec job scripts
+)
+
+// levelRank orders log levels from least to most important
+var levelRank = map[LogLevel]int{
+	DEBUG: 0,
+	INFO:  1,
+	WARN:  2,
+	ERROR: 3,
+}
+
+// ParseLevel returns the log level with the given name, case-insensitively
+func ParseLevel(name string) (LogLevel, bool) {
+	level := LogLevel(strings.ToUpper(strings.TrimSpace(name)))
+	_, ok := levelRank[level]
+	return level, ok
+}
+
 func NewETLLogger(db *sql.DB) *ETLLogger {
-	return &ETLLogger{db: db}
+	return NewETLLoggerWithConfig(db, DefaultWriterConfig)
+}
+
+// NewETLLoggerWithConfig creates a logger whose database writes are batched
+// according to config. Close must be called on shutdown to flush the queue.
+func NewETLLoggerWithConfig(db *sql.DB, config WriterConfig) *ETLLogger {
+	return &ETLLogger{
+		db:        db,
+		component: ComponentWorker,
+		shared: &sharedState{
+			writer:   newBatchWriter(db, config),
+			minLevel: DEBUG,
+			runs:     make(map[uuid.UUID]RunSettings),
+		},
+	}
+}
+
+// WithComponent returns a logger that records entries under component
+func (l *ETLLogger) WithComponent(component Component) *ETLLogger {
+	return &ETLLogger{db: l.db, component: component, shared: l.shared}
+}
+
+// SetMinLevel sets the level below which entries are discarded for runs
+// without a level of their own
+func (l *ETLLogger) SetMinLevel(level LogLevel) {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	l.shared.minLevel = level
+}
+
+// BeginRun registers the logging settings of a run
+func (l *ETLLogger) BeginRun(batchID uuid.UUID, settings RunSettings) {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	l.shared.runs[batchID] = settings
+}
+
+// EndRun removes the settings of a finished run
+func (l *ETLLogger) EndRun(batchID uuid.UUID) {
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	delete(l.shared.runs, batchID)
+}
+
+// Enabled reports whether entries of the given level are kept for the run
+func (l *ETLLogger) Enabled(batchID uuid.UUID, level LogLevel) bool {
+	l.shared.mu.RLock()
+	defer l.shared.mu.RUnlock()
+	min := l.shared.runs[batchID].MinLevel
+	if min == "" {
+		min = l.shared.minLevel
+	}
+	return levelRank[level] >= levelRank[min]
+}
+
+// Close flushes queued entries to the database
+func (l *ETLLogger) Close() {
+	l.shared.writer.close(10 * time.Second)
 }
 
 func (l *ETLLogger) Log(batchID uuid.UUID, level LogLevel, message string, context map[string]interface{}) {
+	l.write(batchID, level, message, context, "")
+}
+
+// write logs an entry with an optional stack trace, which is stored in its
+// own column rather than in the context
+func (l *ETLLogger) write(batchID uuid.UUID, level LogLevel, message string, context map[string]interface{}, stackTrace string) {
+	if !l.Enabled(batchID, level) {
+		return
+	}
+
 	// Add timestamp to context
+	now := time.Now()
 	if context == nil {
 		context = make(map[string]interface{})
 	}
-	context["logged_at"] = time.Now().Format(time.RFC3339)
+	context["logged_at"] = now.Format(time.RFC3339)
 	
-	// Log to stdout with structured format
-	contextStr := ""
-	if len(context) > 0 {
-		if ctxJSON, err := json.Marshal(context); err == nil {
-			contextStr = string(ctxJSON)
-		}
+	contextJSON, _ := json.Marshal(context)
+	l.echo(batchID, level, message, context, stackTrace)
+
+	l.shared.mu.RLock()
+	correlationID := l.shared.runs[batchID].CorrelationID
+	l.shared.mu.RUnlock()
+
+	// Queue for the database; rows are written in batches by the writer
+	entry := logEntry{
+		runID:     batchID,
+		timestamp: now,
+		level:     level,
+		message:   message,
+		context:   contextJSON,
+		component: l.component,
 	}
-	log.Printf("[%s] [%s] %s %s", batchID.String()[:8], level, message, contextStr)
+	if correlationID != uuid.Nil {
+		entry.correlationID = &correlationID
+	}
+	if stackTrace != "" {
+		entry.stackTrace = &stackTrace
+	}
+	l.shared.writer.enqueue(entry)
+}
 
-	// Log to database - try new table first, fallback to old table
-	contextJSON, _ := json.Marshal(context)
-	
-	// Try new table first (etl_job_logs_v2 with run_id)
-	newQuery := `
-		INSERT INTO aquaflow.etl_job_logs_v2 (run_id, log_level, message, context, component)
-		VALUES ($1, $2, $3, $4, 'worker')
-	`
-	
-	if _, err := l.db.Exec(newQuery, batchID, string(level), message, contextJSON); err != nil {
-		// Fallback to old table (etl_job_logs with batch_id)
-		oldQuery := `
-			INSERT INTO aquaflow.etl_job_logs (batch_id, log_level, message, context)
-			VALUES ($1, $2, $3, $4)
-		`
-		if _, err := l.db.Exec(oldQuery, batchID, string(level), message, contextJSON); err != nil {
-			log.Printf("Failed to write log to database (both tables): %v", err)
+// echo writes the entry to the process log. Context keys become top-level
+// fields so run logs share the service vocabulary (job_name, series_id).
+func (l *ETLLogger) echo(batchID uuid.UUID, level LogLevel, message string, context map[string]interface{}, stackTrace string) {
+	keys := make([]string, 0, len(context))
+	for k := range context {
+		if k != "logged_at" {
+			keys = append(keys, k)
 		}
 	}
+	sort.Strings(keys)
+
+	args := make([]any, 0, 2*len(keys)+6)
+	args = append(args, logging.KeyRunID, batchID.String(), "component", string(l.component))
+	for _, k := range keys {
+		args = append(args, k, context[k])
+	}
+	if stackTrace != "" {
+		args = append(args, "stack_trace", stackTrace)
+	}
+
+	switch level {
+	case DEBUG:
+		slog.Debug(message, args...)
+	case WARN:
+		slog.Warn(message, args...)
+	case ERROR:
+		slog.Error(message, args...)
+	default:
+		slog.Info(message, args...)
+	}
 }
 
 // LogWithStackTrace logs an error with stack trace
 func (l *ETLLogger) LogWithStackTrace(batchID uuid.UUID, level LogLevel, message string, err error) {
 	context := map[string]interface{}{
 		"error": err.Error(),
-		"stack_trace": string(debug.Stack()),
 	}
-	l.Log(batchID, level, message, context)
+	l.write(batchID, level, message, context, string(debug.Stack()))
 }
 
 func (l *ETLLogger) Debug(batchID uuid.UUID, message string, context ...map[string]interface{}) {
@@ -125,12 +269,16 @@ func (l *ETLLogger) LogJobStart(batchID uuid.UUID, jobName, jobType string, para
 }
 
 func (l *ETLLogger) LogJobProgress(batchID uuid.UUID, jobName string, processed, failed, total int) {
+	progressPercent := 0.0
+	if total > 0 {
+		progressPercent = float64(processed+failed) / float64(total) * 100
+	}
 	l.Info(batchID, "JOB_PROGRESS", map[string]interface{}{
 		"job_name": jobName,
 		"records_processed": processed,
 		"records_failed": failed,
 		"total_records": total,
-		"progress_percent": float64(processed) / float64(total) * 100,
+		"progress_percent": progressPercent,
 		"event": "job_progress",
 	})
 }
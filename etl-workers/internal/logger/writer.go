@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WriterConfig controls how log rows are buffered before they are written
+type WriterConfig struct {
+	// BatchSize is the number of queued rows that triggers a flush
+	BatchSize int
+	// FlushInterval is the longest a row waits in the queue
+	FlushInterval time.Duration
+	// QueueSize bounds the queue; when full, DEBUG rows are dropped first
+	QueueSize int
+}
+
+// DefaultWriterConfig is used by NewETLLogger
+var DefaultWriterConfig = WriterConfig{
+	BatchSize:     200,
+	FlushInterval: time.Second,
+	QueueSize:     10000,
+}
+
+// logEntry is one queued row of etl_job_logs_v2
+type logEntry struct {
+	runID     uuid.UUID
+	timestamp time.Time
+	level     LogLevel
+	message   string
+	context   []byte
+	component Component
+
+	correlationID *uuid.UUID
+	stackTrace    *string
+}
+
+// batchWriter queues log rows and writes them with multi-row INSERTs from a
+// background goroutine
+type batchWriter struct {
+	db     *sql.DB
+	config WriterConfig
+
+	mu      sync.Mutex
+	queue   []logEntry
+	dropped map[LogLevel]int
+
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newBatchWriter(db *sql.DB, config WriterConfig) *batchWriter {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultWriterConfig.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultWriterConfig.FlushInterval
+	}
+	if config.QueueSize < config.BatchSize {
+		config.QueueSize = config.BatchSize
+	}
+
+	w := &batchWriter{
+		db:      db,
+		config:  config,
+		dropped: make(map[LogLevel]int),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue adds a row to the queue. When the queue is full the oldest row of
+// the lowest level is evicted, as long as it is not more important than the
+// new row; otherwise the new row is dropped.
+func (w *batchWriter) enqueue(entry logEntry) {
+	w.mu.Lock()
+	if len(w.queue) >= w.config.QueueSize {
+		victim := -1
+		for i, queued := range w.queue {
+			if victim == -1 || levelRank[queued.level] < levelRank[w.queue[victim].level] {
+				victim = i
+			}
+			if levelRank[queued.level] == levelRank[DEBUG] {
+				break
+			}
+		}
+		if levelRank[w.queue[victim].level] > levelRank[entry.level] {
+			w.dropped[entry.level]++
+			w.mu.Unlock()
+			return
+		}
+		w.dropped[w.queue[victim].level]++
+		w.queue = append(w.queue[:victim], w.queue[victim+1:]...)
+	}
+	w.queue = append(w.queue, entry)
+	full := len(w.queue) >= w.config.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (w *batchWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			w.flush()
+			return
+		case <-ticker.C:
+			w.flush()
+		case <-w.wake:
+			w.flush()
+		}
+	}
+}
+
+// flush writes everything queued so far in batches of BatchSize
+func (w *batchWriter) flush() {
+	w.mu.Lock()
+	entries := w.queue
+	w.queue = nil
+	dropped := w.dropped
+	if len(dropped) > 0 {
+		w.dropped = make(map[LogLevel]int)
+	}
+	w.mu.Unlock()
+
+	if len(dropped) > 0 {
+		slog.Warn("ETL log queue full, dropped rows", "dropped", dropped)
+	}
+
+	for start := 0; start < len(entries); start += w.config.BatchSize {
+		end := start + w.config.BatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		w.writeBatch(entries[start:end])
+	}
+}
+
+// writeBatch inserts a batch with one statement. If the batch is refused,
+// typically because a row references a run that only exists in the legacy
+// tables, the rows are written one at a time with the legacy fallback.
+func (w *batchWriter) writeBatch(entries []logEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	var query strings.Builder
+	query.WriteString(`INSERT INTO aquaflow.etl_job_logs_v2 (run_id, timestamp, log_level, message, context, component, correlation_id, stack_trace) VALUES `)
+	args := make([]interface{}, 0, len(entries)*8)
+	for i, e := range entries {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := i * 8
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+		args = append(args, e.runID, e.timestamp, string(e.level), e.message, e.context,
+			string(e.component), e.correlationID, e.stackTrace)
+	}
+
+	if _, err := w.db.Exec(query.String(), args...); err == nil {
+		return
+	}
+
+	for _, e := range entries {
+		w.writeOne(e)
+	}
+}
+
+// writeOne writes a single row, falling back to the legacy etl_job_logs table
+func (w *batchWriter) writeOne(e logEntry) {
+	newQuery := `
+		INSERT INTO aquaflow.etl_job_logs_v2 (run_id, timestamp, log_level, message, context, component, correlation_id, stack_trace)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	if _, err := w.db.Exec(newQuery, e.runID, e.timestamp, string(e.level), e.message, e.context,
+		string(e.component), e.correlationID, e.stackTrace); err != nil {
+		// Fallback to old table (etl_job_logs with batch_id)
+		oldQuery := `
+			INSERT INTO aquaflow.etl_job_logs (batch_id, timestamp, log_level, message, context)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		if _, err := w.db.Exec(oldQuery, e.runID, e.timestamp, string(e.level), e.message, e.context); err != nil {
+			slog.Error("Failed to write log to database (both tables)", "run_id", e.runID.String(), "error", err)
+		}
+	}
+}
+
+// close flushes the queue and stops the background goroutine, waiting at
+// most timeout for the final flush
+func (w *batchWriter) close(timeout time.Duration) {
+	select {
+	case <-w.stop:
+		return
+	default:
+		close(w.stop)
+	}
+
+	select {
+	case <-w.stopped:
+	case <-time.After(timeout):
+		slog.Warn("Timed out flushing ETL logs on shutdown")
+	}
+}
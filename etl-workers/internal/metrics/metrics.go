@@ -0,0 +1,110 @@
+// Package metrics holds the worker's Prometheus metrics. Label names are
+// shared with the backend and scheduler: job_name, job_type, status,
+// series_id, source, code.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aquaflow/etl-workers/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "aquaflow"
+
+var (
+	// RunsTotal counts runs the worker finished, by their final status
+	RunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "runs_total",
+		Help:      "Runs executed by the worker, by final status.",
+	}, []string{"job_name", "job_type", "status"})
+
+	// RunDuration is the time from claim to final status
+	RunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "run_duration_seconds",
+		Help:      "Duration of runs executed by the worker.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200, 14400},
+	}, []string{"job_name", "job_type", "status"})
+
+	// RecordsIngested counts values written to numeric_values
+	RecordsIngested = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "records_ingested_total",
+		Help:      "Values written to numeric_values, by series.",
+	}, []string{"series_id"})
+
+	// RecordsRejected counts records sent to the dead-letter store
+	RecordsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "records_rejected_total",
+		Help:      "Records sent to the dead-letter store, by series and rejection category.",
+	}, []string{"series_id", "category"})
+
+	// SourceRequestDuration is the latency of requests to data sources
+	SourceRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "source_request_duration_seconds",
+		Help:      "Latency of HTTP requests to data sources.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"job_type", "source", "code"})
+
+	// SourceRequestErrors counts source requests that failed or did not return 2xx
+	SourceRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "source_request_errors_total",
+		Help:      "HTTP requests to data sources that failed or returned a non-2xx status.",
+	}, []string{"job_type", "source", "code"})
+)
+
+func init() {
+	prometheus.MustRegister(RunsTotal, RunDuration, RecordsIngested, RecordsRejected,
+		SourceRequestDuration, SourceRequestErrors)
+}
+
+// ObserveRun records a finished run
+func ObserveRun(jobName, jobType, status string, duration time.Duration) {
+	RunsTotal.WithLabelValues(jobName, jobType, status).Inc()
+	RunDuration.WithLabelValues(jobName, jobType, status).Observe(duration.Seconds())
+}
+
+// SeriesLabel formats a series ID as a label value
+func SeriesLabel(seriesID int) string {
+	return strconv.Itoa(seriesID)
+}
+
+// sourceTransport records the latency and errors of requests to a data source
+type sourceTransport struct {
+	jobType string
+	base    http.RoundTripper
+}
+
+// SourceClient returns an HTTP client whose requests are recorded under the
+// job type, with the request host as source, and traced
+func SourceClient(jobType string) *http.Client {
+	return &http.Client{Transport: &sourceTransport{jobType: jobType, base: tracing.Transport(http.DefaultTransport)}}
+}
+
+func (t *sourceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	SourceRequestDuration.WithLabelValues(t.jobType, req.URL.Host, code).Observe(time.Since(start).Seconds())
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		SourceRequestErrors.WithLabelValues(t.jobType, req.URL.Host, code).Inc()
+	}
+	return resp, err
+}
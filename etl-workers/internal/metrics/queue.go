@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"log/slog"
+
+	"github.com/aquaflow/etl-workers/internal/db"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "etl", "queue_depth"),
+		"Runs waiting for dependencies or queued for a worker.",
+		[]string{"job_name", "status"}, nil,
+	)
+	queueOldestAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "etl", "queue_oldest_age_seconds"),
+		"Time the oldest waiting or queued run has been in the queue.",
+		[]string{"job_name", "status"}, nil,
+	)
+)
+
+// queueCollector reads the queue from the database on every scrape
+type queueCollector struct {
+	db *db.Client
+}
+
+// NewQueueCollector reports the depth and age of the run queue
+func NewQueueCollector(dbClient *db.Client) prometheus.Collector {
+	return &queueCollector{db: dbClient}
+}
+
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- queueOldestAgeDesc
+}
+
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.db.GetQueueStats()
+	if err != nil {
+		slog.Warn("Failed to collect queue metrics", "error", err)
+		return
+	}
+	for _, stat := range stats {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(stat.Count), stat.JobName, stat.Status)
+		ch <- prometheus.MustNewConstMetric(queueOldestAgeDesc, prometheus.GaugeValue, stat.OldestAge.Seconds(), stat.JobName, stat.Status)
+	}
+}
+
+// NewActiveRunsGauge reports the number of runs the worker is executing
+func NewActiveRunsGauge(activeRuns func() int) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "etl",
+		Name:      "active_runs",
+		Help:      "Runs this worker is executing.",
+	}, func() float64 { return float64(activeRuns()) })
+}
@@ -0,0 +1,153 @@
+// Package tracing sets up OpenTelemetry tracing. Runs carry the W3C trace
+// context of the request or scheduling cycle that created them, so a run's
+// spans join the trace that started it.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is the worker's tracer
+var Tracer trace.Tracer = otel.Tracer("github.com/aquaflow/etl-workers")
+
+// Init installs the global tracer provider. OTEL_TRACES_EXPORTER selects
+// "otlp" (configured by the standard OTEL_EXPORTER_OTLP_* variables),
+// "stdout" or "none", the default. The returned function flushes spans.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")); name {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout", "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	if envName := os.Getenv("OTEL_SERVICE_NAME"); envName != "" {
+		serviceName = envName
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Inject returns the trace context of ctx as JSON for the trace_context
+// column of a run, or nil, stored as NULL, when ctx is not traced
+func Inject(ctx context.Context) *string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	encoded, err := json.Marshal(carrier)
+	if err != nil {
+		return nil
+	}
+	stored := string(encoded)
+	return &stored
+}
+
+// Extract returns ctx carrying the trace context stored on a run
+func Extract(ctx context.Context, stored []byte) context.Context {
+	if len(stored) == 0 {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	if err := json.Unmarshal(stored, &carrier); err != nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// EndSpan records err on span and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartSeries starts the span of loading one series of a run
+func StartSeries(ctx context.Context, seriesID int) (context.Context, trace.Span) {
+	return Tracer.Start(ctx, "series", trace.WithAttributes(attribute.Int("etl.series_id", seriesID)))
+}
+
+// StartPage starts the span of loading one page of a series
+func StartPage(ctx context.Context, seriesID, page int) (context.Context, trace.Span) {
+	return Tracer.Start(ctx, "page", trace.WithAttributes(
+		attribute.Int("etl.series_id", seriesID),
+		attribute.Int("etl.page", page),
+	))
+}
+
+// transport traces requests to data sources and passes the trace context on
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base so every request gets a client span
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLFull(req.URL.String()),
+		))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}